@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/andro-kes/gokode/internal/depgraph"
+	"github.com/andro-kes/gokode/internal/git"
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// testTargets returns the packages the test and coverage commands run: with
+// --affected the packages whose tests depend on the changes since --since,
+// otherwise the packages of the --since scope. done is set when there is
+// nothing left to do, with code the exit code to return.
+func testTargets(ctx context.Context, path, metricsDir string, scope *changeScope, opts options) (pkgs []string, done bool, code int) {
+	if !opts.affected {
+		// A selection of an earlier run would be mistaken for this one's
+		removeArtifacts(metricsDir, depgraph.SelectionFile)
+		pkgs = scope.goPackages()
+		if nothingChanged(scope, pkgs) {
+			return nil, true, 0
+		}
+		return pkgs, false, 0
+	}
+
+	pkgs, err := selectTests(ctx, path, metricsDir, opts.since)
+	if err != nil {
+		return nil, true, exitCode(err)
+	}
+	if len(pkgs) == 0 {
+		i18n.Println("affected.none", opts.since)
+		return nil, true, 0
+	}
+	return pkgs, false, 0
+}
+
+// selectTests finds the packages whose tests transitively depend on the files
+// changed since rev, records why each was selected in the metrics directory
+// and returns their directories
+func selectTests(ctx context.Context, path, metricsDir, rev string) ([]string, error) {
+	i18n.Println("affected.selecting", rev)
+
+	changed, err := git.ChangedFiles(ctx, path, rev)
+	if err != nil {
+		return nil, fmt.Errorf("error finding files changed since %s: %w", rev, err)
+	}
+	graph, err := depgraph.LoadTestGraph(ctx, path)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil, fmt.Errorf("go list interrupted: %w", ctx.Err())
+		}
+		return nil, err
+	}
+
+	selection := graph.SelectTests(changed)
+	if err := depgraph.WriteSelection(filepath.Join(metricsDir, depgraph.SelectionFile), rev, selection); err != nil {
+		i18n.Fprintln(os.Stderr, "cli.warning", err)
+	}
+
+	pkgs := make([]string, 0, len(selection))
+	for _, s := range selection {
+		i18n.Println("affected.package", s.Package, s.Reason())
+		pkgs = append(pkgs, s.Dir)
+	}
+	return pkgs, nil
+}
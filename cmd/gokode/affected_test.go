@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andro-kes/gokode/internal/config"
+)
+
+// writeFiles writes files, keyed by slash-separated path, under dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(file), err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+// gitCommit commits everything in dir, initialising the repository first
+func gitCommit(t *testing.T, dir string) {
+	t.Helper()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=gokode", "-c", "user.email=gokode@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %v\n%s", args[0], err, output)
+		}
+	}
+}
+
+func TestAffectedSelectionInReport(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	project := t.TempDir()
+	writeFiles(t, project, map[string]string{
+		"go.mod":      "module example.com/m\n\ngo 1.21\n",
+		"a/a.go":      "package a\n\nfunc A() int { return 1 }\n",
+		"b/b.go":      "package b\n\nimport \"example.com/m/a\"\n\nfunc B() int { return a.A() }\n",
+		"b/b_test.go": "package b\n\nimport \"testing\"\n\nfunc TestB(t *testing.T) { B() }\n",
+		"c/c_test.go": "package c\n\nimport \"testing\"\n\nfunc TestC(t *testing.T) {}\n",
+	})
+	gitCommit(t, project)
+	writeFiles(t, project, map[string]string{"a/a.go": "package a\n\nfunc A() int { return 2 }\n"})
+
+	metricsDir := t.TempDir()
+	pkgs, err := selectTests(context.Background(), project, metricsDir, "HEAD")
+	if err != nil {
+		t.Fatalf("selectTests failed: %v", err)
+	}
+	if len(pkgs) != 1 || pkgs[0] != "./b" {
+		t.Fatalf("Expected only ./b to be selected, got %v", pkgs)
+	}
+
+	if code := runTests(context.Background(), project, metricsDir, pkgs, &config.Config{}); code != 0 {
+		t.Fatalf("runTests exited with %d", code)
+	}
+	generateReport(metricsDir, false)
+	data, err := os.ReadFile(filepath.Join(metricsDir, "report.html"))
+	if err != nil {
+		t.Fatalf("Failed to read report.html: %v", err)
+	}
+	html := string(data)
+	for _, expected := range []string{"<code>example.com/m/b</code>", "example.com/m/b → example.com/m/a", "a/a.go"} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("Expected the report to explain the selection with %q", expected)
+		}
+	}
+	if strings.Contains(html, "example.com/m/c") {
+		t.Error("Expected the unaffected package to be left out of the report")
+	}
+}
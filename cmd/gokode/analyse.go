@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/andro-kes/gokode/internal/checkpoint"
+	"github.com/andro-kes/gokode/internal/complexity"
+	"github.com/andro-kes/gokode/internal/config"
+	"github.com/andro-kes/gokode/internal/coverage"
+	"github.com/andro-kes/gokode/internal/depgraph"
+	"github.com/andro-kes/gokode/internal/fuzz"
+	"github.com/andro-kes/gokode/internal/git"
+	"github.com/andro-kes/gokode/internal/history"
+	"github.com/andro-kes/gokode/internal/i18n"
+	"github.com/andro-kes/gokode/internal/race"
+	"github.com/andro-kes/gokode/internal/report"
+	"github.com/andro-kes/gokode/internal/runner"
+	"github.com/andro-kes/gokode/internal/snapshot"
+	"github.com/andro-kes/gokode/internal/source"
+	"github.com/andro-kes/gokode/internal/testquality"
+)
+
+// analyseStep is a single step of the analyse pipeline
+type analyseStep struct {
+	name string
+	// artifacts are the files the step writes to the metrics directory
+	artifacts []string
+	fn        func() error
+	// scope identifies the files the step is limited to, if any
+	scope string
+	// skip is set when a partial run has nothing for the step to check
+	skip bool
+	// writesTree is set for steps that may change files of the project, such
+	// as fixes and tests writing fixtures, after which it is hashed again
+	writesTree bool
+}
+
+// integrationArtifacts are the files written by integration coverage in
+// addition to those of the test suite
+var integrationArtifacts = []string{coverage.IntegrationFileName, runner.IntegrationOutputFile, runner.CombinedCoverageFile, runner.CombinedCoverageHTMLFile}
+
+// testStep returns the step running the test suite, which also measures the
+// coverage of the integration commands with --integration
+func testStep(ctx context.Context, path, metricsDir string, cfg *config.Config, opts options, pkgs []string, noPkgs bool, scope string) analyseStep {
+	artifacts := []string{runner.TestOutputFile, runner.TestEventsFile, runner.CoverageFile, runner.CoverageHTMLFile, runner.RawCoverageFile, coverage.ExclusionsFileName, snapshot.File}
+	if !opts.integration {
+		removeArtifacts(metricsDir, integrationArtifacts...)
+		return analyseStep{
+			name:       "Tests and coverage",
+			artifacts:  artifacts,
+			scope:      scope,
+			skip:       noPkgs,
+			writesTree: true,
+			fn: func() error {
+				return runner.RunTestSuite(ctx, path, metricsDir, cfg.KnownFlaky, cfg.CoverageExclude, pkgs...)
+			},
+		}
+	}
+	integration := cfg.Integration
+	return analyseStep{
+		name:       "Tests and integration coverage",
+		artifacts:  append(artifacts, integrationArtifacts...),
+		scope:      scope,
+		skip:       noPkgs,
+		writesTree: true,
+		fn: func() error {
+			return runner.RunIntegrationCoverage(ctx, path, metricsDir, cfg.KnownFlaky, cfg.CoverageExclude, integration.Binaries, integration.Commands, pkgs...)
+		},
+	}
+}
+
+func runAnalyse(ctx context.Context, path, cacheDir string, out *output, scope *changeScope, cfg *config.Config, opts options) int {
+	if scope != nil {
+		i18n.Println("analyse.startPartial")
+		scope.print()
+	} else {
+		i18n.Println("analyse.startFull")
+	}
+	metricsDir := out.dir
+
+	run := &report.RunInfo{
+		Status:      report.StatusRunning,
+		StartedAt:   time.Now(),
+		Commit:      out.commit,
+		PreviousRun: out.previous,
+		Partial:     scope.partial(),
+	}
+
+	files, pkgs := scope.goFiles(), scope.goPackages()
+	noFiles := scope != nil && len(files) == 0
+	noPkgs := scope != nil && len(pkgs) == 0
+
+	// With --affected the tests run for the packages whose tests depend on
+	// the changes, and the reasons are kept for the report
+	testPkgs, noTestPkgs, testScope := pkgs, noPkgs, scope.key()
+	if opts.affected {
+		selected, err := selectTests(ctx, path, metricsDir, opts.since)
+		if err != nil {
+			return exitCode(err)
+		}
+		testPkgs, noTestPkgs = selected, len(selected) == 0
+		testScope = "affected\x00" + strings.Join(selected, ",")
+	} else {
+		// A selection of an earlier run would be mistaken for this one's
+		removeArtifacts(metricsDir, depgraph.SelectionFile)
+	}
+
+	steps := []analyseStep{
+		{name: "Format", skip: noFiles, writesTree: true, fn: func() error { return runner.RunFormat(ctx, path, files...) }},
+		{name: "Vet", artifacts: []string{"vet.txt"}, skip: noPkgs, fn: func() error { return runner.RunVet(ctx, path, metricsDir, pkgs...) }},
+		{name: "Lint with fixes", artifacts: []string{"report.json"}, skip: noPkgs, writesTree: true, fn: func() error { return runner.RunLint(ctx, path, metricsDir, true, pkgs...) }},
+		testStep(ctx, path, metricsDir, cfg, opts, testPkgs, noTestPkgs, testScope),
+		{name: "Cyclomatic complexity", artifacts: []string{"gocyclo.txt"}, skip: noFiles, fn: func() error { return runner.RunGocyclo(ctx, path, metricsDir, files...) }},
+		{name: "CRAP scores", artifacts: []string{complexity.File}, skip: noFiles || noPkgs, fn: func() error { return runner.RunCrap(ctx, path, metricsDir) }},
+		{name: "Test quality", artifacts: []string{testquality.File}, skip: noFiles, fn: func() error { return runner.RunTestQuality(path, metricsDir, files...) }},
+		{name: "Source snapshot", artifacts: []string{source.File}, fn: func() error { return runner.RunSources(ctx, path, metricsDir) }},
+	}
+	if opts.race {
+		steps = append(steps, analyseStep{
+			name:       "Race detector",
+			artifacts:  []string{race.File, runner.RaceOutputFile},
+			scope:      testScope,
+			skip:       noTestPkgs,
+			writesTree: true,
+			fn: func() error {
+				_, err := runner.RunRace(ctx, path, metricsDir, testPkgs...)
+				return err
+			},
+		})
+	} else {
+		removeArtifacts(metricsDir, race.File, runner.RaceOutputFile)
+	}
+	for i := range steps {
+		if steps[i].scope == "" {
+			steps[i].scope = scope.key()
+		}
+	}
+	if opts.fuzz {
+		steps = append(steps, analyseStep{
+			name:       "Fuzzing",
+			artifacts:  []string{fuzz.File, runner.FuzzOutputFile},
+			scope:      scope.key() + "\x00" + opts.fuzzTime,
+			skip:       noPkgs,
+			writesTree: true,
+			fn: func() error {
+				_, err := runner.RunFuzz(ctx, path, metricsDir, opts.fuzzTime, pkgs...)
+				return err
+			},
+		})
+	} else {
+		removeArtifacts(metricsDir, fuzz.File, runner.FuzzOutputFile)
+	}
+
+	if opts.base != "" {
+		// The base is resolved so that a moved branch invalidates the cache
+		baseCommit, err := git.ResolveRev(ctx, path, opts.base)
+		if err != nil {
+			i18n.Fprintln(os.Stderr, "cli.error", err)
+			return 1
+		}
+		steps = append(steps, analyseStep{
+			name:      "Patch coverage",
+			artifacts: []string{coverage.PatchFileName, runner.PatchMarkdownFile},
+			scope:     scope.key() + "\x00" + baseCommit,
+			skip:      noPkgs,
+			fn:        func() error { return runner.RunPatchCoverage(ctx, path, metricsDir, opts.base) },
+		})
+	} else {
+		removeArtifacts(metricsDir, coverage.PatchFileName, runner.PatchMarkdownFile)
+	}
+
+	inputs, err := newStepInputs(ctx, path, cacheDir, out.root)
+	if err != nil {
+		i18n.Fprintln(os.Stderr, "analyse.inputsFailed", err)
+	}
+	cache := openStepCache(metricsDir)
+	cp := loadCheckpoint(metricsDir, opts.resume)
+
+	for _, step := range steps {
+		stepName := i18n.Current().Name("step", step.name)
+		i18n.Println("analyse.step", stepName)
+		start := time.Now()
+
+		if step.skip {
+			i18n.Println("analyse.nothingChanged", stepName)
+			// Results of an earlier run would be mistaken for this one's
+			removeArtifacts(metricsDir, step.artifacts...)
+			run.Steps = append(run.Steps, report.StepResult{
+				Name:   step.name,
+				Status: report.StatusSkipped,
+			})
+			continue
+		}
+		key := inputs.hash(step)
+
+		if opts.resume && key != "" && cp.Done(step.name, key) {
+			i18n.Println("analyse.alreadyCompleted", stepName)
+			run.Steps = append(run.Steps, report.StepResult{
+				Name:   step.name,
+				Status: report.StatusResumed,
+			})
+			continue
+		}
+
+		if cache.restore(key) {
+			i18n.Println("analyse.restored", stepName)
+			run.Steps = append(run.Steps, report.StepResult{
+				Name:     step.name,
+				Status:   report.StatusCached,
+				Duration: time.Since(start).Round(time.Millisecond),
+			})
+			markCheckpoint(cp, step, key)
+			continue
+		}
+
+		err := step.fn()
+		result := report.StepResult{
+			Name:     step.name,
+			Status:   report.StatusOK,
+			Duration: time.Since(start).Round(time.Millisecond),
+		}
+
+		if err != nil {
+			result.Error = err.Error()
+			if isInterrupted(err) {
+				result.Status = report.StatusInterrupted
+				run.Status = report.StatusInterrupted
+			} else {
+				result.Status = report.StatusFailed
+				run.Status = report.StatusFailed
+			}
+		}
+		run.Steps = append(run.Steps, result)
+
+		if err != nil {
+			finishRun(metricsDir, run)
+			if run.Interrupted() {
+				i18n.Fprintln(os.Stderr, "analyse.interrupted", stepName)
+				generateReport(metricsDir, opts.selfContained)
+				return exitInterrupted
+			}
+			i18n.Fprintln(os.Stderr, "analyse.failed", stepName, err)
+			return 1
+		}
+
+		// Formatting, lint fixes and tests may have changed the sources, so
+		// the result is recorded under the inputs as they are now
+		if step.writesTree {
+			inputs.changed()
+			key = inputs.hash(step)
+		}
+		cache.store(step, key)
+		markCheckpoint(cp, step, key)
+	}
+
+	run.Status = report.StatusComplete
+	run.Gates = evaluateGates(cfg, metricsDir)
+	gatesPassed := printGates(run.Gates)
+	// A partial run would distort the trends of the whole project
+	if scope == nil {
+		appendHistory(path, out, run)
+	}
+	finishRun(metricsDir, run)
+
+	// Generate HTML report
+	i18n.Println("analyse.generatingReport")
+	generateReport(metricsDir, opts.selfContained)
+
+	i18n.Println("analyse.complete")
+	i18n.Println("analyse.written", metricsDir)
+	if !gatesPassed {
+		i18n.Fprintln(os.Stderr, "gates.failed")
+		return 1
+	}
+	return 0
+}
+
+// removeArtifacts removes files of an earlier run from the metrics directory
+func removeArtifacts(metricsDir string, artifacts ...string) {
+	for _, artifact := range artifacts {
+		if err := os.Remove(filepath.Join(metricsDir, artifact)); err != nil && !os.IsNotExist(err) {
+			i18n.Fprintln(os.Stderr, "cli.warning", err)
+		}
+	}
+}
+
+// loadCheckpoint loads the checkpoint of the previous run when resuming and
+// starts a new one otherwise
+func loadCheckpoint(metricsDir string, resume bool) *checkpoint.Checkpoint {
+	if !resume {
+		cp := checkpoint.New(metricsDir)
+		if err := cp.Save(); err != nil {
+			i18n.Fprintln(os.Stderr, "cli.warning", err)
+		}
+		return cp
+	}
+	cp, err := checkpoint.Load(metricsDir)
+	if err != nil {
+		i18n.Fprintln(os.Stderr, "analyse.checkpointFailed", err)
+		return checkpoint.New(metricsDir)
+	}
+	return cp
+}
+
+// markCheckpoint records a completed step in the checkpoint
+func markCheckpoint(cp *checkpoint.Checkpoint, step analyseStep, key string) {
+	if key == "" {
+		return
+	}
+	if err := cp.Mark(step.name, key); err != nil {
+		i18n.Fprintln(os.Stderr, "cli.warning", err)
+	}
+}
+
+// appendHistory adds the key numbers of the completed run to history.jsonl
+// in the output root, which is shared by all runs
+func appendHistory(path string, out *output, run *report.RunInfo) {
+	historyFile := filepath.Join(out.root, history.File)
+	if rel, err := filepath.Rel(out.dir, historyFile); err == nil {
+		run.HistoryFile = rel
+	}
+
+	summary, err := report.Load(out.dir)
+	if err != nil {
+		i18n.Fprintln(os.Stderr, "history.failed", err)
+		return
+	}
+
+	entry := summary.HistoryEntry()
+	entry.Timestamp = run.StartedAt
+	entry.Commit = run.Commit
+	entry.Duration = time.Since(run.StartedAt).Seconds()
+	if loc, err := history.CountLOC(path, out.root); err == nil {
+		entry.LOC = loc
+	}
+
+	if err := history.Append(historyFile, entry); err != nil {
+		i18n.Fprintln(os.Stderr, "history.failed", err)
+	}
+}
+
+// finishRun records the end of the run in run.json
+func finishRun(metricsDir string, run *report.RunInfo) {
+	run.FinishedAt = time.Now()
+	if err := report.WriteRunInfo(metricsDir, run); err != nil {
+		i18n.Fprintln(os.Stderr, "cli.warning", err)
+	}
+}
+
+func generateReport(metricsDir string, selfContained bool) {
+	generate := report.GenerateHTML
+	if selfContained {
+		generate = report.GenerateSelfContainedHTML
+	}
+	if err := generate(metricsDir); err != nil {
+		i18n.Fprintln(os.Stderr, "report.failed", err)
+		// Don't fail the entire analysis if HTML generation fails
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/andro-kes/gokode/internal/bench"
+	"github.com/andro-kes/gokode/internal/config"
+	"github.com/andro-kes/gokode/internal/i18n"
+	"github.com/andro-kes/gokode/internal/report"
+	"github.com/andro-kes/gokode/internal/rundir"
+	"github.com/andro-kes/gokode/internal/runner"
+)
+
+// runBench runs the benchmarks, compares them with the baseline and fails
+// when the benchmark regression gate fails
+func runBench(ctx context.Context, path string, out *output, pkgs []string, cfg *config.Config, opts options) int {
+	baseline, baselineName, err := loadBaseline(out, opts.baseline)
+	if err != nil {
+		i18n.Fprintln(os.Stderr, "cli.error", err)
+		return 1
+	}
+	if baseline == nil {
+		i18n.Println("bench.noBaseline")
+	}
+
+	results, err := runner.RunBench(ctx, path, out.dir, opts.count, baseline, baselineName, pkgs...)
+	if err != nil {
+		return exitCode(err)
+	}
+
+	if regressions := results.Regressions(0); len(regressions) > 0 {
+		i18n.Println("bench.regressions", len(regressions))
+		for _, delta := range regressions {
+			i18n.Println("bench.regression", delta.Package, delta.Name, delta.Unit, delta.Change)
+		}
+	}
+
+	gate := benchGate(cfg, results)
+	if gate == nil {
+		return 0
+	}
+	results.Gate = gate
+	if err := bench.Write(filepath.Join(out.dir, bench.File), results); err != nil {
+		i18n.Fprintln(os.Stderr, "cli.error", err)
+		return 1
+	}
+	if !printGates([]report.GateResult{report.GateResult(*gate)}) {
+		i18n.Fprintln(os.Stderr, "gates.failed")
+		return 1
+	}
+	return 0
+}
+
+// loadBaseline reads the benchmark results to compare with: --baseline, a
+// metrics directory or bench.json file, or else the newest earlier run with
+// benchmark results. Without per-run directories that is the bench.json this
+// run is about to replace. It returns nil when there are no earlier results.
+func loadBaseline(out *output, baseline string) (*bench.Report, string, error) {
+	if baseline != "" {
+		name := baseline
+		if info, err := os.Stat(name); err == nil && info.IsDir() {
+			name = filepath.Join(name, bench.File)
+		}
+		results, err := bench.Read(name)
+		if err != nil {
+			return nil, "", fmt.Errorf("error reading baseline benchmark results: %w", err)
+		}
+		return results, baseline, nil
+	}
+
+	if out.dir == out.root {
+		results, err := bench.Read(filepath.Join(out.dir, bench.File))
+		if err != nil {
+			return nil, "", nil
+		}
+		return results, i18n.T("bench.previousRun"), nil
+	}
+
+	runs, err := rundir.List(out.root)
+	if err != nil {
+		return nil, "", nil
+	}
+	current := filepath.Base(out.dir)
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i] >= current {
+			continue
+		}
+		if results, err := bench.Read(filepath.Join(out.root, runs[i], bench.File)); err == nil {
+			return results, runs[i], nil
+		}
+	}
+	return nil, "", nil
+}
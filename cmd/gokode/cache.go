@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/cache"
+	"github.com/andro-kes/gokode/internal/i18n"
+	"github.com/andro-kes/gokode/internal/tools"
+)
+
+// stepInputs hashes everything an analyse step's result depends on
+type stepInputs struct {
+	env  cache.Environment
+	path string
+	// keyDir identifies the project in keys, which for --ref is not the
+	// worktree at path
+	keyDir string
+	// outDir is excluded from hashing when it lies inside the project
+	outDir string
+	// sources is the hash of the sources, empty until it is computed and
+	// after a step changed the tree
+	sources string
+}
+
+func newStepInputs(ctx context.Context, path, keyDir, outDir string) (*stepInputs, error) {
+	goVersion, err := tools.GoVersion(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return &stepInputs{
+		env:    cache.Environment{GoVersion: goVersion, Tools: tools.Identities()},
+		path:   path,
+		keyDir: keyDir,
+		outDir: outDir,
+	}, nil
+}
+
+// hash returns the inputs hash of step for the current state of the sources,
+// or an empty string if it cannot be computed
+func (in *stepInputs) hash(step analyseStep) string {
+	if in == nil {
+		return ""
+	}
+	if in.sources == "" {
+		sources, err := cache.SourcesHash(in.path, in.outDir)
+		if err != nil {
+			i18n.Fprintln(os.Stderr, "cli.warning", err)
+			return ""
+		}
+		in.sources = sources
+	}
+	// The artifact list is part of the key so entries written before a step
+	// produced a new artifact are not restored without it
+	id := step.name + "\x00" + strings.Join(step.artifacts, ",") + "\x00" + step.scope
+	return cache.Key(id, in.keyDir, in.sources, in.env)
+}
+
+// changed makes the next hash rehash the sources after a step that may have
+// written to the project tree
+func (in *stepInputs) changed() {
+	if in != nil {
+		in.sources = ""
+	}
+}
+
+// stepCache looks up and stores analyse step results. A nil stepCache
+// disables caching.
+type stepCache struct {
+	cache      *cache.Cache
+	metricsDir string
+}
+
+// openStepCache opens the result cache, disabling it with a warning if it is
+// unavailable
+func openStepCache(metricsDir string) *stepCache {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		i18n.Fprintln(os.Stderr, "cache.disabled", err)
+		return nil
+	}
+	c, err := cache.Open(dir)
+	if err != nil {
+		i18n.Fprintln(os.Stderr, "cache.disabled", err)
+		return nil
+	}
+	return &stepCache{cache: c, metricsDir: metricsDir}
+}
+
+// restore copies the artifacts cached under key into the metrics directory
+// and reports whether the step can be skipped
+func (s *stepCache) restore(key string) bool {
+	if s == nil || key == "" {
+		return false
+	}
+	hit, err := s.cache.Get(key, s.metricsDir)
+	if err != nil {
+		i18n.Fprintln(os.Stderr, "cli.warning", err)
+		return false
+	}
+	return hit
+}
+
+// store caches the artifacts of a successful step under key
+func (s *stepCache) store(step analyseStep, key string) {
+	if s == nil || key == "" {
+		return
+	}
+	if err := s.cache.Put(key, step.name, s.metricsDir, step.artifacts); err != nil {
+		i18n.Fprintln(os.Stderr, "cache.storeFailed", i18n.Current().Name("step", step.name), err)
+	}
+}
+
+func runCache(args []string) int {
+	if len(args) != 1 {
+		i18n.Fprintln(os.Stderr, "cache.usage")
+		return 1
+	}
+
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return exitCode(err)
+	}
+	c := &cache.Cache{Dir: dir}
+
+	switch args[0] {
+	case "stats":
+		stats, err := c.Stats()
+		if err != nil {
+			return exitCode(err)
+		}
+		i18n.Println("cache.dir", stats.Dir)
+		i18n.Println("cache.entries", stats.Entries, formatSize(stats.Size))
+		for _, step := range stats.Steps {
+			i18n.Println("cache.step", i18n.Current().Name("step", step.Step), step.Entries, formatSize(step.Size))
+		}
+	case "clean":
+		if err := c.Clean(); err != nil {
+			return exitCode(err)
+		}
+		i18n.Println("cache.cleaned", dir)
+	default:
+		i18n.Fprintln(os.Stderr, "cache.unknown", args[0])
+		i18n.Fprintln(os.Stderr, "cache.usage")
+		return 1
+	}
+	return 0
+}
+
+// formatSize formats a byte count for console output
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return i18n.T("size.B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return i18n.T("size."+string("KMGTPE"[exp])+"iB", float64(size)/float64(div))
+}
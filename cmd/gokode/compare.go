@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/andro-kes/gokode/internal/compare"
+	"github.com/andro-kes/gokode/internal/fileutil"
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+func runCompare(args []string) int {
+	args, opts, err := parseArgs(args)
+	if err != nil || len(args) != 2 {
+		i18n.Fprintln(os.Stderr, "compare.usage")
+		return 1
+	}
+
+	delta, err := compare.Load(args[0], args[1])
+	if err != nil {
+		return exitCode(err)
+	}
+
+	if opts.outputFile == "" {
+		if err := compare.Write(os.Stdout, delta, opts.format); err != nil {
+			return exitCode(err)
+		}
+		return 0
+	}
+
+	// Written in full and renamed into place so a failed write never leaves
+	// a truncated comparison behind
+	var buf bytes.Buffer
+	if err := compare.Write(&buf, delta, opts.format); err != nil {
+		return exitCode(err)
+	}
+	if err := fileutil.WriteFile(opts.outputFile, buf.Bytes(), 0644); err != nil {
+		return exitCode(fmt.Errorf("error writing %s: %w", opts.outputFile, err))
+	}
+	i18n.Println("compare.written", opts.outputFile)
+	return 0
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/andro-kes/gokode/internal/bench"
+	"github.com/andro-kes/gokode/internal/complexity"
+	"github.com/andro-kes/gokode/internal/config"
+	"github.com/andro-kes/gokode/internal/coverage"
+	"github.com/andro-kes/gokode/internal/i18n"
+	"github.com/andro-kes/gokode/internal/report"
+)
+
+// evaluateGates checks the metrics in metricsDir against the quality gates
+// in cfg. Gates whose metric was not collected by this run are left out.
+func evaluateGates(cfg *config.Config, metricsDir string) []report.GateResult {
+	var gates []report.GateResult
+
+	if cfg.PatchCoverageMin != nil {
+		patch, err := coverage.ReadPatch(filepath.Join(metricsDir, coverage.PatchFileName))
+		if err == nil {
+			gates = append(gates, minGate(i18n.T("gate.patchCoverage"), patch.Percent(), *cfg.PatchCoverageMin))
+		} else {
+			i18n.Fprintln(os.Stderr, "gate.noPatchCoverage")
+		}
+	}
+
+	if cfg.CrapMax != nil {
+		risks, err := complexity.Read(filepath.Join(metricsDir, complexity.File))
+		if err == nil {
+			gates = append(gates, crapGate(risks, *cfg.CrapMax))
+		} else {
+			i18n.Fprintln(os.Stderr, "gate.noCrap")
+		}
+	}
+
+	return gates
+}
+
+// minGate checks that a percentage is at least min
+func minGate(name string, value, min float64) report.GateResult {
+	return report.GateResult{
+		Name:      name,
+		Actual:    i18n.T("gate.percent", value),
+		Threshold: i18n.T("gate.atLeastPercent", min),
+		Passed:    value >= min,
+	}
+}
+
+// crapGate checks that no function has a CRAP score above max
+func crapGate(risks *complexity.Report, max float64) report.GateResult {
+	gate := report.GateResult{
+		Name:      i18n.T("gate.crap"),
+		Actual:    i18n.T("gate.score", 0.0),
+		Threshold: i18n.T("gate.atMost", max),
+		Passed:    true,
+	}
+	if worst := risks.Max(); worst != nil {
+		gate.Actual = i18n.T("gate.scoreFunc", worst.Score, worst.Package, worst.Func)
+		gate.Passed = worst.Score <= max
+	}
+	return gate
+}
+
+// benchGate checks the worst significant benchmark regression against
+// bench_regression_max. It returns nil when the gate is not configured or
+// there was no baseline to compare with.
+func benchGate(cfg *config.Config, results *bench.Report) *bench.Gate {
+	if cfg.BenchRegressionMax == nil {
+		return nil
+	}
+	if results.Baseline == "" {
+		i18n.Fprintln(os.Stderr, "gate.noBaseline")
+		return nil
+	}
+	worst := 0.0
+	for _, delta := range results.Deltas {
+		if delta.Significant {
+			worst = max(worst, delta.Regression())
+		}
+	}
+	return &bench.Gate{
+		Name:      i18n.T("gate.benchRegression"),
+		Actual:    i18n.T("gate.change", worst),
+		Threshold: i18n.T("gate.atMostPercent", *cfg.BenchRegressionMax),
+		Passed:    worst <= *cfg.BenchRegressionMax,
+	}
+}
+
+// printGates prints the gate results and reports whether all of them passed
+func printGates(gates []report.GateResult) bool {
+	passed := true
+	for _, gate := range gates {
+		if gate.Passed {
+			i18n.Println("gate.passed", gate.Name, gate.Actual, gate.Threshold)
+		} else {
+			i18n.Println("gate.notPassed", gate.Name, gate.Actual, gate.Threshold)
+			passed = false
+		}
+	}
+	return passed
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// selectLanguage sets the language of the output from --lang, or from the
+// locale of the environment without it, and returns args without the flag.
+// An unknown --lang is an error while an unknown locale falls back to
+// English, which is what most environments without a catalog expect.
+func selectLanguage(args []string) ([]string, error) {
+	var lang string
+	explicit := false
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || name != "lang" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				return nil, errors.New(i18n.T("lang.needsArgument"))
+			}
+			i++
+			value = args[i]
+		}
+		lang, explicit = value, true
+	}
+
+	if !explicit {
+		lang = i18n.FromEnv()
+	}
+	locale, ok := i18n.Lookup(lang)
+	switch {
+	case ok:
+		i18n.Set(locale)
+	case explicit:
+		return nil, errors.New(i18n.T("lang.unsupported", lang, strings.Join(i18n.Languages(), ", ")))
+	}
+	return rest, nil
+}
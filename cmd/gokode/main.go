@@ -2,194 +2,386 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
-	"github.com/andro-kes/gokode/internal/report"
+	"github.com/andro-kes/gokode/internal/config"
+	"github.com/andro-kes/gokode/internal/flaky"
+	"github.com/andro-kes/gokode/internal/gotest"
+	"github.com/andro-kes/gokode/internal/i18n"
 	"github.com/andro-kes/gokode/internal/runner"
 	"github.com/andro-kes/gokode/internal/tools"
 )
 
 const (
 	defaultTimeout = 5 * time.Minute
+	// exitInterrupted is the conventional exit code for a process stopped by SIGINT
+	exitInterrupted = 130
 )
 
 func main() {
-	if len(os.Args) < 2 {
+	// The language is selected first so that every message is translated
+	args, err := selectLanguage(os.Args[1:])
+	if err != nil {
+		i18n.Fprintln(os.Stderr, "cli.error", err)
+		os.Exit(1)
+	}
+	if len(args) < 1 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	command := args[0]
+	switch command {
+	case "cache":
+		os.Exit(runCache(args[1:]))
+	case "compare":
+		os.Exit(runCompare(args[1:]))
+	}
+
+	args, opts, err := parseArgs(args[1:])
+	if err != nil {
+		i18n.Fprintln(os.Stderr, "cli.error", err)
 		printUsage()
 		os.Exit(1)
 	}
 
-	command := os.Args[1]
 	path := "."
-	if len(os.Args) > 2 {
-		path = os.Args[2]
+	if len(args) > 0 {
+		path = args[0]
 	}
 
 	// Ensure path exists
 	absPath, err := filepath.Abs(path)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error resolving path %s: %v\n", path, err)
+		i18n.Fprintln(os.Stderr, "main.resolvePath", path, err)
 		os.Exit(1)
 	}
 
 	if _, err := os.Stat(absPath); os.IsNotExist(err) {
-		fmt.Fprintf(os.Stderr, "Error: path does not exist: %s\n", absPath)
+		i18n.Fprintln(os.Stderr, "main.noPath", absPath)
 		os.Exit(1)
 	}
 
+	// Ctrl-C cancels the context so running tools are interrupted and waited
+	// for; a second Ctrl-C falls back to the default behaviour and exits
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-sigCtx.Done()
+		stop()
+	}()
+
+	// Mutation testing runs the tests once per mutant and routinely takes
+	// longer than the default limit, so only an explicit --timeout applies
+	ctx := context.Context(sigCtx)
+	if command != "mutate" || opts.timeoutSet {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(sigCtx, opts.timeout)
+		defer cancel()
+	}
+
+	os.Exit(runCommand(ctx, command, absPath, opts))
+}
+
+// runCommand runs command against the project at path and returns the exit
+// code. With --ref the project is first checked out into a temporary
+// worktree, which is removed before returning.
+func runCommand(ctx context.Context, command, path string, opts options) int {
+	defaultRoot := filepath.Join(path, "metrics")
+	// The cache is keyed on the project rather than the temporary worktree
+	// of --ref, which is different on every run
+	cacheDir := path
+	if opts.ref != "" {
+		worktree, err := checkoutRef(ctx, path, opts.ref)
+		if err != nil {
+			i18n.Fprintln(os.Stderr, "cli.error", err)
+			return 1
+		}
+		defer worktree.remove()
+
+		// Results go next to the project's own metrics, not into the
+		// worktree that is about to be removed
+		defaultRoot = filepath.Join(path, "metrics", "refs", sanitizeRef(opts.ref))
+		path = worktree.projectDir
+	}
+
 	// Create metrics directory
-	metricsDir := filepath.Join(absPath, "metrics")
-	if err := os.MkdirAll(metricsDir, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating metrics directory: %v\n", err)
-		os.Exit(1)
+	out, err := prepareOutput(ctx, path, defaultRoot, opts)
+	if err != nil {
+		i18n.Fprintln(os.Stderr, "cli.error", err)
+		return 1
 	}
+	metricsDir := out.dir
 
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
-	defer cancel()
+	if opts.affected && command != "analyse" && command != "test" && command != "coverage" && command != "flaky" && command != "race" {
+		i18n.Fprintln(os.Stderr, "main.affectedUnsupported")
+		return 1
+	}
+
+	// analyse limits the other steps to the changes even when the tests are
+	// selected with --affected
+	var scope *changeScope
+	if opts.since != "" && (!opts.affected || command == "analyse") {
+		scope, err = loadScope(ctx, path, opts.since)
+		if err != nil {
+			i18n.Fprintln(os.Stderr, "cli.error", err)
+			return 1
+		}
+	}
+	files, pkgs := scope.goFiles(), scope.goPackages()
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		i18n.Fprintln(os.Stderr, "cli.error", err)
+		return 1
+	}
+	if opts.integration {
+		if command != "coverage" && command != "analyse" {
+			i18n.Fprintln(os.Stderr, "main.integrationUnsupported")
+			return 1
+		}
+		if cfg.Integration == nil {
+			i18n.Fprintln(os.Stderr, "main.integrationUnconfigured", config.File)
+			return 1
+		}
+	}
 
-	var exitCode int
 	switch command {
 	case "analyse":
-		exitCode = runAnalyse(ctx, absPath, metricsDir)
+		return runAnalyse(ctx, path, cacheDir, out, scope, cfg, opts)
 	case "fmt":
-		exitCode = runFormat(ctx, absPath)
+		if nothingChanged(scope, files) {
+			return 0
+		}
+		return runFormat(ctx, path, files)
 	case "vet":
-		exitCode = runVet(ctx, absPath, metricsDir)
+		if nothingChanged(scope, pkgs) {
+			return 0
+		}
+		return runVet(ctx, path, metricsDir, pkgs)
 	case "lint":
-		exitCode = runLint(ctx, absPath, metricsDir, false)
+		if nothingChanged(scope, pkgs) {
+			return 0
+		}
+		return runLint(ctx, path, metricsDir, false, pkgs)
 	case "lint-fix":
-		exitCode = runLint(ctx, absPath, metricsDir, true)
+		if nothingChanged(scope, pkgs) {
+			return 0
+		}
+		return runLint(ctx, path, metricsDir, true, pkgs)
 	case "test":
-		exitCode = runTests(ctx, absPath)
+		pkgs, done, code := testTargets(ctx, path, metricsDir, scope, opts)
+		if done {
+			return code
+		}
+		return runTests(ctx, path, metricsDir, pkgs, cfg)
 	case "coverage":
-		exitCode = runCoverage(ctx, absPath, metricsDir)
+		pkgs, done, code := testTargets(ctx, path, metricsDir, scope, opts)
+		if done {
+			return code
+		}
+		return runCoverage(ctx, path, metricsDir, pkgs, cfg, opts)
 	case "gocyclo":
-		exitCode = runGocyclo(ctx, absPath, metricsDir)
+		if nothingChanged(scope, files) {
+			return 0
+		}
+		return runGocyclo(ctx, path, metricsDir, files)
+	case "testquality":
+		if nothingChanged(scope, files) {
+			return 0
+		}
+		return runTestQuality(path, metricsDir, files)
+	case "flaky":
+		pkgs, done, code := testTargets(ctx, path, metricsDir, scope, opts)
+		if done {
+			return code
+		}
+		return withReport(metricsDir, opts, runFlaky(ctx, path, metricsDir, pkgs, cfg, opts))
+	case "race":
+		pkgs, done, code := testTargets(ctx, path, metricsDir, scope, opts)
+		if done {
+			return code
+		}
+		return withReport(metricsDir, opts, runRace(ctx, path, metricsDir, pkgs))
+	case "fuzz":
+		if nothingChanged(scope, pkgs) {
+			return 0
+		}
+		return withReport(metricsDir, opts, runFuzz(ctx, path, metricsDir, pkgs, opts.fuzzTime))
+	case "bench":
+		if nothingChanged(scope, pkgs) {
+			return 0
+		}
+		return withReport(metricsDir, opts, runBench(ctx, path, out, pkgs, cfg, opts))
+	case "mutate":
+		if nothingChanged(scope, files) {
+			return 0
+		}
+		return withReport(metricsDir, opts, runMutate(ctx, path, metricsDir, files))
+	case "order":
+		return withReport(metricsDir, opts, runOrder(ctx, path, metricsDir, scope.goPackages(), opts))
 	case "tools":
-		exitCode = installTools()
+		return installTools()
 	default:
-		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
+		i18n.Fprintln(os.Stderr, "main.unknownCommand", command)
 		printUsage()
-		exitCode = 1
+		return 1
 	}
+}
 
-	os.Exit(exitCode)
+// withReport regenerates the HTML report after a command whose results are
+// only shown there, so they don't wait for the next analyse into the same
+// directory, and returns code
+func withReport(metricsDir string, opts options, code int) int {
+	generateReport(metricsDir, opts.selfContained)
+	return code
 }
 
 func printUsage() {
-	usage := `gokode - Go code analysis and quality tool
-
-Usage:
-  gokode <command> [path]
-
-Commands:
-  analyse      Run full analysis (fmt, vet, lint with fixes, test, coverage, gocyclo) and generate HTML report
-  fmt          Format code with gofmt
-  vet          Run go vet and write output to metrics/vet.txt
-  lint         Run golangci-lint and write pretty-printed JSON to metrics/report.json
-  lint-fix     Run golangci-lint with --fix
-  test         Run tests
-  coverage     Run tests with coverage (metrics/coverage.out and coverage.html)
-  gocyclo      Run cyclomatic complexity analysis (metrics/gocyclo.txt)
-  tools        Install required tools (golangci-lint, gocyclo)
-
-Arguments:
-  path         Target directory (default: current directory)
-
-Examples:
-  gokode analyse .
-  gokode lint ./myproject
-  gokode coverage /path/to/project
-`
-	fmt.Fprint(os.Stderr, usage)
-}
-
-func runAnalyse(ctx context.Context, path, metricsDir string) int {
-	fmt.Println("Starting full analysis...")
-
-	steps := []struct {
-		name string
-		fn   func() error
-	}{
-		{"Format", func() error { return runner.RunFormat(ctx, path) }},
-		{"Vet", func() error { return runner.RunVet(ctx, path, metricsDir) }},
-		{"Lint with fixes", func() error { return runner.RunLint(ctx, path, metricsDir, true) }},
-		{"Tests", func() error { return runner.RunTests(ctx, path) }},
-		{"Coverage", func() error { return runner.RunCoverage(ctx, path, metricsDir) }},
-		{"Cyclomatic complexity", func() error { return runner.RunGocyclo(ctx, path, metricsDir) }},
-	}
-
-	for _, step := range steps {
-		fmt.Printf("\n=== %s ===\n", step.name)
-		if err := step.fn(); err != nil {
-			fmt.Fprintf(os.Stderr, "Analysis failed at step: %s: %v\n", step.name, err)
-			return 1
-		}
+	fmt.Fprint(os.Stderr, i18n.T("usage"))
+}
+
+// isInterrupted reports whether err was caused by Ctrl-C or SIGTERM
+func isInterrupted(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
+
+// exitCode prints err and returns the process exit code for it
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "%v\n", err)
+	if isInterrupted(err) {
+		return exitInterrupted
 	}
+	return 1
+}
 
-	// Generate HTML report
-	fmt.Println("\n=== Generating HTML report ===")
-	if err := report.GenerateHTML(metricsDir); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to generate HTML report: %v\n", err)
-		// Don't fail the entire analysis if HTML generation fails
+// nothingChanged reports whether a partial run has nothing to check and
+// prints the scope otherwise
+func nothingChanged(scope *changeScope, targets []string) bool {
+	if scope == nil {
+		return false
 	}
+	scope.print()
+	if len(targets) == 0 {
+		i18n.Println("main.nothingToCheck", scope.since)
+		return true
+	}
+	return false
+}
 
-	fmt.Println("\n=== Analysis complete ===")
-	fmt.Printf("Reports written to: %s\n", metricsDir)
-	return 0
+func runFormat(ctx context.Context, path string, files []string) int {
+	return exitCode(runner.RunFormat(ctx, path, files...))
 }
 
-func runFormat(ctx context.Context, path string) int {
-	if err := runner.RunFormat(ctx, path); err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		return 1
-	}
-	return 0
+func runVet(ctx context.Context, path, metricsDir string, pkgs []string) int {
+	return exitCode(runner.RunVet(ctx, path, metricsDir, pkgs...))
 }
 
-func runVet(ctx context.Context, path, metricsDir string) int {
-	if err := runner.RunVet(ctx, path, metricsDir); err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		return 1
-	}
-	return 0
+func runLint(ctx context.Context, path, metricsDir string, fix bool, pkgs []string) int {
+	return exitCode(runner.RunLint(ctx, path, metricsDir, fix, pkgs...))
+}
+
+func runTests(ctx context.Context, path, metricsDir string, pkgs []string, cfg *config.Config) int {
+	return exitCode(runner.RunTestSuite(ctx, path, metricsDir, cfg.KnownFlaky, cfg.CoverageExclude, pkgs...))
 }
 
-func runLint(ctx context.Context, path, metricsDir string, fix bool) int {
-	if err := runner.RunLint(ctx, path, metricsDir, fix); err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+// runCoverage runs the test suite, with --integration also the integration
+// commands, and given a base revision computes and gates the coverage of the
+// changes since it
+func runCoverage(ctx context.Context, path, metricsDir string, pkgs []string, cfg *config.Config, opts options) int {
+	var err error
+	if opts.integration {
+		err = runner.RunIntegrationCoverage(ctx, path, metricsDir, cfg.KnownFlaky, cfg.CoverageExclude, cfg.Integration.Binaries, cfg.Integration.Commands, pkgs...)
+	} else {
+		removeArtifacts(metricsDir, integrationArtifacts...)
+		err = runner.RunTestSuite(ctx, path, metricsDir, cfg.KnownFlaky, cfg.CoverageExclude, pkgs...)
+	}
+	if err != nil {
+		return exitCode(err)
+	}
+	if opts.base == "" {
+		return 0
+	}
+	if err := runner.RunPatchCoverage(ctx, path, metricsDir, opts.base); err != nil {
+		return exitCode(err)
+	}
+	if !printGates(evaluateGates(cfg, metricsDir)) {
+		i18n.Fprintln(os.Stderr, "gates.failed")
 		return 1
 	}
 	return 0
 }
 
-func runTests(ctx context.Context, path string) int {
-	if err := runner.RunTests(ctx, path); err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+func runTestQuality(path, metricsDir string, files []string) int {
+	return exitCode(runner.RunTestQuality(path, metricsDir, files...))
+}
+
+func runGocyclo(ctx context.Context, path, metricsDir string, files []string) int {
+	return exitCode(runner.RunGocyclo(ctx, path, metricsDir, files...))
+}
+
+// runFlaky runs the tests repeatedly and fails when it finds flaky tests that
+// are not in the known flaky list yet
+func runFlaky(ctx context.Context, path, metricsDir string, pkgs []string, cfg *config.Config, opts options) int {
+	report, err := runner.RunFlaky(ctx, path, metricsDir, opts.runs, opts.shuffle, pkgs...)
+	if err != nil {
+		return exitCode(err)
+	}
+
+	var unknown int
+	for _, test := range report.Flaky() {
+		if !flaky.IsKnown(cfg.KnownFlaky, gotest.Test{Package: test.Package, Name: test.Name}) {
+			unknown++
+		}
+	}
+	if unknown > 0 {
+		i18n.Fprintln(os.Stderr, "main.unknownFlaky", unknown, config.File)
 		return 1
 	}
 	return 0
 }
 
-func runCoverage(ctx context.Context, path, metricsDir string) int {
-	if err := runner.RunCoverage(ctx, path, metricsDir); err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+// runRace runs the tests with the race detector and fails when it finds data
+// races
+func runRace(ctx context.Context, path, metricsDir string, pkgs []string) int {
+	report, err := runner.RunRace(ctx, path, metricsDir, pkgs...)
+	if err != nil {
+		return exitCode(err)
+	}
+	if len(report.Races) > 0 {
 		return 1
 	}
 	return 0
 }
 
-func runGocyclo(ctx context.Context, path, metricsDir string) int {
-	if err := runner.RunGocyclo(ctx, path, metricsDir); err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+// runFuzz fuzzes all fuzz targets and fails when a failing input was found
+func runFuzz(ctx context.Context, path, metricsDir string, pkgs []string, fuzzTime string) int {
+	report, err := runner.RunFuzz(ctx, path, metricsDir, fuzzTime, pkgs...)
+	if err != nil {
+		return exitCode(err)
+	}
+	if len(report.Failed()) > 0 {
 		return 1
 	}
 	return 0
 }
 
+// runMutate runs mutation testing on the given files, or all files if none
+// are given
+func runMutate(ctx context.Context, path, metricsDir string, files []string) int {
+	_, err := runner.RunMutate(ctx, path, metricsDir, files...)
+	return exitCode(err)
+}
+
 func installTools() int {
 	if err := tools.InstallAll(); err != nil {
 		return 1
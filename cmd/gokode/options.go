@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"time"
+
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// options holds the command-line flags
+type options struct {
+	// resume skips analyse steps recorded as completed in the checkpoint
+	resume bool
+	// out is the output root, <project>/metrics by default
+	out string
+	// perRun writes each run into its own timestamped subdirectory of out
+	perRun bool
+	// keepRuns and keepDays limit how many per-run directories are retained
+	keepRuns int
+	keepDays int
+	// ref analyses a git revision in a temporary worktree
+	ref string
+	// since limits the run to Go files changed since a git revision and the
+	// packages affected by them
+	since string
+	// base is the revision patch coverage is computed against, --since by
+	// default
+	base string
+	// affected limits test and coverage to the packages whose tests depend
+	// on the changes since --since
+	affected bool
+	// runs and shuffle control how flaky runs the tests
+	runs    int
+	shuffle bool
+	// seed is the -shuffle seed order bisects the failures of
+	seed string
+	// integration adds the coverage of the integration commands configured
+	// in .gokode.json to coverage and analyse
+	integration bool
+	// race adds a run of the tests with the race detector to analyse
+	race bool
+	// fuzz adds fuzzing to analyse and fuzzTime is how long each fuzz
+	// target runs
+	fuzz     bool
+	fuzzTime string
+	// selfContained makes the commands writing the HTML report write it as a
+	// single file embedding the source pages and linked artifacts
+	selfContained bool
+	// count is the number of times bench runs each benchmark and baseline
+	// the metrics directory or file it compares them with
+	count    int
+	baseline string
+	// timeout limits the whole run and timeoutSet is whether --timeout was
+	// given; mutate has no limit without it
+	timeout    time.Duration
+	timeoutSet bool
+	// format and outputFile select how compare renders its result
+	format     string
+	outputFile string
+}
+
+// parseArgs parses flags and returns the remaining positional arguments.
+// Flags may appear before or after positional arguments.
+func parseArgs(args []string) ([]string, options, error) {
+	var opts options
+
+	fs := flag.NewFlagSet("gokode", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&opts.resume, "resume", false, "skip steps completed by the previous run")
+	fs.StringVar(&opts.out, "out", "", "output directory")
+	fs.BoolVar(&opts.perRun, "per-run", false, "write each run into its own subdirectory")
+	fs.IntVar(&opts.keepRuns, "keep", 0, "number of runs to keep")
+	fs.IntVar(&opts.keepDays, "keep-days", 0, "number of days to keep runs for")
+	fs.StringVar(&opts.ref, "ref", "", "git revision to analyse in a temporary worktree")
+	fs.StringVar(&opts.since, "since", "", "only check changes since the git revision")
+	fs.StringVar(&opts.base, "base", "", "git revision to compute patch coverage against")
+	fs.BoolVar(&opts.affected, "affected", false, "only run tests affected by changes since --since")
+	fs.IntVar(&opts.runs, "runs", 10, "number of test runs for flaky")
+	fs.BoolVar(&opts.shuffle, "shuffle", false, "shuffle test order in each flaky run")
+	fs.StringVar(&opts.seed, "seed", "", "-shuffle seed to find test order dependencies for")
+	fs.BoolVar(&opts.integration, "integration", false, "also measure the coverage of the configured integration commands")
+	fs.BoolVar(&opts.race, "race", false, "also run tests with the race detector in analyse")
+	fs.BoolVar(&opts.fuzz, "fuzz", false, "also run fuzz targets in analyse")
+	fs.StringVar(&opts.fuzzTime, "fuzztime", "10s", "time to run each fuzz target for, e.g. 30s or 1000x")
+	fs.BoolVar(&opts.selfContained, "self-contained", false, "write the HTML report as a single self-contained file")
+	fs.IntVar(&opts.count, "count", 6, "number of runs of each benchmark")
+	fs.StringVar(&opts.baseline, "baseline", "", "metrics directory or bench.json to compare benchmarks with")
+	fs.DurationVar(&opts.timeout, "timeout", defaultTimeout, "time limit for the whole run")
+	fs.StringVar(&opts.format, "format", "console", "output format: console, markdown or html")
+	fs.StringVar(&opts.outputFile, "o", "", "write output to file instead of stdout")
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, opts, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if opts.keepRuns < 0 || opts.keepDays < 0 {
+		return nil, opts, errors.New(i18n.T("options.keepNegative"))
+	}
+
+	if opts.runs < 1 {
+		return nil, opts, errors.New(i18n.T("options.runs"))
+	}
+	if opts.count < 1 {
+		return nil, opts, errors.New(i18n.T("options.count"))
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "timeout" {
+			opts.timeoutSet = true
+		}
+	})
+	if opts.timeout <= 0 {
+		return nil, opts, errors.New(i18n.T("options.timeout"))
+	}
+	if opts.affected && opts.since == "" {
+		return nil, opts, errors.New(i18n.T("options.affected"))
+	}
+	if opts.base == "" {
+		opts.base = opts.since
+	}
+
+	return positional, opts, nil
+}
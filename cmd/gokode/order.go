@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/flaky"
+	"github.com/andro-kes/gokode/internal/i18n"
+	"github.com/andro-kes/gokode/internal/runner"
+)
+
+// runOrder finds the tests that make tests failing with a -shuffle seed fail.
+// The seed comes from --seed or, without it, from the failing seeds of the
+// flaky tests recorded by the flaky command.
+func runOrder(ctx context.Context, path, metricsDir string, pkgs []string, opts options) int {
+	var targets []runner.OrderTarget
+	if opts.seed != "" {
+		seed, err := strconv.ParseInt(opts.seed, 10, 64)
+		if err != nil {
+			i18n.Fprintln(os.Stderr, "order.invalidSeed", opts.seed)
+			return 1
+		}
+		targets, err = runner.FailingTests(ctx, path, seed, pkgs...)
+		if err != nil {
+			return exitCode(err)
+		}
+		if len(targets) == 0 {
+			i18n.Println("order.noFailures", seed)
+			return 0
+		}
+	} else {
+		report, err := flaky.Read(filepath.Join(metricsDir, flaky.File))
+		if err != nil {
+			i18n.Fprintln(os.Stderr, "order.noReport", err)
+			return 1
+		}
+		targets = flakyTargets(report)
+		if len(targets) == 0 {
+			i18n.Println("order.noSeeds")
+			return 0
+		}
+	}
+
+	_, err := runner.RunOrderDeps(ctx, path, metricsDir, targets)
+	return exitCode(err)
+}
+
+// flakyTargets returns the top-level flaky tests of the report that failed in
+// a shuffled run, each with the first seed it failed with
+func flakyTargets(report *flaky.Report) []runner.OrderTarget {
+	var targets []runner.OrderTarget
+	seen := make(map[string]bool)
+	for _, test := range report.Flaky() {
+		if len(test.FailingSeeds) == 0 {
+			continue
+		}
+		// -run selects top-level tests, a failing subtest fails its parent
+		name, _, _ := strings.Cut(test.Name, "/")
+		key := test.Package + "\x00" + name
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		targets = append(targets, runner.OrderTarget{Package: test.Package, Test: name, Seed: test.FailingSeeds[0]})
+	}
+	return targets
+}
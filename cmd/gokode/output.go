@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/andro-kes/gokode/internal/git"
+	"github.com/andro-kes/gokode/internal/i18n"
+	"github.com/andro-kes/gokode/internal/rundir"
+)
+
+// output describes where a command writes its metrics
+type output struct {
+	// root is the output directory given with --out, <project>/metrics by default
+	root string
+	// dir is the metrics directory of this run; it equals root unless
+	// per-run directories are enabled
+	dir string
+	// commit is the abbreviated HEAD commit of the project, if known
+	commit string
+	// previous is the name of the previous run directory under root
+	previous string
+}
+
+// prepareOutput creates the metrics directory for this invocation under
+// defaultRoot or --out. With --per-run it creates a new run directory, points
+// the latest symlink at it and prunes old runs; resuming reuses the latest run
+// instead.
+func prepareOutput(ctx context.Context, projectDir, defaultRoot string, opts options) (*output, error) {
+	root := defaultRoot
+	if opts.out != "" {
+		abs, err := filepath.Abs(opts.out)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving output directory %s: %w", opts.out, err)
+		}
+		root = abs
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("error creating metrics directory: %w", err)
+	}
+
+	out := &output{root: root, dir: root}
+	if commit, err := git.ShortCommit(ctx, projectDir); err == nil {
+		out.commit = commit
+	}
+
+	if !opts.perRun {
+		return out, nil
+	}
+
+	name := ""
+	if opts.resume {
+		if latest, err := rundir.Latest(root); err == nil {
+			name = latest
+		}
+	}
+	if name == "" {
+		created, err := rundir.Create(root, out.commit, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		name = created
+	}
+	out.dir = filepath.Join(root, name)
+
+	if err := rundir.SetLatest(root, name); err != nil {
+		i18n.Fprintln(os.Stderr, "cli.warning", err)
+	}
+
+	removed, err := rundir.Prune(root, name, opts.keepRuns, opts.keepDays, time.Now())
+	if err != nil {
+		i18n.Fprintln(os.Stderr, "cli.warning", err)
+	}
+	for _, run := range removed {
+		i18n.Println("output.removedRun", run)
+	}
+
+	if previous, err := rundir.Previous(root, name); err == nil {
+		out.previous = previous
+	}
+
+	return out, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/git"
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// unsafeRefChars matches characters not allowed in directory names derived
+// from git refs
+var unsafeRefChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// refWorktree is a temporary worktree with a git ref checked out
+type refWorktree struct {
+	repoDir string
+	dir     string
+	// projectDir is the analysed directory inside the worktree
+	projectDir string
+}
+
+// sanitizeRef turns a git ref into a string usable as a directory name.
+// Leading dots are replaced so that refs like .. cannot name the parent
+// directory or a hidden one.
+func sanitizeRef(ref string) string {
+	name := unsafeRefChars.ReplaceAllString(ref, "-")
+	if trimmed := strings.TrimLeft(name, "."); trimmed != name {
+		name = strings.Repeat("-", len(name)-len(trimmed)) + trimmed
+	}
+	if name == "" {
+		return "-"
+	}
+	return name
+}
+
+// checkoutRef creates a worktree for ref in the repository containing
+// projectDir. Each run gets its own worktree, so concurrent runs on the same
+// ref don't remove each other's.
+func checkoutRef(ctx context.Context, projectDir, ref string) (*refWorktree, error) {
+	repoDir, err := git.RepoRoot(ctx, projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("--ref requires a git repository: %w", err)
+	}
+	rel, err := filepath.Rel(repoDir, projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving project path in repository: %w", err)
+	}
+
+	// Forget worktrees of killed runs whose directories are gone
+	if err := git.PruneWorktrees(ctx, repoDir); err != nil {
+		i18n.Fprintln(os.Stderr, "cli.warning", err)
+	}
+
+	dir, err := os.MkdirTemp("", "gokode-worktree-"+sanitizeRef(ref)+"-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating worktree directory: %w", err)
+	}
+	i18n.Println("ref.checkout", ref, dir)
+	if err := git.AddWorktree(ctx, repoDir, dir, ref); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("error creating worktree for %s: %w", ref, err)
+	}
+
+	return &refWorktree{
+		repoDir:    repoDir,
+		dir:        dir,
+		projectDir: filepath.Join(dir, rel),
+	}, nil
+}
+
+// remove deletes the worktree. It does not use the command's context, which
+// is already cancelled when the run was interrupted.
+func (w *refWorktree) remove() {
+	if err := git.RemoveWorktree(context.Background(), w.repoDir, w.dir); err != nil {
+		i18n.Fprintln(os.Stderr, "ref.removeFailed", w.dir, err)
+		return
+	}
+	i18n.Println("ref.removed", w.dir)
+}
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestSanitizeRef(t *testing.T) {
+	for ref, expected := range map[string]string{
+		"main":         "main",
+		"origin/main":  "origin-main",
+		"v1.2.0":       "v1.2.0",
+		"HEAD~1":       "HEAD-1",
+		"..":           "--",
+		".":            "-",
+		".hidden":      "-hidden",
+		"../../etc":    "---..-etc",
+		"feature/a..b": "feature-a..b",
+		"":             "-",
+	} {
+		if got := sanitizeRef(ref); got != expected {
+			t.Errorf("sanitizeRef(%q) = %q, expected %q", ref, got, expected)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/depgraph"
+	"github.com/andro-kes/gokode/internal/git"
+	"github.com/andro-kes/gokode/internal/i18n"
+	"github.com/andro-kes/gokode/internal/report"
+)
+
+// changeScope limits a run to the Go files changed since a revision and the
+// packages affected by them. A nil changeScope means the whole project.
+type changeScope struct {
+	since string
+	// files are the changed Go files that still exist, relative to the project
+	files []string
+	// packages are the changed packages and their reverse dependencies in the
+	// ./dir form accepted by go tools
+	packages []string
+}
+
+// loadScope finds the Go files of the project at path changed since rev and
+// the packages that have to be rechecked because of them
+func loadScope(ctx context.Context, path, rev string) (*changeScope, error) {
+	changed, err := git.ChangedFiles(ctx, path, rev)
+	if err != nil {
+		return nil, fmt.Errorf("error finding files changed since %s: %w", rev, err)
+	}
+
+	scope := &changeScope{since: rev}
+	var goFiles []string
+	moduleChanged := false
+	for _, file := range changed {
+		rel, err := filepath.Rel(path, file)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		switch filepath.Base(file) {
+		case "go.mod", "go.sum", "go.work":
+			moduleChanged = true
+		}
+		if filepath.Ext(file) != ".go" {
+			continue
+		}
+		goFiles = append(goFiles, file)
+		if _, err := os.Stat(file); err == nil {
+			scope.files = append(scope.files, rel)
+		}
+	}
+	if len(goFiles) == 0 && !moduleChanged {
+		return scope, nil
+	}
+
+	graph, err := depgraph.Load(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var changedPkgs []string
+	if moduleChanged {
+		// A dependency change can affect any package
+		for importPath := range graph.Packages {
+			changedPkgs = append(changedPkgs, importPath)
+		}
+	}
+	for _, file := range goFiles {
+		if pkg := graph.PackageForFile(file); pkg != nil {
+			changedPkgs = append(changedPkgs, pkg.ImportPath)
+		}
+	}
+	for _, importPath := range graph.Affected(changedPkgs) {
+		if dir := graph.RelDir(importPath); dir != "" {
+			scope.packages = append(scope.packages, dir)
+		}
+	}
+	sort.Strings(scope.files)
+	return scope, nil
+}
+
+// goFiles returns the changed files for file-based tools, or nil for the
+// whole project
+func (s *changeScope) goFiles() []string {
+	if s == nil {
+		return nil
+	}
+	return s.files
+}
+
+// goPackages returns the affected packages for package-based tools, or nil
+// for the whole project
+func (s *changeScope) goPackages() []string {
+	if s == nil {
+		return nil
+	}
+	return s.packages
+}
+
+// key identifies the scope in cache keys and checkpoints
+func (s *changeScope) key() string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(s.files, ",") + "\x00" + strings.Join(s.packages, ",")
+}
+
+// partial describes the scope in run.json
+func (s *changeScope) partial() *report.PartialRun {
+	if s == nil {
+		return nil
+	}
+	return &report.PartialRun{
+		Since:        s.since,
+		ChangedFiles: s.files,
+		Packages:     s.packages,
+	}
+}
+
+// print reports what the run is limited to
+func (s *changeScope) print() {
+	if s == nil {
+		return
+	}
+	i18n.Println("since.partial", len(s.files), len(s.packages), s.since)
+}
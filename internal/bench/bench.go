@@ -0,0 +1,194 @@
+package bench
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/fileutil"
+)
+
+// File is the name of the benchmark results in the metrics directory
+const File = "bench.json"
+
+// Benchmark holds the measurements of a benchmark over all runs
+type Benchmark struct {
+	Package string `json:"package"`
+	// Name is the name without the GOMAXPROCS suffix, e.g. BenchmarkParse
+	// for BenchmarkParse-8, so results from machines with a different
+	// number of CPUs can be compared
+	Name string `json:"name"`
+	// Procs is the GOMAXPROCS the benchmark ran with
+	Procs int `json:"procs"`
+	// Values are the measurements by unit, e.g. "ns/op", one per run
+	Values map[string][]float64 `json:"values"`
+}
+
+// unitOrder lists the units go test reports by default first
+var unitOrder = map[string]int{"ns/op": 0, "B/op": 1, "allocs/op": 2}
+
+// Units returns the units measured, the standard ones first
+func (b Benchmark) Units() []string {
+	units := make([]string, 0, len(b.Values))
+	for unit := range b.Values {
+		units = append(units, unit)
+	}
+	sort.Slice(units, func(i, j int) bool {
+		oi, iok := unitOrder[units[i]]
+		oj, jok := unitOrder[units[j]]
+		if iok != jok {
+			return iok
+		}
+		if iok {
+			return oi < oj
+		}
+		return units[i] < units[j]
+	})
+	return units
+}
+
+// Parse parses go test -bench output. Measurements of the same benchmark
+// from several runs, e.g. with -count, are collected together.
+func Parse(r io.Reader) ([]Benchmark, error) {
+	var benchmarks []Benchmark
+	index := make(map[string]int)
+	pkg := ""
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "pkg: "); ok {
+			pkg = strings.TrimSpace(name)
+			continue
+		}
+		fullName, values, ok := parseLine(line)
+		if !ok {
+			continue
+		}
+		name, procs := SplitProcs(fullName)
+
+		// With -cpu the same benchmark runs with several GOMAXPROCS values
+		key := pkg + "\x00" + name + "\x00" + strconv.Itoa(procs)
+		i, ok := index[key]
+		if !ok {
+			i = len(benchmarks)
+			index[key] = i
+			benchmarks = append(benchmarks, Benchmark{Package: pkg, Name: name, Procs: procs, Values: make(map[string][]float64)})
+		}
+		for unit, value := range values {
+			benchmarks[i].Values[unit] = append(benchmarks[i].Values[unit], value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return benchmarks, nil
+}
+
+// SplitProcs splits the GOMAXPROCS suffix go test appends to benchmark names
+// off name, e.g. BenchmarkParse-8 into BenchmarkParse and 8. Without a suffix
+// the benchmark ran with GOMAXPROCS=1.
+func SplitProcs(name string) (string, int) {
+	i := strings.LastIndexByte(name, '-')
+	if i <= 0 {
+		return name, 1
+	}
+	procs, err := strconv.Atoi(name[i+1:])
+	if err != nil || procs <= 0 {
+		return name, 1
+	}
+	return name[:i], procs
+}
+
+// parseLine parses a result line of the form
+// BenchmarkName-8  1000  1234 ns/op  16 B/op  1 allocs/op
+func parseLine(line string) (string, map[string]float64, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || len(fields)%2 != 0 || !strings.HasPrefix(fields[0], "Benchmark") {
+		return "", nil, false
+	}
+	if _, err := strconv.ParseInt(fields[1], 10, 64); err != nil {
+		return "", nil, false
+	}
+
+	values := make(map[string]float64)
+	for i := 2; i < len(fields); i += 2 {
+		value, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return "", nil, false
+		}
+		values[fields[i+1]] = value
+	}
+	return fields[0], values, true
+}
+
+// FormatValue formats a measurement with four significant digits, large
+// values without an exponent
+func FormatValue(v float64) string {
+	if v >= 1000 || v <= -1000 {
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+// Report is the result of a benchmark run and its comparison with a
+// baseline run
+type Report struct {
+	// Count is the -count the benchmarks were run with
+	Count      int         `json:"count"`
+	Benchmarks []Benchmark `json:"benchmarks"`
+	// Baseline describes the results compared against, empty when there
+	// were none
+	Baseline string  `json:"baseline,omitempty"`
+	Deltas   []Delta `json:"deltas,omitempty"`
+	// Gate is the verdict of the benchmark regression gate, nil when the
+	// gate is not configured or there was no baseline
+	Gate *Gate `json:"gate,omitempty"`
+}
+
+// Gate is the result of the benchmark regression gate, laid out like the
+// gate results of a run
+type Gate struct {
+	Name string `json:"name"`
+	// Actual and Threshold are formatted for display, e.g. "12.5%"
+	Actual    string `json:"actual"`
+	Threshold string `json:"threshold"`
+	Passed    bool   `json:"passed"`
+}
+
+// Regressions returns the significant changes for the worse by more than
+// maxPercent, worst first
+func (r *Report) Regressions(maxPercent float64) []Delta {
+	var regressions []Delta
+	for _, delta := range r.Deltas {
+		if delta.Significant && delta.Regression() > maxPercent {
+			regressions = append(regressions, delta)
+		}
+	}
+	sort.SliceStable(regressions, func(i, j int) bool {
+		return regressions[i].Regression() > regressions[j].Regression()
+	})
+	return regressions
+}
+
+// Write atomically writes the report as JSON
+func Write(name string, report *Report) error {
+	return fileutil.WriteJSON(name, report, "benchmark results")
+}
+
+// Read reads a report written by Write
+func Read(name string) (*Report, error) {
+	var report Report
+	if err := fileutil.ReadJSON(name, &report, "benchmark results"); err != nil {
+		return nil, err
+	}
+	// Results written before Procs was recorded keep the suffix in the name
+	for i, b := range report.Benchmarks {
+		if b.Procs == 0 {
+			report.Benchmarks[i].Name, report.Benchmarks[i].Procs = SplitProcs(b.Name)
+		}
+	}
+	return &report, nil
+}
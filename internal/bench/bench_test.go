@@ -0,0 +1,217 @@
+package bench
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleOutput = `goos: linux
+goarch: amd64
+pkg: example.com/m/parser
+cpu: AMD EPYC
+BenchmarkParse-8   	  1000	      1200 ns/op	     256 B/op	       4 allocs/op
+BenchmarkParse-8   	  1000	      1100 ns/op	     256 B/op	       4 allocs/op
+BenchmarkRead-8    	   500	      3000 ns/op	  341.33 MB/s	       0 B/op	       0 allocs/op
+PASS
+ok  	example.com/m/parser	3.210s
+goos: linux
+goarch: amd64
+pkg: example.com/m/server
+BenchmarkParse-8   	  2000	       900 ns/op
+BenchmarkBroken-8  	--- FAIL: BenchmarkBroken-8
+PASS
+ok  	example.com/m/server	1.020s
+`
+
+func TestParse(t *testing.T) {
+	benchmarks, err := Parse(strings.NewReader(sampleOutput))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(benchmarks) != 3 {
+		t.Fatalf("Expected 3 benchmarks, got %d: %+v", len(benchmarks), benchmarks)
+	}
+
+	parse := benchmarks[0]
+	if parse.Package != "example.com/m/parser" || parse.Name != "BenchmarkParse" || parse.Procs != 8 {
+		t.Errorf("Unexpected benchmark: %s %s-%d", parse.Package, parse.Name, parse.Procs)
+	}
+	if !reflect.DeepEqual(parse.Values["ns/op"], []float64{1200, 1100}) {
+		t.Errorf("Unexpected ns/op: %v", parse.Values["ns/op"])
+	}
+	if !reflect.DeepEqual(benchmarks[1].Units(), []string{"ns/op", "B/op", "allocs/op", "MB/s"}) {
+		t.Errorf("Unexpected units: %v", benchmarks[1].Units())
+	}
+	if benchmarks[2].Package != "example.com/m/server" {
+		t.Errorf("Expected the same benchmark name in another package to be separate, got %+v", benchmarks[2])
+	}
+}
+
+func TestSplitProcs(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		expected string
+		procs    int
+	}{
+		{"BenchmarkParse-8", "BenchmarkParse", 8},
+		{"BenchmarkParse", "BenchmarkParse", 1},
+		{"BenchmarkSize/n-1024-16", "BenchmarkSize/n-1024", 16},
+		{"BenchmarkSize/a-b", "BenchmarkSize/a-b", 1},
+	} {
+		name, procs := SplitProcs(tc.name)
+		if name != tc.expected || procs != tc.procs {
+			t.Errorf("SplitProcs(%q) = %q, %d, expected %q, %d", tc.name, name, procs, tc.expected, tc.procs)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	values := []float64{10, 1, 9, 2, 8, 3, 7, 4, 6, 5}
+	sample := Summarize(values, Confidence)
+	if sample.N != 10 || sample.Median != 5.5 {
+		t.Errorf("Unexpected median: %+v", sample)
+	}
+	// For 10 values the 2nd and 9th order statistics bound a 97.9% interval,
+	// the 3rd and 8th only an 89.1% one
+	if sample.Low != 2 || sample.High != 9 {
+		t.Errorf("Expected interval [2, 9], got [%v, %v]", sample.Low, sample.High)
+	}
+	if math.Abs(sample.Confidence-0.9785) > 0.001 {
+		t.Errorf("Unexpected confidence: %v", sample.Confidence)
+	}
+
+	// Five values cannot reach 95%, the full range is used
+	small := Summarize([]float64{3, 1, 2, 5, 4}, Confidence)
+	if small.Median != 3 || small.Low != 1 || small.High != 5 || math.Abs(small.Confidence-0.9375) > 1e-9 {
+		t.Errorf("Unexpected summary of five values: %+v", small)
+	}
+}
+
+func TestMannWhitney(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y []float64
+		want float64
+	}{
+		// The only more extreme ordering is the reverse one: 2/C(10,5)
+		{"separated", []float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 2.0 / 252},
+		{"identical", []float64{1, 1, 1}, []float64{1, 1, 1}, 1},
+		{"interleaved", []float64{1, 3, 5, 7}, []float64{2, 4, 6, 8}, 0.6857},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MannWhitney(tt.x, tt.y); math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("MannWhitney = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Ties use the normal approximation, which still finds a clear shift
+	tied := MannWhitney([]float64{1, 1, 2, 2, 2, 3}, []float64{5, 5, 6, 6, 7, 7})
+	if tied >= Alpha {
+		t.Errorf("Expected a significant difference with ties, got p=%v", tied)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	base := []Benchmark{
+		{Package: "p", Name: "BenchmarkA-8", Values: map[string][]float64{
+			"ns/op":     {100, 101, 99, 100, 102, 98},
+			"allocs/op": {0, 0, 0, 0, 0, 0},
+		}},
+		{Package: "p", Name: "BenchmarkB-8", Values: map[string][]float64{
+			"ns/op": {100, 101, 99, 100, 102, 98},
+			"MB/s":  {10, 10.1, 9.9, 10, 10.2, 9.8},
+		}},
+		{Package: "p", Name: "BenchmarkGone-8", Values: map[string][]float64{"ns/op": {1}}},
+	}
+	head := []Benchmark{
+		{Package: "p", Name: "BenchmarkA-8", Values: map[string][]float64{
+			"ns/op":     {130, 131, 129, 130, 132, 128},
+			"allocs/op": {2, 2, 2, 2, 2, 2},
+		}},
+		{Package: "p", Name: "BenchmarkB-8", Values: map[string][]float64{
+			"ns/op": {99, 102, 100, 98, 101, 100},
+			"MB/s":  {8, 8.1, 7.9, 8, 8.2, 7.8},
+		}},
+		{Package: "p", Name: "BenchmarkNew-8", Values: map[string][]float64{"ns/op": {1}}},
+	}
+
+	deltas := Compare(base, head)
+	if len(deltas) != 4 {
+		t.Fatalf("Expected 4 deltas, got %d: %+v", len(deltas), deltas)
+	}
+
+	byKey := make(map[string]Delta)
+	for _, d := range deltas {
+		byKey[d.Name+" "+d.Unit] = d
+	}
+	if d := byKey["BenchmarkA-8 ns/op"]; !d.Significant || math.Abs(d.Change-30) > 0.01 {
+		t.Errorf("Expected a significant +30%% change, got %+v", d)
+	}
+	if d := byKey["BenchmarkA-8 allocs/op"]; d.Change != 200 {
+		t.Errorf("Expected the change from zero allocations to be measured against one, got %v", d.Change)
+	}
+	if d := byKey["BenchmarkB-8 ns/op"]; d.Significant {
+		t.Errorf("Expected noise not to be significant, got p=%v", d.P)
+	}
+	if d := byKey["BenchmarkB-8 MB/s"]; !d.Significant || math.Abs(d.Regression()-20) > 0.01 {
+		t.Errorf("Expected lower throughput to be a 20%% regression, got %v", d.Regression())
+	}
+
+	report := &Report{Deltas: deltas}
+	regressions := report.Regressions(25)
+	if len(regressions) != 2 || regressions[0].Unit != "allocs/op" || regressions[1].Unit != "ns/op" {
+		t.Errorf("Unexpected regressions beyond 25%%: %+v", regressions)
+	}
+}
+
+func TestCompareAcrossProcs(t *testing.T) {
+	parse := func(output string) []Benchmark {
+		t.Helper()
+		benchmarks, err := Parse(strings.NewReader("pkg: example.com/m\n" + output))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return benchmarks
+	}
+	// The baseline comes from an 8-core machine and the run from a 16-core CI
+	// runner
+	base := parse(strings.Repeat("BenchmarkX-8   1000   100 ns/op\n", 6))
+	head := parse(strings.Repeat("BenchmarkX-16  1000   150 ns/op\n", 6))
+
+	deltas := Compare(base, head)
+	if len(deltas) != 1 {
+		t.Fatalf("Expected BenchmarkX-8 to be compared with BenchmarkX-16, got %+v", deltas)
+	}
+	if d := deltas[0]; d.Name != "BenchmarkX" || d.Procs != 16 || !d.Significant || math.Abs(d.Change-50) > 0.01 {
+		t.Errorf("Unexpected delta: %+v", d)
+	}
+
+	// With -cpu the runs with the same GOMAXPROCS are compared
+	base = parse("BenchmarkX     1000   100 ns/op\nBenchmarkX-4   1000   400 ns/op\n")
+	head = parse("BenchmarkX     1000   110 ns/op\nBenchmarkX-4   1000   440 ns/op\n")
+	deltas = Compare(base, head)
+	if len(deltas) != 2 || deltas[0].Base.Median != 100 || deltas[1].Base.Median != 400 {
+		t.Errorf("Expected runs to be matched by GOMAXPROCS, got %+v", deltas)
+	}
+}
+
+func TestReadLegacyNames(t *testing.T) {
+	name := filepath.Join(t.TempDir(), File)
+	legacy := `{"count": 1, "benchmarks": [{"package": "p", "name": "BenchmarkX-8", "values": {"ns/op": [1]}}]}`
+	if err := os.WriteFile(name, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", File, err)
+	}
+	report, err := Read(name)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if b := report.Benchmarks[0]; b.Name != "BenchmarkX" || b.Procs != 8 {
+		t.Errorf("Expected the suffix of an old result to be split off, got %s %d", b.Name, b.Procs)
+	}
+}
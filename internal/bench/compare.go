@@ -0,0 +1,88 @@
+package bench
+
+import "math"
+
+// Delta compares a benchmark measurement between the baseline and this run
+type Delta struct {
+	Package string `json:"package"`
+	Name    string `json:"name"`
+	// Procs is the GOMAXPROCS of this run
+	Procs int    `json:"procs"`
+	Unit  string `json:"unit"`
+	Base  Sample `json:"base"`
+	Head  Sample `json:"head"`
+	// Change is the relative change of the median in percent
+	Change float64 `json:"change"`
+	// P is the p-value of the Mann-Whitney U test
+	P float64 `json:"p"`
+	// Significant is set when P is below Alpha
+	Significant bool `json:"significant"`
+}
+
+// Regression returns by how many percent the benchmark got worse, negative
+// for an improvement
+func (d Delta) Regression() float64 {
+	if higherIsBetter(d.Unit) {
+		return -d.Change
+	}
+	return d.Change
+}
+
+// Compare compares the benchmarks present in both base and head, unit by
+// unit. A benchmark is matched by name with the run of the same GOMAXPROCS,
+// or with its only run in base, as when the baseline comes from a machine
+// with a different number of CPUs.
+func Compare(base, head []Benchmark) []Delta {
+	baseIndex := make(map[string][]Benchmark, len(base))
+	for _, b := range base {
+		key := b.Package + "\x00" + b.Name
+		baseIndex[key] = append(baseIndex[key], b)
+	}
+
+	var deltas []Delta
+	for _, h := range head {
+		b, ok := matchProcs(baseIndex[h.Package+"\x00"+h.Name], h.Procs)
+		if !ok {
+			continue
+		}
+		for _, unit := range h.Units() {
+			baseValues, ok := b.Values[unit]
+			if !ok {
+				continue
+			}
+			delta := Delta{
+				Package: h.Package,
+				Name:    h.Name,
+				Procs:   h.Procs,
+				Unit:    unit,
+				Base:    Summarize(baseValues, Confidence),
+				Head:    Summarize(h.Values[unit], Confidence),
+				P:       MannWhitney(baseValues, h.Values[unit]),
+			}
+			// A change from zero, e.g. the first allocation, has no
+			// relative size, so it is measured against one unit
+			denominator := math.Abs(delta.Base.Median)
+			if denominator == 0 {
+				denominator = 1
+			}
+			delta.Change = (delta.Head.Median - delta.Base.Median) / denominator * 100
+			delta.Significant = delta.P < Alpha
+			deltas = append(deltas, delta)
+		}
+	}
+	return deltas
+}
+
+// matchProcs returns the run among candidates, the runs of one benchmark,
+// with procs GOMAXPROCS, or the only run if there is one
+func matchProcs(candidates []Benchmark, procs int) (Benchmark, bool) {
+	for _, b := range candidates {
+		if b.Procs == procs {
+			return b, true
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+	return Benchmark{}, false
+}
@@ -0,0 +1,199 @@
+package bench
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// Alpha is the significance level below which a change is reported
+	Alpha = 0.05
+	// Confidence is the level of the confidence intervals around medians
+	Confidence = 0.95
+)
+
+// Sample summarizes the measurements of a benchmark in one unit
+type Sample struct {
+	N      int     `json:"n"`
+	Median float64 `json:"median"`
+	// Low and High bound the confidence interval of the median
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+	// Confidence is the level of the interval; with few measurements it is
+	// lower than requested
+	Confidence float64 `json:"confidence"`
+}
+
+// Spread returns the larger distance from the median to the interval bounds
+// relative to the median, in percent
+func (s Sample) Spread() float64 {
+	if s.Median == 0 {
+		return 0
+	}
+	return math.Max(s.Median-s.Low, s.High-s.Median) / math.Abs(s.Median) * 100
+}
+
+// Summarize returns the median of values and a distribution-free confidence
+// interval for it at the given level, computed from order statistics as
+// benchstat does
+func Summarize(values []float64, confidence float64) Sample {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	sample := Sample{N: n}
+	if n == 0 {
+		return sample
+	}
+
+	if n%2 == 1 {
+		sample.Median = sorted[n/2]
+	} else {
+		sample.Median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+
+	// The interval [x(k), x(n+1-k)] covers the median with probability
+	// 1 - 2*P(B < k) for B ~ Binomial(n, 1/2); the narrowest interval
+	// reaching the level is used, or the widest one if none does
+	k := 1
+	sample.Confidence = 1 - 2*binomialCDF(0, n)
+	for j := 2; j <= n/2; j++ {
+		level := 1 - 2*binomialCDF(j-1, n)
+		if level < confidence {
+			break
+		}
+		k, sample.Confidence = j, level
+	}
+	sample.Low, sample.High = sorted[k-1], sorted[n-k]
+	return sample
+}
+
+// binomialCDF returns P(B <= k) for B ~ Binomial(n, 1/2)
+func binomialCDF(k, n int) float64 {
+	sum := 0.0
+	for i := 0; i <= k; i++ {
+		sum += math.Exp(logChoose(n, i) - float64(n)*math.Ln2)
+	}
+	return sum
+}
+
+func logChoose(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// MannWhitney returns the two-sided p-value of the Mann-Whitney U test of
+// whether x and y come from the same distribution. Without ties the exact
+// distribution of U is used, otherwise the normal approximation with a tie
+// correction.
+func MannWhitney(x, y []float64) float64 {
+	n1, n2 := len(x), len(y)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	type value struct {
+		v     float64
+		first bool
+	}
+	all := make([]value, 0, n1+n2)
+	for _, v := range x {
+		all = append(all, value{v, true})
+	}
+	for _, v := range y {
+		all = append(all, value{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	// Tied values get the mean of the ranks they span
+	rankSum := 0.0
+	tieTerm := 0.0
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for _, v := range all[i:j] {
+			if v.first {
+				rankSum += rank
+			}
+		}
+		t := float64(j - i)
+		tieTerm += t*t*t - t
+		i = j
+	}
+	u := rankSum - float64(n1*(n1+1))/2
+
+	if tieTerm == 0 {
+		return exactMannWhitney(u, n1, n2)
+	}
+
+	n := float64(n1 + n2)
+	mean := float64(n1*n2) / 2
+	variance := float64(n1*n2) / 12 * ((n + 1) - tieTerm/(n*(n-1)))
+	if variance <= 0 {
+		return 1
+	}
+	z := (math.Abs(u-mean) - 0.5) / math.Sqrt(variance)
+	if z < 0 {
+		return 1
+	}
+	return math.Min(1, math.Erfc(z/math.Sqrt2))
+}
+
+// exactMannWhitney returns the two-sided p-value of U from its exact
+// distribution, counting the orderings of n1 and n2 values with each U
+func exactMannWhitney(u float64, n1, n2 int) float64 {
+	// counts[i][j][k] is the number of orderings of i and j values in
+	// which the first values exceed k pairs; only the layers for the
+	// current i are kept
+	maxU := n1 * n2
+	prev := make([][]float64, n2+1)
+	for j := range prev {
+		prev[j] = make([]float64, maxU+1)
+		prev[j][0] = 1
+	}
+	for i := 1; i <= n1; i++ {
+		cur := make([][]float64, n2+1)
+		for j := range cur {
+			cur[j] = make([]float64, maxU+1)
+			for k := 0; k <= i*j; k++ {
+				// The largest value belongs either to the first group,
+				// exceeding all j values of the second, or to the second
+				if k >= j {
+					cur[j][k] += prev[j][k-j]
+				}
+				if j > 0 {
+					cur[j][k] += cur[j-1][k]
+				}
+			}
+		}
+		prev = cur
+	}
+
+	counts := prev[n2]
+	total := 0.0
+	for _, c := range counts {
+		total += c
+	}
+	k := int(math.Round(u))
+	below, above := 0.0, 0.0
+	for i, c := range counts {
+		if i <= k {
+			below += c
+		}
+		if i >= k {
+			above += c
+		}
+	}
+	return math.Min(1, 2*math.Min(below, above)/total)
+}
+
+// higherIsBetter reports whether larger values of the unit are improvements,
+// as for throughput like MB/s
+func higherIsBetter(unit string) bool {
+	return strings.HasSuffix(unit, "/s")
+}
@@ -0,0 +1,227 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/andro-kes/gokode/internal/fileutil"
+)
+
+// entryFile describes a cache entry and is stored next to its artifacts
+const entryFile = "entry.json"
+
+// Cache stores the artifacts of analysis steps keyed by a hash of their inputs
+type Cache struct {
+	Dir string
+}
+
+// Entry describes a cached step result
+type Entry struct {
+	Step      string    `json:"step"`
+	Key       string    `json:"key"`
+	Artifacts []string  `json:"artifacts"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// StepStats contains cache usage for a single step
+type StepStats struct {
+	Step    string
+	Entries int
+	Size    int64
+}
+
+// Stats contains cache usage
+type Stats struct {
+	Dir     string
+	Entries int
+	Size    int64
+	Steps   []StepStats
+}
+
+// DefaultDir returns $XDG_CACHE_HOME/gokode, falling back to the platform's
+// user cache directory
+func DefaultDir() (string, error) {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "gokode"), nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating cache directory: %w", err)
+	}
+	return filepath.Join(dir, "gokode"), nil
+}
+
+// Open opens the cache rooted at dir, creating it if needed
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating cache directory: %w", err)
+	}
+	return &Cache{Dir: dir}, nil
+}
+
+func (c *Cache) entryDir(key string) string {
+	return filepath.Join(c.Dir, key[:2], key)
+}
+
+// Get restores the artifacts stored under key into metricsDir. It reports
+// false if there is no entry for key.
+func (c *Cache) Get(key, metricsDir string) (bool, error) {
+	dir := c.entryDir(key)
+	data, err := os.ReadFile(filepath.Join(dir, entryFile))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error reading cache entry: %w", err)
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return false, fmt.Errorf("error parsing cache entry: %w", err)
+	}
+
+	for _, name := range entry.Artifacts {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return false, fmt.Errorf("error reading cached %s: %w", name, err)
+		}
+		if err := fileutil.WriteFile(filepath.Join(metricsDir, name), content, 0644); err != nil {
+			return false, fmt.Errorf("error restoring %s: %w", name, err)
+		}
+	}
+
+	return true, nil
+}
+
+// Put stores the named artifacts from metricsDir under key
+func (c *Cache) Put(key, step, metricsDir string, artifacts []string) error {
+	dir := c.entryDir(key)
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return fmt.Errorf("error creating cache entry: %w", err)
+	}
+
+	// Fill a temporary directory first so a partially written entry is
+	// never visible under its key
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), ".tmp-")
+	if err != nil {
+		return fmt.Errorf("error creating cache entry: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, name := range artifacts {
+		content, err := os.ReadFile(filepath.Join(metricsDir, name))
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(tmp, name), content, 0644); err != nil {
+			return fmt.Errorf("error caching %s: %w", name, err)
+		}
+	}
+
+	entry := Entry{
+		Step:      step,
+		Key:       key,
+		Artifacts: artifacts,
+		CreatedAt: time.Now(),
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding cache entry: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmp, entryFile), data, 0644); err != nil {
+		return fmt.Errorf("error writing cache entry: %w", err)
+	}
+
+	// Replace any previous entry for the same key
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("error replacing cache entry: %w", err)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return fmt.Errorf("error writing cache entry: %w", err)
+	}
+	return nil
+}
+
+// Stats walks the cache and reports the number and size of entries per step
+func (c *Cache) Stats() (*Stats, error) {
+	stats := &Stats{Dir: c.Dir}
+	byStep := make(map[string]*StepStats)
+
+	err := filepath.WalkDir(c.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != entryFile {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var entry Entry
+		if json.Unmarshal(data, &entry) != nil {
+			return nil
+		}
+
+		size, err := dirSize(filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+
+		step, ok := byStep[entry.Step]
+		if !ok {
+			step = &StepStats{Step: entry.Step}
+			byStep[entry.Step] = step
+		}
+		step.Entries++
+		step.Size += size
+		stats.Entries++
+		stats.Size += size
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading cache: %w", err)
+	}
+
+	for _, step := range byStep {
+		stats.Steps = append(stats.Steps, *step)
+	}
+	sort.Slice(stats.Steps, func(i, j int) bool {
+		return stats.Steps[i].Step < stats.Steps[j].Step
+	})
+
+	return stats, nil
+}
+
+// Clean removes all cache entries
+func (c *Cache) Clean() error {
+	if err := os.RemoveAll(c.Dir); err != nil {
+		return fmt.Errorf("error cleaning cache: %w", err)
+	}
+	return nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
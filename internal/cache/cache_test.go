@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSourcesHash(t *testing.T) {
+	projectDir := t.TempDir()
+	metricsDir := filepath.Join(projectDir, "metrics")
+	if err := os.MkdirAll(metricsDir, 0755); err != nil {
+		t.Fatalf("Failed to create metrics dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write main.go: %v", err)
+	}
+
+	first, err := SourcesHash(projectDir, metricsDir)
+	if err != nil {
+		t.Fatalf("SourcesHash failed: %v", err)
+	}
+
+	// Files that are not analysis inputs must not change the hash
+	if err := os.WriteFile(filepath.Join(metricsDir, "vet.txt"), []byte("issue"), 0644); err != nil {
+		t.Fatalf("Failed to write vet.txt: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "README.md"), []byte("docs"), 0644); err != nil {
+		t.Fatalf("Failed to write README.md: %v", err)
+	}
+	second, err := SourcesHash(projectDir, metricsDir)
+	if err != nil {
+		t.Fatalf("SourcesHash failed: %v", err)
+	}
+	if first != second {
+		t.Error("Expected hash to ignore metrics and non-Go files")
+	}
+
+	if err := os.WriteFile(filepath.Join(projectDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write main.go: %v", err)
+	}
+	third, err := SourcesHash(projectDir, metricsDir)
+	if err != nil {
+		t.Fatalf("SourcesHash failed: %v", err)
+	}
+	if first == third {
+		t.Error("Expected hash to change when a Go source changes")
+	}
+}
+
+func TestSourcesHashFixtures(t *testing.T) {
+	projectDir := t.TempDir()
+	fixture := filepath.Join(projectDir, "jsoner", "test", "test.json")
+	if err := os.MkdirAll(filepath.Dir(fixture), 0755); err != nil {
+		t.Fatalf("Failed to create fixture dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "jsoner", "json_test.go"), []byte("package jsoner\n"), 0644); err != nil {
+		t.Fatalf("Failed to write json_test.go: %v", err)
+	}
+	if err := os.WriteFile(fixture, []byte(`{"a": 1}`), 0644); err != nil {
+		t.Fatalf("Failed to write test.json: %v", err)
+	}
+
+	first, err := SourcesHash(projectDir)
+	if err != nil {
+		t.Fatalf("SourcesHash failed: %v", err)
+	}
+
+	// A fixture outside testdata is still read by the package's tests
+	if err := os.WriteFile(fixture, []byte(`{"a": 2}`), 0644); err != nil {
+		t.Fatalf("Failed to write test.json: %v", err)
+	}
+	second, err := SourcesHash(projectDir)
+	if err != nil {
+		t.Fatalf("SourcesHash failed: %v", err)
+	}
+	if first == second {
+		t.Error("Expected hash to change when a fixture next to a package changes")
+	}
+
+	// Files outside any package are not read by tests
+	if err := os.WriteFile(filepath.Join(projectDir, "notes.txt"), []byte("todo"), 0644); err != nil {
+		t.Fatalf("Failed to write notes.txt: %v", err)
+	}
+	third, err := SourcesHash(projectDir)
+	if err != nil {
+		t.Fatalf("SourcesHash failed: %v", err)
+	}
+	if second != third {
+		t.Error("Expected hash to ignore files outside packages")
+	}
+}
+
+func TestKey(t *testing.T) {
+	env := Environment{GoVersion: "go1.24.2", Tools: map[string]string{"gocyclo": "v0.6.0"}}
+	key := Key("Vet", "/project", "abc", env)
+
+	if key != Key("Vet", "/project", "abc", env) {
+		t.Error("Expected key to be deterministic")
+	}
+	if key == Key("Lint", "/project", "abc", env) {
+		t.Error("Expected key to depend on the step")
+	}
+
+	newer := Environment{GoVersion: "go1.25.0", Tools: env.Tools}
+	if key == Key("Vet", "/project", "abc", newer) {
+		t.Error("Expected key to depend on the Go version")
+	}
+}
+
+func TestGetPut(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	key := Key("Vet", "/project", "abc", Environment{})
+
+	metricsDir := t.TempDir()
+	if hit, err := c.Get(key, metricsDir); err != nil || hit {
+		t.Fatalf("Expected cache miss, got hit=%v err=%v", hit, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(metricsDir, "vet.txt"), []byte("issue"), 0644); err != nil {
+		t.Fatalf("Failed to write vet.txt: %v", err)
+	}
+	if err := c.Put(key, "Vet", metricsDir, []string{"vet.txt"}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	restoreDir := t.TempDir()
+	hit, err := c.Get(key, restoreDir)
+	if err != nil || !hit {
+		t.Fatalf("Expected cache hit, got hit=%v err=%v", hit, err)
+	}
+	data, err := os.ReadFile(filepath.Join(restoreDir, "vet.txt"))
+	if err != nil {
+		t.Fatalf("Expected vet.txt to be restored: %v", err)
+	}
+	if string(data) != "issue" {
+		t.Errorf("Expected restored content %q, got %q", "issue", string(data))
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Entries != 1 || len(stats.Steps) != 1 || stats.Steps[0].Step != "Vet" {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	if err := c.Clean(); err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+	if hit, _ := c.Get(key, restoreDir); hit {
+		t.Error("Expected cache miss after Clean")
+	}
+}
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Environment contains the toolchain versions that affect step results
+type Environment struct {
+	GoVersion string
+	Tools     map[string]string
+}
+
+// SourcesHash hashes the inputs of analysis steps found under projectDir: Go
+// sources, module files, linter and gokode configuration, testdata and any
+// other file in a package directory or below one, since tests may read
+// fixtures from anywhere next to their package. Directories in skipDirs
+// (typically the metrics directory) are ignored.
+func SourcesHash(projectDir string, skipDirs ...string) (string, error) {
+	skip := make(map[string]bool, len(skipDirs))
+	for _, dir := range skipDirs {
+		skip[filepath.Clean(dir)] = true
+	}
+
+	// Whether a file is a fixture depends on the packages above it, so the
+	// files are listed before any of them is hashed
+	var files []string
+	packages := make(map[string]bool)
+	err := filepath.WalkDir(projectDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			// Skip output directories and those the go tool ignores
+			if path != projectDir && (skip[path] || strings.HasPrefix(d.Name(), ".") || strings.HasPrefix(d.Name(), "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(projectDir, path)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(rel, ".go") {
+			packages[filepath.Dir(rel)] = true
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error hashing sources: %w", err)
+	}
+
+	h := sha256.New()
+	for _, rel := range files {
+		if !isInput(rel) && !isFixture(rel, packages) {
+			continue
+		}
+		sum, err := fileHash(filepath.Join(projectDir, rel))
+		if err != nil {
+			return "", fmt.Errorf("error hashing sources: %w", err)
+		}
+		fmt.Fprintf(h, "%s\x00%s\n", filepath.ToSlash(rel), sum)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Key combines everything a step's result depends on into a cache key
+func Key(step, projectDir, sourcesHash string, env Environment) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "step=%s\nproject=%s\nsources=%s\ngo=%s\n", step, projectDir, sourcesHash, env.GoVersion)
+
+	names := make([]string, 0, len(env.Tools))
+	for name := range env.Tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(h, "tool=%s@%s\n", name, env.Tools[name])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// isInput reports whether the file at rel affects analysis results
+func isInput(rel string) bool {
+	base := filepath.Base(rel)
+	switch {
+	case strings.HasSuffix(base, ".go"):
+		return true
+	case base == "go.mod", base == "go.sum", base == "go.work", base == "go.work.sum":
+		return true
+	case strings.HasPrefix(base, ".golangci."), base == ".gokode.json":
+		return true
+	}
+
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		if part == "testdata" {
+			return true
+		}
+	}
+	return false
+}
+
+// isFixture reports whether the file at rel lies in one of the package
+// directories or below one, where its tests may read it. Documentation is
+// not a fixture.
+func isFixture(rel string, packages map[string]bool) bool {
+	if strings.EqualFold(filepath.Ext(rel), ".md") {
+		return false
+	}
+	for dir := filepath.Dir(rel); ; dir = filepath.Dir(dir) {
+		if packages[dir] {
+			return true
+		}
+		if dir == "." || dir == string(filepath.Separator) {
+			return false
+		}
+	}
+}
+
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
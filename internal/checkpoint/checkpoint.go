@@ -0,0 +1,80 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/andro-kes/gokode/internal/fileutil"
+)
+
+// File is the name of the checkpoint file in the metrics directory
+const File = "checkpoint.json"
+
+// Step records a successfully completed analysis step
+type Step struct {
+	Inputs      string    `json:"inputs"`
+	CompletedAt time.Time `json:"completedAt"`
+}
+
+// Checkpoint records which analysis steps completed and for which inputs, so
+// an interrupted or failed run can be resumed
+type Checkpoint struct {
+	Steps map[string]Step `json:"steps"`
+	path  string
+}
+
+// New returns an empty checkpoint stored in metricsDir
+func New(metricsDir string) *Checkpoint {
+	return &Checkpoint{
+		Steps: make(map[string]Step),
+		path:  filepath.Join(metricsDir, File),
+	}
+}
+
+// Load reads the checkpoint from metricsDir. A missing file yields an empty
+// checkpoint.
+func Load(metricsDir string) (*Checkpoint, error) {
+	cp := New(metricsDir)
+	data, err := os.ReadFile(cp.path)
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("error parsing checkpoint: %w", err)
+	}
+	if cp.Steps == nil {
+		cp.Steps = make(map[string]Step)
+	}
+	return cp, nil
+}
+
+// Done reports whether step completed with the given inputs hash
+func (c *Checkpoint) Done(step, inputs string) bool {
+	done, ok := c.Steps[step]
+	return ok && done.Inputs == inputs
+}
+
+// Mark records step as completed with the given inputs hash and saves the
+// checkpoint
+func (c *Checkpoint) Mark(step, inputs string) error {
+	c.Steps[step] = Step{Inputs: inputs, CompletedAt: time.Now()}
+	return c.Save()
+}
+
+// Save atomically writes the checkpoint file
+func (c *Checkpoint) Save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding checkpoint: %w", err)
+	}
+	if err := fileutil.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("error writing checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package checkpoint
+
+import (
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	cp, err := Load(metricsDir)
+	if err != nil {
+		t.Fatalf("Load failed for missing checkpoint: %v", err)
+	}
+	if cp.Done("Vet", "abc") {
+		t.Error("Expected empty checkpoint to have no completed steps")
+	}
+
+	if err := cp.Mark("Vet", "abc"); err != nil {
+		t.Fatalf("Mark failed: %v", err)
+	}
+
+	loaded, err := Load(metricsDir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !loaded.Done("Vet", "abc") {
+		t.Error("Expected Vet to be completed for the recorded inputs")
+	}
+	if loaded.Done("Vet", "def") {
+		t.Error("Expected Vet to be incomplete when its inputs changed")
+	}
+	if loaded.Done("Lint with fixes", "abc") {
+		t.Error("Expected unrecorded step to be incomplete")
+	}
+}
@@ -0,0 +1,275 @@
+package compare
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andro-kes/gokode/internal/report"
+)
+
+// Issue is a vet or lint issue present in only one of the compared runs
+type Issue struct {
+	Tool   string
+	Linter string
+	File   string
+	Line   int
+	Text   string
+}
+
+// PackageCoverage is the statement coverage of a package in both runs. A
+// negative percentage means the package is missing from that run.
+type PackageCoverage struct {
+	Package string
+	Base    float64
+	Head    float64
+}
+
+// Delta returns the coverage change in percentage points
+func (p PackageCoverage) Delta() float64 {
+	return p.Head - p.Base
+}
+
+// ComplexityChange is a function whose cyclomatic complexity increased
+type ComplexityChange struct {
+	Package  string
+	Function string
+	File     string
+	Line     int
+	Base     int
+	Head     int
+}
+
+// Tests compares test counts and durations
+type Tests struct {
+	BaseCount    int
+	HeadCount    int
+	BaseFailed   int
+	HeadFailed   int
+	BaseDuration time.Duration
+	HeadDuration time.Duration
+}
+
+// Delta describes what changed in quality between two metrics directories
+type Delta struct {
+	BaseDir      string
+	HeadDir      string
+	NewIssues    []Issue
+	FixedIssues  []Issue
+	BaseCoverage float64
+	HeadCoverage float64
+	HasCoverage  bool
+	Packages     []PackageCoverage
+	Complexity   []ComplexityChange
+	Tests        *Tests
+}
+
+// artifacts are the metrics files a comparison is made from
+var artifacts = []string{"vet.txt", "report.json", "coverage.out", "gocyclo.txt", "test.json", "test.txt"}
+
+// checkMetricsDir returns an error unless dir exists and contains at least
+// one of the compared artifacts, so a mistyped directory is not taken for a
+// clean baseline
+func checkMetricsDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("error reading metrics directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("error reading metrics directory: %s is not a directory", dir)
+	}
+	for _, name := range artifacts {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("error reading metrics directory: %s contains none of %s", dir, strings.Join(artifacts, ", "))
+}
+
+// Load parses both metrics directories and compares them
+func Load(baseDir, headDir string) (*Delta, error) {
+	for _, dir := range []string{baseDir, headDir} {
+		if err := checkMetricsDir(dir); err != nil {
+			return nil, err
+		}
+	}
+	base, err := report.Load(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	head, err := report.Load(headDir)
+	if err != nil {
+		return nil, err
+	}
+
+	delta := Diff(base, head)
+	delta.BaseDir = baseDir
+	delta.HeadDir = headDir
+	return delta, nil
+}
+
+// Diff compares two metrics summaries
+func Diff(base, head *report.MetricsSummary) *Delta {
+	delta := &Delta{}
+	delta.NewIssues, delta.FixedIssues = diffIssues(issues(base), issues(head))
+	diffCoverage(delta, base, head)
+	delta.Complexity = diffComplexity(base.Complexity, head.Complexity)
+
+	if base.Tests != nil || head.Tests != nil {
+		delta.Tests = &Tests{}
+		if base.Tests != nil {
+			delta.Tests.BaseCount = len(base.Tests.Tests)
+			delta.Tests.BaseFailed = base.TestsFailed
+			delta.Tests.BaseDuration = base.Tests.Elapsed()
+		}
+		if head.Tests != nil {
+			delta.Tests.HeadCount = len(head.Tests.Tests)
+			delta.Tests.HeadFailed = head.TestsFailed
+			delta.Tests.HeadDuration = head.Tests.Elapsed()
+		}
+	}
+
+	return delta
+}
+
+// fingerprintedIssue is an issue with a fingerprint that identifies it across
+// runs even when unrelated edits move it to another line
+type fingerprintedIssue struct {
+	Issue
+	fingerprint string
+}
+
+func issues(summary *report.MetricsSummary) []fingerprintedIssue {
+	var result []fingerprintedIssue
+	for _, vet := range summary.VetIssues {
+		result = append(result, fingerprintedIssue{
+			Issue:       Issue{Tool: "vet", File: vet.File, Line: vet.Line, Text: vet.Message},
+			fingerprint: strings.Join([]string{"vet", vet.File, vet.Message}, "\x00"),
+		})
+	}
+	for _, lint := range summary.LintIssues {
+		source := ""
+		if len(lint.SourceLines) > 0 {
+			source = strings.TrimSpace(lint.SourceLines[0])
+		}
+		result = append(result, fingerprintedIssue{
+			Issue: Issue{
+				Tool:   "golangci-lint",
+				Linter: lint.FromLinter,
+				File:   lint.Pos.Filename,
+				Line:   lint.Pos.Line,
+				Text:   lint.Text,
+			},
+			fingerprint: strings.Join([]string{lint.FromLinter, lint.Pos.Filename, lint.Text, source}, "\x00"),
+		})
+	}
+	return result
+}
+
+// diffIssues matches issues by fingerprint. Identical issues are matched one
+// to one, so a second copy of an existing issue is reported as new.
+func diffIssues(base, head []fingerprintedIssue) (added, fixed []Issue) {
+	baseCount := make(map[string]int)
+	for _, issue := range base {
+		baseCount[issue.fingerprint]++
+	}
+	headCount := make(map[string]int)
+	for _, issue := range head {
+		headCount[issue.fingerprint]++
+	}
+
+	seen := make(map[string]int)
+	for _, issue := range head {
+		seen[issue.fingerprint]++
+		if seen[issue.fingerprint] > baseCount[issue.fingerprint] {
+			added = append(added, issue.Issue)
+		}
+	}
+	seen = make(map[string]int)
+	for _, issue := range base {
+		seen[issue.fingerprint]++
+		if seen[issue.fingerprint] > headCount[issue.fingerprint] {
+			fixed = append(fixed, issue.Issue)
+		}
+	}
+	return added, fixed
+}
+
+func diffCoverage(delta *Delta, base, head *report.MetricsSummary) {
+	if base.Coverage == nil && head.Coverage == nil {
+		return
+	}
+	delta.HasCoverage = true
+	delta.BaseCoverage, delta.HeadCoverage = -1, -1
+	if base.Coverage != nil {
+		delta.BaseCoverage = base.Coverage.Percent()
+	}
+	if head.Coverage != nil {
+		delta.HeadCoverage = head.Coverage.Percent()
+	}
+
+	packages := make(map[string]*PackageCoverage)
+	get := func(name string) *PackageCoverage {
+		pkg, ok := packages[name]
+		if !ok {
+			pkg = &PackageCoverage{Package: name, Base: -1, Head: -1}
+			packages[name] = pkg
+		}
+		return pkg
+	}
+	if base.CoverageProfile != nil {
+		for name, stats := range base.CoverageProfile.PackageStats() {
+			get(name).Base = stats.Percent()
+		}
+	}
+	if head.CoverageProfile != nil {
+		for name, stats := range head.CoverageProfile.PackageStats() {
+			get(name).Head = stats.Percent()
+		}
+	}
+
+	for _, pkg := range packages {
+		delta.Packages = append(delta.Packages, *pkg)
+	}
+	sort.Slice(delta.Packages, func(i, j int) bool {
+		return delta.Packages[i].Package < delta.Packages[j].Package
+	})
+}
+
+func diffComplexity(base, head []report.FunctionComplexity) []ComplexityChange {
+	key := func(fn report.FunctionComplexity) string {
+		return fn.Package + "\x00" + fn.Function
+	}
+	baseByName := make(map[string]int)
+	for _, fn := range base {
+		baseByName[key(fn)] = fn.Complexity
+	}
+
+	var changes []ComplexityChange
+	for _, fn := range head {
+		old, ok := baseByName[key(fn)]
+		if !ok || fn.Complexity <= old {
+			continue
+		}
+		changes = append(changes, ComplexityChange{
+			Package:  fn.Package,
+			Function: fn.Function,
+			File:     fn.File,
+			Line:     fn.Line,
+			Base:     old,
+			Head:     fn.Complexity,
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		di := changes[i].Head - changes[i].Base
+		dj := changes[j].Head - changes[j].Base
+		if di != dj {
+			return di > dj
+		}
+		return changes[i].Function < changes[j].Function
+	})
+	return changes
+}
@@ -0,0 +1,122 @@
+package compare
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andro-kes/gokode/internal/coverage"
+	"github.com/andro-kes/gokode/internal/report"
+)
+
+func lintIssue(linter, file string, line int, text string) report.LintIssue {
+	issue := report.LintIssue{FromLinter: linter, Text: text}
+	issue.Pos.Filename = file
+	issue.Pos.Line = line
+	return issue
+}
+
+func summary(t *testing.T, profile string, issues []report.LintIssue, complexity []report.FunctionComplexity) *report.MetricsSummary {
+	t.Helper()
+	p, err := coverage.Parse(strings.NewReader(profile))
+	if err != nil {
+		t.Fatalf("Failed to parse profile: %v", err)
+	}
+	total := p.Total()
+	return &report.MetricsSummary{
+		LintIssues:      issues,
+		Coverage:        &total,
+		CoverageProfile: p,
+		Complexity:      complexity,
+	}
+}
+
+func TestDiff(t *testing.T) {
+	base := summary(t,
+		"mode: set\nexample.com/a/a.go:1.1,2.2 2 1\nexample.com/b/b.go:1.1,2.2 2 0\n",
+		[]report.LintIssue{
+			lintIssue("errcheck", "a.go", 10, "Error return value not checked"),
+			lintIssue("revive", "b.go", 3, "exported function should have comment"),
+		},
+		[]report.FunctionComplexity{
+			{Complexity: 3, Package: "a", Function: "Run"},
+			{Complexity: 5, Package: "b", Function: "Parse"},
+		})
+	head := summary(t,
+		"mode: set\nexample.com/a/a.go:1.1,2.2 2 1\nexample.com/b/b.go:1.1,2.2 2 1\n",
+		[]report.LintIssue{
+			// Same issue moved by an unrelated edit
+			lintIssue("errcheck", "a.go", 14, "Error return value not checked"),
+			lintIssue("gosec", "a.go", 20, "G304: file inclusion"),
+		},
+		[]report.FunctionComplexity{
+			{Complexity: 7, Package: "a", Function: "Run"},
+			{Complexity: 4, Package: "b", Function: "Parse"},
+			{Complexity: 9, Package: "b", Function: "New"},
+		})
+
+	delta := Diff(base, head)
+
+	if len(delta.NewIssues) != 1 || delta.NewIssues[0].Linter != "gosec" {
+		t.Errorf("Expected the gosec issue to be new, got %+v", delta.NewIssues)
+	}
+	if len(delta.FixedIssues) != 1 || delta.FixedIssues[0].Linter != "revive" {
+		t.Errorf("Expected the revive issue to be fixed, got %+v", delta.FixedIssues)
+	}
+
+	if delta.BaseCoverage != 50 || delta.HeadCoverage != 100 {
+		t.Errorf("Unexpected total coverage: %.1f -> %.1f", delta.BaseCoverage, delta.HeadCoverage)
+	}
+	if len(delta.Packages) != 2 || delta.Packages[1].Package != "example.com/b" || delta.Packages[1].Delta() != 100 {
+		t.Errorf("Unexpected package coverage: %+v", delta.Packages)
+	}
+
+	if len(delta.Complexity) != 1 || delta.Complexity[0].Function != "Run" ||
+		delta.Complexity[0].Base != 3 || delta.Complexity[0].Head != 7 {
+		t.Errorf("Expected only Run to increase in complexity, got %+v", delta.Complexity)
+	}
+}
+
+func TestWriteFormats(t *testing.T) {
+	delta := &Delta{
+		BaseDir:   "base",
+		HeadDir:   "head",
+		NewIssues: []Issue{{Tool: "vet", File: "a.go", Line: 3, Text: "unreachable code"}},
+	}
+
+	for format, expected := range map[string]string{
+		FormatConsole:  "+ [vet] a.go:3 unreachable code",
+		FormatMarkdown: "| 🆕 | vet | `a.go:3` | unreachable code |",
+		FormatHTML:     "unreachable code",
+	} {
+		var b strings.Builder
+		if err := Write(&b, delta, format); err != nil {
+			t.Fatalf("Write(%s) failed: %v", format, err)
+		}
+		if !strings.Contains(b.String(), expected) {
+			t.Errorf("%s output missing %q:\n%s", format, expected, b.String())
+		}
+	}
+
+	if err := Write(&strings.Builder{}, delta, "pdf"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestLoadMissingDir(t *testing.T) {
+	head := t.TempDir()
+	if err := os.WriteFile(filepath.Join(head, "vet.txt"), []byte("a.go:3:1: unreachable code\n"), 0644); err != nil {
+		t.Fatalf("Failed to write vet.txt: %v", err)
+	}
+
+	if _, err := Load(filepath.Join(head, "missing"), head); err == nil {
+		t.Error("Expected an error for a missing base directory")
+	}
+	if _, err := Load(t.TempDir(), head); err == nil || !strings.Contains(err.Error(), "contains none of") {
+		t.Errorf("Expected an error for a directory without metrics, got %v", err)
+	}
+	if _, err := Load(head, head); err != nil {
+		t.Errorf("Expected directories with metrics to load, got %v", err)
+	}
+}
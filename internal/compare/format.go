@@ -0,0 +1,297 @@
+package compare
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// Output formats
+const (
+	FormatConsole  = "console"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Write renders the delta in the given format
+func Write(w io.Writer, d *Delta, format string) error {
+	switch format {
+	case "", FormatConsole:
+		return WriteConsole(w, d)
+	case FormatMarkdown:
+		return WriteMarkdown(w, d)
+	case FormatHTML:
+		return WriteHTML(w, d)
+	default:
+		return fmt.Errorf("unknown format %q (expected console, markdown or html)", format)
+	}
+}
+
+// WriteConsole renders the delta as plain text
+func WriteConsole(w io.Writer, d *Delta) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", i18n.T("compare.comparing", d.BaseDir, d.HeadDir))
+
+	fmt.Fprintf(&b, "%s\n", i18n.T("compare.issues", len(d.NewIssues), len(d.FixedIssues)))
+	for _, issue := range d.NewIssues {
+		fmt.Fprintf(&b, "  + [%s] %s:%d %s\n", issueSource(issue), issue.File, issue.Line, issue.Text)
+	}
+	for _, issue := range d.FixedIssues {
+		fmt.Fprintf(&b, "  - [%s] %s:%d %s\n", issueSource(issue), issue.File, issue.Line, issue.Text)
+	}
+
+	if d.HasCoverage {
+		fmt.Fprintf(&b, "\n%s\n", i18n.T("compare.coverage", percent(d.BaseCoverage), percent(d.HeadCoverage), percentDelta(d.BaseCoverage, d.HeadCoverage)))
+		for _, pkg := range d.Packages {
+			if pkg.Base == pkg.Head {
+				continue
+			}
+			fmt.Fprintf(&b, "  %-50s %7s -> %7s  %s\n", pkg.Package, percent(pkg.Base), percent(pkg.Head), percentDelta(pkg.Base, pkg.Head))
+		}
+	}
+
+	fmt.Fprintf(&b, "\n%s\n", i18n.T("compare.complexity", len(d.Complexity)))
+	for _, fn := range d.Complexity {
+		fmt.Fprintf(&b, "  %s.%s (%s:%d) %d -> %d\n", fn.Package, fn.Function, fn.File, fn.Line, fn.Base, fn.Head)
+	}
+
+	if d.Tests != nil {
+		fmt.Fprintf(&b, "\n%s\n", i18n.T("compare.tests",
+			d.Tests.BaseCount, d.Tests.HeadCount, d.Tests.HeadCount-d.Tests.BaseCount,
+			d.Tests.BaseFailed, d.Tests.HeadFailed,
+			duration(d.Tests.BaseDuration), duration(d.Tests.HeadDuration), durationDelta(d.Tests.BaseDuration, d.Tests.HeadDuration)))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMarkdown renders the delta as Markdown, e.g. for a pull request comment
+func WriteMarkdown(w io.Writer, d *Delta) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", i18n.T("compare.markdown.title"))
+	fmt.Fprintf(&b, "%s\n\n", i18n.T("compare.markdown.dirs", d.BaseDir, d.HeadDir))
+
+	fmt.Fprintf(&b, "### %s\n\n", i18n.T("compare.issues", len(d.NewIssues), len(d.FixedIssues)))
+	if len(d.NewIssues)+len(d.FixedIssues) > 0 {
+		fmt.Fprintf(&b, "| | %s | %s | %s |\n|---|---|---|---|\n", i18n.T("compare.col.tool"), i18n.T("compare.col.location"), i18n.T("compare.col.issue"))
+		for _, issue := range d.NewIssues {
+			fmt.Fprintf(&b, "| 🆕 | %s | `%s:%d` | %s |\n", issueSource(issue), issue.File, issue.Line, markdownCell(issue.Text))
+		}
+		for _, issue := range d.FixedIssues {
+			fmt.Fprintf(&b, "| ✅ | %s | `%s:%d` | %s |\n", issueSource(issue), issue.File, issue.Line, markdownCell(issue.Text))
+		}
+		b.WriteString("\n")
+	}
+
+	if d.HasCoverage {
+		fmt.Fprintf(&b, "### %s: %s → %s (%s)\n\n", i18n.T("compare.label.coverage"), percent(d.BaseCoverage), percent(d.HeadCoverage), percentDelta(d.BaseCoverage, d.HeadCoverage))
+		fmt.Fprintf(&b, "| %s | %s | %s | Δ |\n|---|---:|---:|---:|\n", i18n.T("compare.col.package"), i18n.T("compare.col.base"), i18n.T("compare.col.head"))
+		for _, pkg := range d.Packages {
+			fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", pkg.Package, percent(pkg.Base), percent(pkg.Head), percentDelta(pkg.Base, pkg.Head))
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "### %s\n\n", i18n.T("compare.complexity", len(d.Complexity)))
+	if len(d.Complexity) > 0 {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n|---|---|---:|---:|\n", i18n.T("compare.col.function"), i18n.T("compare.col.location"), i18n.T("compare.col.base"), i18n.T("compare.col.head"))
+		for _, fn := range d.Complexity {
+			fmt.Fprintf(&b, "| `%s.%s` | `%s:%d` | %d | %d |\n", fn.Package, fn.Function, fn.File, fn.Line, fn.Base, fn.Head)
+		}
+		b.WriteString("\n")
+	}
+
+	if d.Tests != nil {
+		fmt.Fprintf(&b, "### %s\n\n| | %s | %s | Δ |\n|---|---:|---:|---:|\n", i18n.T("compare.label.tests"), i18n.T("compare.col.base"), i18n.T("compare.col.head"))
+		fmt.Fprintf(&b, "| %s | %d | %d | %+d |\n", i18n.T("compare.row.tests"), d.Tests.BaseCount, d.Tests.HeadCount, d.Tests.HeadCount-d.Tests.BaseCount)
+		fmt.Fprintf(&b, "| %s | %d | %d | %+d |\n", i18n.T("compare.row.failed"), d.Tests.BaseFailed, d.Tests.HeadFailed, d.Tests.HeadFailed-d.Tests.BaseFailed)
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", i18n.T("compare.row.duration"), duration(d.Tests.BaseDuration), duration(d.Tests.HeadDuration), durationDelta(d.Tests.BaseDuration, d.Tests.HeadDuration))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteHTML renders the delta as a standalone HTML page
+func WriteHTML(w io.Writer, d *Delta) error {
+	tmpl := template.Must(template.New("compare").Funcs(i18n.Current().Funcs()).Funcs(template.FuncMap{
+		"percent":        percent,
+		"percentDelta":   percentDelta,
+		"durationDelta":  durationDelta,
+		"issueSource":    issueSource,
+		"deltaClass":     deltaClass,
+		"sub":            func(a, b int) int { return a - b },
+		"testCountDelta": func(t *Tests) string { return fmt.Sprintf("%+d", t.HeadCount-t.BaseCount) },
+	}).Parse(htmlTemplate))
+	return tmpl.Execute(w, d)
+}
+
+func issueSource(issue Issue) string {
+	if issue.Linter != "" {
+		return issue.Tool + "/" + issue.Linter
+	}
+	return issue.Tool
+}
+
+func percent(p float64) string {
+	if p < 0 {
+		return "—"
+	}
+	return i18n.Current().Percent(p)
+}
+
+func percentDelta(base, head float64) string {
+	if base < 0 || head < 0 {
+		return "—"
+	}
+	return i18n.T("compare.delta", head-base)
+}
+
+// deltaClass returns the CSS class for a coverage change
+func deltaClass(base, head float64) string {
+	switch {
+	case base < 0 || head < 0 || head == base:
+		return ""
+	case head > base:
+		return "better"
+	default:
+		return "worse"
+	}
+}
+
+// duration formats a test duration in the current language
+func duration(d time.Duration) string {
+	return i18n.Current().Duration(d)
+}
+
+func durationDelta(base, head time.Duration) string {
+	d := head - base
+	if d >= 0 {
+		return "+" + duration(d)
+	}
+	return "-" + duration(-d)
+}
+
+// markdownCell escapes text for use in a Markdown table cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="{{lang}}">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{t "compare.title"}}</title>
+    <style>
+        body {
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', 'Roboto', 'Oxygen', 'Ubuntu', 'Cantarell', sans-serif;
+            line-height: 1.6;
+            color: #333;
+            background: #f5f5f5;
+            margin: 0;
+            padding: 20px;
+        }
+        .container {
+            max-width: 1200px;
+            margin: 0 auto;
+            background: white;
+            border-radius: 8px;
+            box-shadow: 0 2px 8px rgba(0,0,0,0.1);
+            overflow: hidden;
+        }
+        header {
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            color: white;
+            padding: 30px;
+            text-align: center;
+        }
+        .content {
+            padding: 30px;
+        }
+        h2 {
+            color: #667eea;
+            border-bottom: 2px solid #667eea;
+            padding-bottom: 10px;
+        }
+        table {
+            width: 100%;
+            border-collapse: collapse;
+            margin-bottom: 30px;
+        }
+        th, td {
+            text-align: left;
+            padding: 8px 12px;
+            border-bottom: 1px solid #e9ecef;
+        }
+        .new, .worse {
+            color: #dc3545;
+        }
+        .fixed, .better {
+            color: #28a745;
+        }
+        code {
+            font-family: 'Courier New', monospace;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <header>
+            <h1>⚖ {{t "compare.title"}}</h1>
+            <div>{{.BaseDir}} → {{.HeadDir}}</div>
+        </header>
+        <div class="content">
+            <h2>{{t "compare.issues" (len .NewIssues) (len .FixedIssues)}}</h2>
+            {{if or .NewIssues .FixedIssues}}
+            <table>
+                <tr><th></th><th>{{t "compare.col.tool"}}</th><th>{{t "compare.col.location"}}</th><th>{{t "compare.col.issue"}}</th></tr>
+                {{range .NewIssues}}
+                <tr class="new"><td>+ {{t "compare.new"}}</td><td>{{issueSource .}}</td><td><code>{{.File}}:{{.Line}}</code></td><td>{{.Text}}</td></tr>
+                {{end}}
+                {{range .FixedIssues}}
+                <tr class="fixed"><td>− {{t "compare.fixed"}}</td><td>{{issueSource .}}</td><td><code>{{.File}}:{{.Line}}</code></td><td>{{.Text}}</td></tr>
+                {{end}}
+            </table>
+            {{end}}
+
+            {{if .HasCoverage}}
+            <h2>{{t "compare.label.coverage"}}: {{percent .BaseCoverage}} → {{percent .HeadCoverage}} <span class="{{deltaClass .BaseCoverage .HeadCoverage}}">({{percentDelta .BaseCoverage .HeadCoverage}})</span></h2>
+            <table>
+                <tr><th>{{t "compare.col.package"}}</th><th>{{t "compare.col.base"}}</th><th>{{t "compare.col.head"}}</th><th>Δ</th></tr>
+                {{range .Packages}}
+                <tr><td><code>{{.Package}}</code></td><td>{{percent .Base}}</td><td>{{percent .Head}}</td><td class="{{deltaClass .Base .Head}}">{{percentDelta .Base .Head}}</td></tr>
+                {{end}}
+            </table>
+            {{end}}
+
+            <h2>{{t "compare.complexity" (len .Complexity)}}</h2>
+            {{if .Complexity}}
+            <table>
+                <tr><th>{{t "compare.col.function"}}</th><th>{{t "compare.col.location"}}</th><th>{{t "compare.col.base"}}</th><th>{{t "compare.col.head"}}</th></tr>
+                {{range .Complexity}}
+                <tr><td><code>{{.Package}}.{{.Function}}</code></td><td><code>{{.File}}:{{.Line}}</code></td><td>{{.Base}}</td><td class="worse">{{.Head}} (+{{sub .Head .Base}})</td></tr>
+                {{end}}
+            </table>
+            {{end}}
+
+            {{with .Tests}}
+            <h2>{{t "compare.label.tests"}}</h2>
+            <table>
+                <tr><th></th><th>{{t "compare.col.base"}}</th><th>{{t "compare.col.head"}}</th><th>Δ</th></tr>
+                <tr><td>{{t "compare.row.tests"}}</td><td>{{.BaseCount}}</td><td>{{.HeadCount}}</td><td>{{testCountDelta .}}</td></tr>
+                <tr><td>{{t "compare.row.failed"}}</td><td>{{.BaseFailed}}</td><td>{{.HeadFailed}}</td><td>{{sub .HeadFailed .BaseFailed}}</td></tr>
+                <tr><td>{{t "compare.row.duration"}}</td><td>{{duration .BaseDuration}}</td><td>{{duration .HeadDuration}}</td><td>{{durationDelta .BaseDuration .HeadDuration}}</td></tr>
+            </table>
+            {{end}}
+        </div>
+    </div>
+</body>
+</html>
+`
@@ -0,0 +1,227 @@
+package complexity
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/coverage"
+	"github.com/andro-kes/gokode/internal/fileutil"
+)
+
+// File is the name of the CRAP score file in the metrics directory
+const File = "crap.json"
+
+// Crappy is the score above which a function is conventionally considered
+// too risky to change
+const Crappy = 30
+
+// Function is a single line of gocyclo output
+type Function struct {
+	Complexity int
+	Package    string
+	Function   string
+	File       string
+	Line       int
+	Column     int
+}
+
+// Parse parses gocyclo output lines of the form
+// <complexity> <package> <function> <file:line:column>, skipping malformed ones
+func Parse(lines []string) []Function {
+	var functions []Function
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		complexity, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+
+		fn := Function{
+			Complexity: complexity,
+			Package:    fields[1],
+			Function:   strings.Join(fields[2:len(fields)-1], " "),
+		}
+
+		// The position is file:line:column and the file may contain colons
+		pos := fields[len(fields)-1]
+		parts := strings.Split(pos, ":")
+		if len(parts) >= 3 {
+			fn.File = strings.Join(parts[:len(parts)-2], ":")
+			fn.Line, _ = strconv.Atoi(parts[len(parts)-2])
+			fn.Column, _ = strconv.Atoi(parts[len(parts)-1])
+		} else {
+			fn.File = pos
+		}
+		functions = append(functions, fn)
+	}
+	return functions
+}
+
+// Risk is the CRAP score of a function, combining its cyclomatic complexity
+// with its statement coverage
+type Risk struct {
+	Package string `json:"package"`
+	Func    string `json:"func"`
+	// File is relative to the project with forward slashes
+	File       string  `json:"file"`
+	Line       int     `json:"line"`
+	EndLine    int     `json:"endLine"`
+	Complexity int     `json:"complexity"`
+	Statements int     `json:"statements"`
+	Covered    int     `json:"covered"`
+	Score      float64 `json:"score"`
+}
+
+// Coverage returns the percentage of the function's statements covered
+func (r Risk) Coverage() float64 {
+	return coverage.Stats{Statements: r.Statements, Covered: r.Covered}.Percent()
+}
+
+// Crappy reports whether the function's score is above Crappy
+func (r Risk) Crappy() bool {
+	return r.Score > Crappy
+}
+
+// Crap returns the CRAP score c² × (1 − cov)³ + c of a function with
+// cyclomatic complexity c and coverage cov between 0 and 1
+func Crap(complexity int, cov float64) float64 {
+	c := float64(complexity)
+	return c*c*math.Pow(1-cov, 3) + c
+}
+
+// Report is the CRAP scores of the functions of a project, riskiest first
+type Report struct {
+	Functions []Risk `json:"functions"`
+}
+
+// Max returns the riskiest function, or nil if there are none
+func (r *Report) Max() *Risk {
+	if len(r.Functions) == 0 {
+		return nil
+	}
+	return &r.Functions[0]
+}
+
+// CrappyCount returns the number of functions scoring above Crappy
+func (r *Report) CrappyCount() int {
+	n := 0
+	for _, risk := range r.Functions {
+		if risk.Crappy() {
+			n++
+		}
+	}
+	return n
+}
+
+// Top returns at most n of the riskiest functions
+func (r *Report) Top(n int) []Risk {
+	return r.Functions[:min(n, len(r.Functions))]
+}
+
+// Score computes the CRAP score of the functions from the coverage of the
+// profile blocks within each function's body. resolve maps the import path
+// based file names of the profile to file names relative to the project, and
+// read returns the source of a project file, which gives the line range of
+// each function. Functions without statements in the profile, such as those
+// of test files or excluded from coverage, are left out.
+func Score(functions []Function, profile *coverage.Profile, resolve func(string) string, read func(string) ([]byte, error)) (*Report, error) {
+	blocks := make(map[string][]coverage.Block)
+	for _, block := range profile.Blocks {
+		if file := resolve(block.File); file != "" {
+			blocks[file] = append(blocks[file], block)
+		}
+	}
+
+	ends := make(map[string]map[int]int)
+	report := &Report{}
+	for _, fn := range functions {
+		file := path.Clean(filepath.ToSlash(fn.File))
+		if len(blocks[file]) == 0 {
+			continue
+		}
+		if _, ok := ends[file]; !ok {
+			src, err := read(file)
+			if err != nil {
+				return nil, fmt.Errorf("error reading %s: %w", file, err)
+			}
+			lines, err := funcLines(file, src)
+			if err != nil {
+				return nil, err
+			}
+			ends[file] = lines
+		}
+		end, ok := ends[file][fn.Line]
+		if !ok {
+			continue
+		}
+
+		risk := Risk{Package: fn.Package, Func: fn.Function, File: file, Line: fn.Line, EndLine: end, Complexity: fn.Complexity}
+		for _, block := range blocks[file] {
+			if block.StartLine >= fn.Line && block.EndLine <= end {
+				risk.Statements += block.NumStmt
+				if block.Count > 0 {
+					risk.Covered += block.NumStmt
+				}
+			}
+		}
+		if risk.Statements == 0 {
+			continue
+		}
+		risk.Score = Crap(fn.Complexity, float64(risk.Covered)/float64(risk.Statements))
+		report.Functions = append(report.Functions, risk)
+	}
+
+	sort.SliceStable(report.Functions, func(i, j int) bool {
+		a, b := report.Functions[i], report.Functions[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return report, nil
+}
+
+// funcLines maps the first line of each function declared in the source to
+// its last line
+func funcLines(name string, src []byte) (map[int]int, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", name, err)
+	}
+	lines := make(map[int]int)
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+			lines[fset.Position(fn.Pos()).Line] = fset.Position(fn.End()).Line
+		}
+	}
+	return lines, nil
+}
+
+// Write atomically writes the CRAP scores as JSON
+func Write(name string, report *Report) error {
+	return fileutil.WriteJSON(name, report, "CRAP scores")
+}
+
+// Read reads CRAP scores written by Write
+func Read(name string) (*Report, error) {
+	var report Report
+	if err := fileutil.ReadJSON(name, &report, "CRAP scores"); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,119 @@
+package complexity
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andro-kes/gokode/internal/coverage"
+)
+
+func TestParse(t *testing.T) {
+	functions := Parse([]string{
+		"12 runner (*Runner).Run internal/runner/runner.go:40:1",
+		"3 main main C:/proj/main.go:5:1",
+		"not a line",
+		"x main main main.go:1:1",
+	})
+	want := []Function{
+		{Complexity: 12, Package: "runner", Function: "(*Runner).Run", File: "internal/runner/runner.go", Line: 40, Column: 1},
+		{Complexity: 3, Package: "main", Function: "main", File: "C:/proj/main.go", Line: 5, Column: 1},
+	}
+	if !reflect.DeepEqual(functions, want) {
+		t.Errorf("Parse = %+v, want %+v", functions, want)
+	}
+}
+
+func TestCrap(t *testing.T) {
+	tests := []struct {
+		complexity int
+		coverage   float64
+		want       float64
+	}{
+		{1, 0, 2},
+		{5, 1, 5},
+		{5, 0, 30},
+		{10, 0.5, 22.5},
+	}
+	for _, tt := range tests {
+		if got := Crap(tt.complexity, tt.coverage); got != tt.want {
+			t.Errorf("Crap(%d, %v) = %v, want %v", tt.complexity, tt.coverage, got, tt.want)
+		}
+	}
+}
+
+const source = `package calc
+
+func Add(a, b int) int {
+	return a + b
+}
+
+func Classify(n int) string {
+	if n < 0 {
+		return "negative"
+	}
+	return "positive"
+}
+
+func Unused() {}
+`
+
+func TestScore(t *testing.T) {
+	profile, err := coverage.Parse(strings.NewReader(`mode: set
+example.com/m/calc/calc.go:3.24,5.2 1 1
+example.com/m/calc/calc.go:7.29,8.11 1 1
+example.com/m/calc/calc.go:8.11,10.3 1 0
+example.com/m/calc/calc.go:11.2,11.19 1 0
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	functions := []Function{
+		{Complexity: 1, Package: "calc", Function: "Add", File: "calc/calc.go", Line: 3},
+		{Complexity: 2, Package: "calc", Function: "Classify", File: "calc/calc.go", Line: 7},
+		{Complexity: 1, Package: "calc", Function: "Unused", File: "calc/calc.go", Line: 14},
+		{Complexity: 4, Package: "calc", Function: "TestAdd", File: "calc/calc_test.go", Line: 9},
+	}
+	resolve := func(file string) string { return strings.TrimPrefix(file, "example.com/m/") }
+	read := func(name string) ([]byte, error) {
+		if path.Base(name) != "calc.go" {
+			return nil, fmt.Errorf("unexpected read of %s", name)
+		}
+		return []byte(source), nil
+	}
+
+	report, err := Score(functions, profile, resolve, read)
+	if err != nil {
+		t.Fatalf("Score failed: %v", err)
+	}
+	want := []Risk{
+		{Package: "calc", Func: "Classify", File: "calc/calc.go", Line: 7, EndLine: 12, Complexity: 2, Statements: 3, Covered: 1, Score: Crap(2, 1.0/3)},
+		{Package: "calc", Func: "Add", File: "calc/calc.go", Line: 3, EndLine: 5, Complexity: 1, Statements: 1, Covered: 1, Score: 1},
+	}
+	if !reflect.DeepEqual(report.Functions, want) {
+		t.Errorf("Score = %+v, want %+v", report.Functions, want)
+	}
+	if max := report.Max(); max == nil || max.Func != "Classify" {
+		t.Errorf("Max = %+v, want Classify", max)
+	}
+	if n := report.CrappyCount(); n != 0 {
+		t.Errorf("CrappyCount = %d, want 0", n)
+	}
+	if top := report.Top(1); len(top) != 1 || top[0].Func != "Classify" {
+		t.Errorf("Top(1) = %+v", top)
+	}
+}
+
+func TestCrappy(t *testing.T) {
+	report := &Report{Functions: []Risk{
+		{Package: "calc", Func: "Classify", File: "calc/calc.go", Line: 7, EndLine: 12, Complexity: 8, Statements: 10, Covered: 2, Score: 40.8},
+	}}
+	if !report.Functions[0].Crappy() {
+		t.Error("Expected a score of 40.8 to be crappy")
+	}
+	if (&Report{}).Max() != nil {
+		t.Error("Expected no riskiest function in an empty report")
+	}
+}
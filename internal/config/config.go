@@ -0,0 +1,75 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/andro-kes/gokode/internal/flaky"
+)
+
+// File is the name of the project configuration file
+const File = ".gokode.json"
+
+// Config is the project configuration read from .gokode.json. Unset fields
+// disable the corresponding quality gates.
+type Config struct {
+	// PatchCoverageMin is the minimum percentage of changed statements that
+	// must be covered by tests
+	PatchCoverageMin *float64 `json:"patch_coverage_min,omitempty"`
+	// BenchRegressionMax is the largest significant slowdown of a benchmark,
+	// in percent, that gokode bench accepts
+	BenchRegressionMax *float64 `json:"bench_regression_max,omitempty"`
+	// CrapMax is the highest CRAP score, combining cyclomatic complexity and
+	// coverage, that any function may have
+	CrapMax *float64 `json:"crap_max,omitempty"`
+	// CoverageExclude are globs of files, relative to the project, left out
+	// of coverage, e.g. **/*.pb.go or mocks/**
+	CoverageExclude []string `json:"coverage_exclude,omitempty"`
+	// KnownFlaky are tests whose failures don't fail the test step
+	KnownFlaky []flaky.Known `json:"known_flaky,omitempty"`
+	// Integration configures the integration commands measured with
+	// --integration
+	Integration *Integration `json:"integration,omitempty"`
+}
+
+// Integration configures integration coverage
+type Integration struct {
+	// Binaries are the main packages built with -cover, e.g. ./cmd/app
+	Binaries []string `json:"binaries"`
+	// Commands are shell commands run in the project directory with the
+	// built binaries first in PATH
+	Commands []string `json:"commands"`
+}
+
+// Load reads the configuration from projectDir. A missing file yields an
+// empty configuration.
+func Load(projectDir string) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(filepath.Join(projectDir, File))
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", File, err)
+	}
+
+	// Unknown keys are most likely typos that would silently disable a gate
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", File, err)
+	}
+	for _, glob := range cfg.CoverageExclude {
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("error parsing %s: coverage_exclude %q: %w", File, glob, err)
+		}
+	}
+	if cfg.Integration != nil && (len(cfg.Integration.Binaries) == 0 || len(cfg.Integration.Commands) == 0) {
+		return nil, fmt.Errorf("error parsing %s: integration needs binaries and commands", File)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load without config file failed: %v", err)
+	}
+	if cfg.PatchCoverageMin != nil {
+		t.Errorf("Expected no patch coverage gate, got %v", *cfg.PatchCoverageMin)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, File), []byte(`{"patch_coverage_min": 80}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err = Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.PatchCoverageMin == nil || *cfg.PatchCoverageMin != 80 {
+		t.Errorf("Unexpected patch coverage gate: %v", cfg.PatchCoverageMin)
+	}
+}
+
+func TestLoadUnknownKey(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, File), []byte(`{"patch_coverage_mni": 80}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Error("Expected an error for an unknown key")
+	}
+}
+
+func TestLoadIntegration(t *testing.T) {
+	dir := t.TempDir()
+	config := `{"integration": {"binaries": ["./cmd/app"], "commands": ["./e2e.sh"]}}`
+	if err := os.WriteFile(filepath.Join(dir, File), []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Integration == nil || len(cfg.Integration.Binaries) != 1 || cfg.Integration.Commands[0] != "./e2e.sh" {
+		t.Errorf("Unexpected integration config: %+v", cfg.Integration)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, File), []byte(`{"integration": {"binaries": ["./cmd/app"]}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Error("Expected an error for integration without commands")
+	}
+}
+
+func TestLoadCoverageExclude(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, File), []byte(`{"coverage_exclude": ["**/*.pb.go", "mocks/**"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(cfg.CoverageExclude) != 2 {
+		t.Errorf("Unexpected coverage exclusions: %v", cfg.CoverageExclude)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, File), []byte(`{"coverage_exclude": ["mocks/[a-"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Error("Expected an error for a malformed glob")
+	}
+}
+
+func TestLoadCrapMax(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, File), []byte(`{"crap_max": 30}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.CrapMax == nil || *cfg.CrapMax != 30 {
+		t.Errorf("Unexpected CRAP score gate: %v", cfg.CrapMax)
+	}
+}
@@ -0,0 +1,188 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Block is a single block of a cover profile
+type Block struct {
+	// File is the import path based file name, e.g. example.com/pkg/file.go
+	File      string
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+	NumStmt   int
+	Count     int
+}
+
+// Profile is a parsed cover profile
+type Profile struct {
+	Mode   string
+	Blocks []Block
+}
+
+// ParseFile parses the cover profile at name
+func ParseFile(name string) (*Profile, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse parses a cover profile as written by go test -coverprofile. Blocks
+// reported more than once, e.g. by several test binaries, are merged.
+func Parse(r io.Reader) (*Profile, error) {
+	profile := &Profile{}
+	index := make(map[blockPos]int)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if mode, ok := strings.CutPrefix(line, "mode: "); ok {
+			profile.Mode = mode
+			continue
+		}
+
+		block, err := parseBlock(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+
+		pos := blockPos{block.File, block.StartLine, block.StartCol, block.EndLine, block.EndCol}
+		if i, ok := index[pos]; ok {
+			if profile.Mode == "set" {
+				profile.Blocks[i].Count = max(profile.Blocks[i].Count, block.Count)
+			} else {
+				profile.Blocks[i].Count += block.Count
+			}
+			continue
+		}
+		index[pos] = len(profile.Blocks)
+		profile.Blocks = append(profile.Blocks, block)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+type blockPos struct {
+	file                                 string
+	startLine, startCol, endLine, endCol int
+}
+
+// parseBlock parses a line of the form file.go:10.2,12.16 3 1
+func parseBlock(line string) (Block, error) {
+	var block Block
+
+	colon := strings.LastIndex(line, ":")
+	if colon < 0 {
+		return block, fmt.Errorf("malformed block %q", line)
+	}
+	block.File = line[:colon]
+
+	fields := strings.Fields(line[colon+1:])
+	if len(fields) != 3 {
+		return block, fmt.Errorf("malformed block %q", line)
+	}
+
+	var err error
+	if _, err = fmt.Sscanf(fields[0], "%d.%d,%d.%d", &block.StartLine, &block.StartCol, &block.EndLine, &block.EndCol); err != nil {
+		return block, fmt.Errorf("malformed block position %q", fields[0])
+	}
+	if block.NumStmt, err = strconv.Atoi(fields[1]); err != nil {
+		return block, fmt.Errorf("malformed statement count %q", fields[1])
+	}
+	if block.Count, err = strconv.Atoi(fields[2]); err != nil {
+		return block, fmt.Errorf("malformed execution count %q", fields[2])
+	}
+
+	return block, nil
+}
+
+// Stats holds statement coverage totals
+type Stats struct {
+	Statements int `json:"statements"`
+	Covered    int `json:"covered"`
+}
+
+// Percent returns the percentage of covered statements
+func (s Stats) Percent() float64 {
+	if s.Statements == 0 {
+		return 0
+	}
+	return float64(s.Covered) * 100 / float64(s.Statements)
+}
+
+func (s *Stats) add(block Block) {
+	s.Statements += block.NumStmt
+	if block.Count > 0 {
+		s.Covered += block.NumStmt
+	}
+}
+
+// Total returns the statement coverage of the whole profile
+func (p *Profile) Total() Stats {
+	var stats Stats
+	for _, block := range p.Blocks {
+		stats.add(block)
+	}
+	return stats
+}
+
+// FileStats returns the statement coverage of each file in the profile
+func (p *Profile) FileStats() map[string]Stats {
+	files := make(map[string]Stats)
+	for _, block := range p.Blocks {
+		stats := files[block.File]
+		stats.add(block)
+		files[block.File] = stats
+	}
+	return files
+}
+
+// PackageStats returns the statement coverage of each package in the profile,
+// keyed by import path
+func (p *Profile) PackageStats() map[string]Stats {
+	packages := make(map[string]Stats)
+	for _, block := range p.Blocks {
+		pkg := block.File
+		if i := strings.LastIndex(pkg, "/"); i >= 0 {
+			pkg = pkg[:i]
+		}
+		stats := packages[pkg]
+		stats.add(block)
+		packages[pkg] = stats
+	}
+	return packages
+}
+
+// Files returns the sorted names of the files in the profile
+func (p *Profile) Files() []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, block := range p.Blocks {
+		if !seen[block.File] {
+			seen[block.File] = true
+			files = append(files, block.File)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
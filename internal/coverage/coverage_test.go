@@ -0,0 +1,256 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleProfile = `mode: set
+example.com/proj/a.go:3.24,4.11 1 1
+example.com/proj/a.go:4.11,6.3 1 0
+example.com/proj/a.go:7.2,7.10 1 1
+example.com/proj/sub/b.go:3.14,5.2 2 0
+example.com/proj/a.go:4.11,6.3 1 1
+`
+
+func TestParse(t *testing.T) {
+	profile, err := Parse(strings.NewReader(sampleProfile))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if profile.Mode != "set" {
+		t.Errorf("Expected mode set, got %s", profile.Mode)
+	}
+	// The duplicated block is merged
+	if len(profile.Blocks) != 4 {
+		t.Fatalf("Expected 4 blocks, got %d", len(profile.Blocks))
+	}
+
+	block := profile.Blocks[1]
+	if block.File != "example.com/proj/a.go" || block.StartLine != 4 || block.StartCol != 11 ||
+		block.EndLine != 6 || block.EndCol != 3 || block.NumStmt != 1 || block.Count != 1 {
+		t.Errorf("Unexpected block: %+v", block)
+	}
+
+	total := profile.Total()
+	if total.Statements != 5 || total.Covered != 3 {
+		t.Errorf("Unexpected totals: %+v", total)
+	}
+	if total.Percent() != 60 {
+		t.Errorf("Expected 60%% coverage, got %.1f", total.Percent())
+	}
+
+	packages := profile.PackageStats()
+	if packages["example.com/proj"].Percent() != 100 {
+		t.Errorf("Expected example.com/proj to be fully covered, got %+v", packages["example.com/proj"])
+	}
+	if packages["example.com/proj/sub"].Covered != 0 {
+		t.Errorf("Expected example.com/proj/sub to be uncovered, got %+v", packages["example.com/proj/sub"])
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	if _, err := Parse(strings.NewReader("mode: set\nexample.com/a.go:1.1,2.2 1\n")); err == nil {
+		t.Error("Expected error for truncated block")
+	}
+}
+
+func TestPatchCoverage(t *testing.T) {
+	input := `mode: set
+example.com/m/pkg/a.go:3.14,5.2 2 1
+example.com/m/pkg/a.go:7.14,10.2 3 0
+example.com/m/pkg/a.go:12.14,14.2 1 0
+example.com/m/other.go:1.10,2.2 1 0
+`
+	profile, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	changed := map[string][]int{
+		"pkg/a.go": {4, 8, 9, 20},
+		"gen.go":   {1},
+	}
+	resolve := func(file string) string {
+		rel, ok := strings.CutPrefix(file, "example.com/m/")
+		if !ok {
+			return ""
+		}
+		return rel
+	}
+
+	patch := profile.PatchCoverage(changed, resolve)
+	if patch.Lines != 3 || patch.Covered != 1 {
+		t.Errorf("Expected 1 of 3 changed lines covered, got %d of %d", patch.Covered, patch.Lines)
+	}
+	if len(patch.Files) != 1 || patch.Files[0].File != "pkg/a.go" {
+		t.Fatalf("Unexpected files: %+v", patch.Files)
+	}
+	if got := LineRanges(patch.Files[0].Uncovered); got != "8-9" {
+		t.Errorf("Unexpected uncovered lines: %s", got)
+	}
+
+	empty := profile.PatchCoverage(nil, resolve)
+	if empty.Percent() != 100 {
+		t.Errorf("Expected a patch without statements to be fully covered, got %.1f", empty.Percent())
+	}
+}
+
+func TestPatchCoverageLargeBlock(t *testing.T) {
+	input := `mode: set
+example.com/m/a.go:3.14,25.2 20 1
+example.com/m/a.go:27.14,49.2 20 0
+example.com/m/a.go:51.14,53.2 1 1
+`
+	profile, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	resolve := func(file string) string {
+		return strings.TrimPrefix(file, "example.com/m/")
+	}
+
+	// One line changed in each large block and two in the small one
+	patch := profile.PatchCoverage(map[string][]int{"a.go": {10, 30, 51, 52}}, resolve)
+	if patch.Lines != 4 || patch.Covered != 3 {
+		t.Errorf("Expected 3 of 4 changed lines covered, got %d of %d", patch.Covered, patch.Lines)
+	}
+	if got := LineRanges(patch.Files[0].Uncovered); got != "30" {
+		t.Errorf("Unexpected uncovered lines: %s", got)
+	}
+}
+
+func TestLineRanges(t *testing.T) {
+	if got := LineRanges([]int{1, 2, 3, 5, 7, 8}); got != "1-3, 5, 7-8" {
+		t.Errorf("Unexpected ranges: %s", got)
+	}
+	if got := LineRanges(nil); got != "" {
+		t.Errorf("Unexpected ranges for no lines: %q", got)
+	}
+}
+
+func TestSplitCoverage(t *testing.T) {
+	parse := func(input string) *Profile {
+		t.Helper()
+		profile, err := Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		return profile
+	}
+	unit := parse(`mode: set
+example.com/m/pkg/a.go:3.14,5.2 2 1
+example.com/m/pkg/a.go:7.14,10.2 3 0
+`)
+	integration := parse(`mode: set
+example.com/m/pkg/a.go:3.14,5.2 2 1
+example.com/m/pkg/a.go:7.14,10.2 3 1
+example.com/m/cmd/app/main.go:5.13,8.2 4 1
+example.com/m/cmd/app/main.go:9.13,10.2 1 0
+`)
+	combined := parse(`mode: set
+example.com/m/cmd/app/main.go:5.13,8.2 4 1
+example.com/m/cmd/app/main.go:9.13,10.2 1 0
+example.com/m/pkg/a.go:3.14,5.2 2 1
+example.com/m/pkg/a.go:7.14,10.2 3 1
+`)
+
+	total, packages := SplitCoverage(unit, integration, combined)
+	want := Split{Statements: 10, Unit: 2, Integration: 9, Combined: 9, IntegrationOnly: 7}
+	if total != want {
+		t.Errorf("SplitCoverage total = %+v, want %+v", total, want)
+	}
+	if total.UnitPercent() != 20 || total.CombinedPercent() != 90 {
+		t.Errorf("Unexpected percentages: unit %.1f, combined %.1f", total.UnitPercent(), total.CombinedPercent())
+	}
+	if len(packages) != 2 || packages[0].Package != "example.com/m/cmd/app" || packages[1].Package != "example.com/m/pkg" {
+		t.Fatalf("Unexpected packages: %+v", packages)
+	}
+	if packages[0].Unit != 0 || packages[0].IntegrationOnly != 4 {
+		t.Errorf("Unexpected split of example.com/m/cmd/app: %+v", packages[0].Split)
+	}
+}
+
+func TestFileExclusions(t *testing.T) {
+	src := []byte(`package main
+
+import "os"
+
+func main() {
+	run()
+	os.Exit(0)
+}
+
+//gokode:nocover
+func (s *server) serve() {
+	for {
+	}
+}
+
+func run() {}
+`)
+	exclusions, err := FileExclusions("cmd/app/main.go", src, []string{"**/*.pb.go", "mocks/**"})
+	if err != nil {
+		t.Fatalf("FileExclusions failed: %v", err)
+	}
+	if len(exclusions) != 1 {
+		t.Fatalf("Expected one annotated function, got %+v", exclusions)
+	}
+	if e := exclusions[0]; e.Func != "(*server).serve" || e.StartLine != 11 || e.EndLine != 14 || e.Reason != ReasonAnnotation {
+		t.Errorf("Unexpected exclusion: %+v", e)
+	}
+
+	generated := []byte("// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n")
+	exclusions, err = FileExclusions("api/api.go", generated, nil)
+	if err != nil {
+		t.Fatalf("FileExclusions failed: %v", err)
+	}
+	if len(exclusions) != 1 || exclusions[0].Reason != ReasonGenerated || exclusions[0].Func != "" {
+		t.Errorf("Expected the generated file to be excluded, got %+v", exclusions)
+	}
+
+	for _, name := range []string{"api/v1/user.pb.go", "user.pb.go", "mocks/store.go", "mocks/db/conn.go"} {
+		exclusions, err := FileExclusions(name, []byte("package x\n"), []string{"**/*.pb.go", "mocks/**"})
+		if err != nil {
+			t.Fatalf("FileExclusions failed: %v", err)
+		}
+		if len(exclusions) != 1 || exclusions[0].Reason != ReasonGlob {
+			t.Errorf("Expected %s to match a glob, got %+v", name, exclusions)
+		}
+	}
+	if exclusions, _ := FileExclusions("internal/mocks.go", []byte("package x\n"), []string{"mocks/**"}); len(exclusions) != 0 {
+		t.Errorf("Expected internal/mocks.go not to match, got %+v", exclusions)
+	}
+}
+
+func TestExclude(t *testing.T) {
+	profile, err := Parse(strings.NewReader(`mode: set
+example.com/m/main.go:5.13,8.2 2 1
+example.com/m/main.go:11.26,13.3 1 0
+example.com/m/api/api.pb.go:3.10,9.2 5 0
+example.com/m/util.go:3.10,4.2 2 1
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	exclusions := []Exclusion{
+		{File: "main.go", Func: "(*server).serve", StartLine: 11, EndLine: 14, Reason: ReasonAnnotation},
+		{File: "api/api.pb.go", Reason: ReasonGlob, Rule: "**/*.pb.go"},
+	}
+	resolve := func(file string) string { return strings.TrimPrefix(file, "example.com/m/") }
+
+	adjusted, result := profile.Exclude(exclusions, resolve)
+	if len(adjusted.Blocks) != 2 {
+		t.Fatalf("Expected 2 blocks to remain, got %+v", adjusted.Blocks)
+	}
+	if result.Raw != (Stats{Statements: 10, Covered: 4}) || result.Adjusted != (Stats{Statements: 4, Covered: 4}) {
+		t.Errorf("Unexpected totals: raw %+v, adjusted %+v", result.Raw, result.Adjusted)
+	}
+	if result.Excluded[0].Statements != 1 || result.Excluded[1].Statements != 5 {
+		t.Errorf("Unexpected excluded statements: %+v", result.Excluded)
+	}
+	if exclusions[1].Statements != 0 {
+		t.Error("Exclude must not modify its argument")
+	}
+}
@@ -0,0 +1,219 @@
+package coverage
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/fileutil"
+)
+
+// ExclusionsFileName is the name of the coverage exclusions file in the
+// metrics directory
+const ExclusionsFileName = "coverage_exclusions.json"
+
+// NoCoverDirective excludes the function it documents from coverage
+const NoCoverDirective = "//gokode:nocover"
+
+// Reasons for excluding code from coverage
+const (
+	ReasonGlob       = "glob"
+	ReasonGenerated  = "generated"
+	ReasonAnnotation = "annotation"
+)
+
+// Exclusion is a file or function left out of coverage
+type Exclusion struct {
+	// File is relative to the project with forward slashes
+	File string `json:"file"`
+	// Func is the excluded function, empty when the whole file is excluded
+	Func      string `json:"func,omitempty"`
+	StartLine int    `json:"startLine,omitempty"`
+	EndLine   int    `json:"endLine,omitempty"`
+	Reason    string `json:"reason"`
+	// Rule is the glob that matched the file
+	Rule string `json:"rule,omitempty"`
+	// Statements and Covered count the statements of the excluded blocks
+	Statements int `json:"statements"`
+	Covered    int `json:"covered"`
+}
+
+// contains reports whether the block lies within the exclusion
+func (e Exclusion) contains(block Block) bool {
+	if e.Func == "" {
+		return true
+	}
+	return block.StartLine >= e.StartLine && block.EndLine <= e.EndLine
+}
+
+// Exclusions is the coverage of a profile before and after removing the
+// excluded code
+type Exclusions struct {
+	Raw      Stats       `json:"raw"`
+	Adjusted Stats       `json:"adjusted"`
+	Excluded []Exclusion `json:"excluded"`
+}
+
+// FileExclusions returns what to exclude from coverage in the source file
+// name, relative to the project: the whole file when it matches one of the
+// globs or is generated, otherwise the functions annotated with
+// //gokode:nocover. A glob without a slash matches the file name in any
+// directory, and ** matches any number of directories.
+func FileExclusions(name string, src []byte, globs []string) ([]Exclusion, error) {
+	for _, glob := range globs {
+		if matchGlob(glob, name) {
+			return []Exclusion{{File: name, Reason: ReasonGlob, Rule: glob}}, nil
+		}
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", name, err)
+	}
+	if ast.IsGenerated(file) {
+		return []Exclusion{{File: name, Reason: ReasonGenerated}}, nil
+	}
+
+	var exclusions []Exclusion
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Doc == nil || !hasDirective(fn.Doc) {
+			continue
+		}
+		exclusions = append(exclusions, Exclusion{
+			File:      name,
+			Func:      funcName(fn),
+			StartLine: fset.Position(fn.Pos()).Line,
+			EndLine:   fset.Position(fn.End()).Line,
+			Reason:    ReasonAnnotation,
+		})
+	}
+	return exclusions, nil
+}
+
+func hasDirective(doc *ast.CommentGroup) bool {
+	for _, comment := range doc.List {
+		if strings.TrimSpace(comment.Text) == NoCoverDirective {
+			return true
+		}
+	}
+	return false
+}
+
+// funcName names a function the way gocyclo does, e.g. (*Parser).Parse
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	typ := fn.Recv.List[0].Type
+	star := ""
+	if ptr, ok := typ.(*ast.StarExpr); ok {
+		star = "*"
+		typ = ptr.X
+	}
+	switch t := typ.(type) {
+	case *ast.IndexExpr:
+		typ = t.X
+	case *ast.IndexListExpr:
+		typ = t.X
+	}
+	if ident, ok := typ.(*ast.Ident); ok {
+		return "(" + star + ident.Name + ")." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
+
+// matchGlob matches a slash separated name against a glob where ** matches
+// any number of path elements. Globs without a slash match the base name.
+func matchGlob(glob, name string) bool {
+	if !strings.Contains(glob, "/") {
+		ok, _ := path.Match(glob, path.Base(name))
+		return ok
+	}
+	return matchElems(strings.Split(glob, "/"), strings.Split(name, "/"))
+}
+
+func matchElems(glob, name []string) bool {
+	for len(glob) > 0 {
+		if glob[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchElems(glob[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(glob[0], name[0]); !ok {
+			return false
+		}
+		glob, name = glob[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// Exclude returns the profile without the blocks of the excluded code and
+// the coverage before and after. resolve maps the import path based file
+// names of the profile to file names relative to the project. The
+// statements of each exclusion are counted in the result.
+func (p *Profile) Exclude(exclusions []Exclusion, resolve func(string) string) (*Profile, *Exclusions) {
+	result := &Exclusions{Raw: p.Total(), Excluded: make([]Exclusion, len(exclusions))}
+	copy(result.Excluded, exclusions)
+	byFile := make(map[string][]int)
+	for i, e := range result.Excluded {
+		byFile[e.File] = append(byFile[e.File], i)
+	}
+
+	adjusted := &Profile{Mode: p.Mode}
+	for _, block := range p.Blocks {
+		excluded := false
+		for _, i := range byFile[resolve(block.File)] {
+			if e := &result.Excluded[i]; e.contains(block) {
+				e.Statements += block.NumStmt
+				if block.Count > 0 {
+					e.Covered += block.NumStmt
+				}
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			adjusted.Blocks = append(adjusted.Blocks, block)
+		}
+	}
+	result.Adjusted = adjusted.Total()
+	return adjusted, result
+}
+
+// Write writes the profile in the cover profile format
+func (p *Profile) Write(name string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "mode: %s\n", p.Mode)
+	for _, block := range p.Blocks {
+		fmt.Fprintf(&b, "%s:%d.%d,%d.%d %d %d\n", block.File, block.StartLine, block.StartCol, block.EndLine, block.EndCol, block.NumStmt, block.Count)
+	}
+	if err := fileutil.WriteFile(name, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("error writing coverage profile: %w", err)
+	}
+	return nil
+}
+
+// WriteExclusions atomically writes the coverage exclusions as JSON
+func WriteExclusions(name string, exclusions *Exclusions) error {
+	return fileutil.WriteJSON(name, exclusions, "coverage exclusions")
+}
+
+// ReadExclusions reads coverage exclusions written by WriteExclusions
+func ReadExclusions(name string) (*Exclusions, error) {
+	var exclusions Exclusions
+	if err := fileutil.ReadJSON(name, &exclusions, "coverage exclusions"); err != nil {
+		return nil, err
+	}
+	return &exclusions, nil
+}
@@ -0,0 +1,143 @@
+package coverage
+
+import (
+	"path"
+	"sort"
+
+	"github.com/andro-kes/gokode/internal/fileutil"
+)
+
+// IntegrationFileName is the name of the integration coverage file in the
+// metrics directory
+const IntegrationFileName = "integration_coverage.json"
+
+// Split is the statement coverage of unit tests, integration commands and
+// both combined, all measured over the statements of the combined profile
+type Split struct {
+	Statements  int `json:"statements"`
+	Unit        int `json:"unit"`
+	Integration int `json:"integration"`
+	Combined    int `json:"combined"`
+	// IntegrationOnly are the statements covered by integration commands but
+	// by no unit test
+	IntegrationOnly int `json:"integrationOnly"`
+}
+
+// UnitPercent returns the percentage of statements covered by unit tests
+func (s Split) UnitPercent() float64 {
+	return Stats{Statements: s.Statements, Covered: s.Unit}.Percent()
+}
+
+// IntegrationPercent returns the percentage of statements covered by
+// integration commands
+func (s Split) IntegrationPercent() float64 {
+	return Stats{Statements: s.Statements, Covered: s.Integration}.Percent()
+}
+
+// CombinedPercent returns the percentage of statements covered by either
+func (s Split) CombinedPercent() float64 {
+	return Stats{Statements: s.Statements, Covered: s.Combined}.Percent()
+}
+
+// PackageSplit is the coverage split of a single package
+type PackageSplit struct {
+	Package string `json:"package"`
+	Split
+}
+
+// IntegrationCommand is a configured integration command and its outcome
+type IntegrationCommand struct {
+	Command  string `json:"command"`
+	ExitCode int    `json:"exitCode"`
+	// Elapsed is the run time in seconds
+	Elapsed float64 `json:"elapsed"`
+}
+
+// Integration is the coverage of unit tests combined with integration
+// commands run against binaries built with -cover
+type Integration struct {
+	// Binaries are the main packages built with -cover
+	Binaries []string             `json:"binaries"`
+	Commands []IntegrationCommand `json:"commands"`
+	Split
+	Packages []PackageSplit `json:"packages"`
+}
+
+// Failed reports whether an integration command exited with an error
+func (i *Integration) Failed() bool {
+	for _, cmd := range i.Commands {
+		if cmd.ExitCode != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitCoverage compares the unit and integration profiles over the blocks
+// of their combined profile and returns the totals and the split of each
+// package, sorted by import path
+func SplitCoverage(unit, integration, combined *Profile) (Split, []PackageSplit) {
+	unitCovered := coveredBlocks(unit)
+	integrationCovered := coveredBlocks(integration)
+
+	var total Split
+	packages := make(map[string]*PackageSplit)
+	for _, block := range combined.Blocks {
+		pkg := path.Dir(block.File)
+		split, ok := packages[pkg]
+		if !ok {
+			split = &PackageSplit{Package: pkg}
+			packages[pkg] = split
+		}
+
+		pos := blockPos{block.File, block.StartLine, block.StartCol, block.EndLine, block.EndCol}
+		for _, s := range []*Split{&total, &split.Split} {
+			s.Statements += block.NumStmt
+			if block.Count > 0 {
+				s.Combined += block.NumStmt
+			}
+			if unitCovered[pos] {
+				s.Unit += block.NumStmt
+			}
+			if integrationCovered[pos] {
+				s.Integration += block.NumStmt
+				if !unitCovered[pos] {
+					s.IntegrationOnly += block.NumStmt
+				}
+			}
+		}
+	}
+
+	splits := make([]PackageSplit, 0, len(packages))
+	for _, split := range packages {
+		splits = append(splits, *split)
+	}
+	sort.Slice(splits, func(i, j int) bool { return splits[i].Package < splits[j].Package })
+	return total, splits
+}
+
+// coveredBlocks returns the positions of the blocks of the profile that
+// were executed
+func coveredBlocks(p *Profile) map[blockPos]bool {
+	covered := make(map[blockPos]bool)
+	for _, block := range p.Blocks {
+		if block.Count > 0 {
+			covered[blockPos{block.File, block.StartLine, block.StartCol, block.EndLine, block.EndCol}] = true
+		}
+	}
+	return covered
+}
+
+// WriteIntegration atomically writes the integration coverage as JSON
+func WriteIntegration(name string, integration *Integration) error {
+	return fileutil.WriteJSON(name, integration, "integration coverage")
+}
+
+// ReadIntegration reads integration coverage written by WriteIntegration
+func ReadIntegration(name string) (*Integration, error) {
+	var integration Integration
+	if err := fileutil.ReadJSON(name, &integration, "integration coverage"); err != nil {
+		return nil, err
+	}
+	return &integration, nil
+}
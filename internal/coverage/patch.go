@@ -0,0 +1,151 @@
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/fileutil"
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// PatchFile is the coverage of the changed lines of a single file
+type PatchFile struct {
+	// File is the file name relative to the project
+	File string `json:"file"`
+	// Lines is the number of changed lines inside blocks with statements
+	Lines   int `json:"lines"`
+	Covered int `json:"covered"`
+	// Uncovered are the changed lines inside blocks no test executed
+	Uncovered []int `json:"uncovered,omitempty"`
+}
+
+// Percent returns the percentage of covered changed lines
+func (f PatchFile) Percent() float64 {
+	return Stats{Statements: f.Lines, Covered: f.Covered}.Percent()
+}
+
+// Patch is the coverage of the lines changed since a base revision
+type Patch struct {
+	Base    string      `json:"base"`
+	Lines   int         `json:"lines"`
+	Covered int         `json:"covered"`
+	Files   []PatchFile `json:"files"`
+}
+
+// Percent returns the percentage of covered changed lines. A patch without
+// executable changes is fully covered.
+func (p *Patch) Percent() float64 {
+	if p.Lines == 0 {
+		return 100
+	}
+	return Stats{Statements: p.Lines, Covered: p.Covered}.Percent()
+}
+
+// PatchFileName is the name of the patch coverage file in the metrics directory
+const PatchFileName = "patch_coverage.json"
+
+// PatchCoverage computes the coverage of the changed lines. changed maps
+// file names, as returned by resolve, to their added or modified lines;
+// resolve maps the import path based file names of the profile to the same
+// form and returns an empty string for files outside the project.
+//
+// The profile only records blocks, so every changed line inside a block with
+// statements counts once, and it is covered when a block containing it was
+// executed. Crediting whole blocks instead would let a one-line change in a
+// large block outweigh the rest of the patch.
+func (p *Profile) PatchCoverage(changed map[string][]int, resolve func(string) string) *Patch {
+	// covered maps the executable changed lines of each file to whether a
+	// test ran them
+	covered := make(map[string]map[int]bool)
+
+	for _, block := range p.Blocks {
+		name := resolve(block.File)
+		lines, ok := changed[name]
+		if name == "" || !ok || block.NumStmt == 0 {
+			continue
+		}
+
+		for _, line := range lines {
+			if line < block.StartLine || line > block.EndLine {
+				continue
+			}
+			if covered[name] == nil {
+				covered[name] = make(map[int]bool)
+			}
+			// A line shared by two blocks is covered if either ran
+			covered[name][line] = covered[name][line] || block.Count > 0
+		}
+	}
+
+	patch := &Patch{}
+	for name, lines := range covered {
+		file := PatchFile{File: name, Lines: len(lines)}
+		for line, ran := range lines {
+			if ran {
+				file.Covered++
+			} else {
+				file.Uncovered = append(file.Uncovered, line)
+			}
+		}
+		sort.Ints(file.Uncovered)
+		patch.Lines += file.Lines
+		patch.Covered += file.Covered
+		patch.Files = append(patch.Files, file)
+	}
+	sort.Slice(patch.Files, func(i, j int) bool {
+		return patch.Files[i].File < patch.Files[j].File
+	})
+	return patch
+}
+
+// WritePatch atomically writes the patch coverage as JSON
+func WritePatch(name string, patch *Patch) error {
+	return fileutil.WriteJSON(name, patch, "patch coverage")
+}
+
+// ReadPatch reads patch coverage written by WritePatch
+func ReadPatch(name string) (*Patch, error) {
+	var patch Patch
+	if err := fileutil.ReadJSON(name, &patch, "patch coverage"); err != nil {
+		return nil, err
+	}
+	return &patch, nil
+}
+
+// WritePatchMarkdown renders the patch coverage as Markdown, e.g. for a pull
+// request comment
+func WritePatchMarkdown(w io.Writer, patch *Patch) error {
+	var b strings.Builder
+	b.WriteString(i18n.T("patch.markdown.title", patch.Percent()) + "\n\n")
+	b.WriteString(i18n.T("patch.markdown.summary", patch.Covered, patch.Lines, patch.Base) + "\n")
+
+	if len(patch.Files) > 0 {
+		b.WriteString("\n" + i18n.T("patch.markdown.header") + "\n|---|---:|---|\n")
+		for _, file := range patch.Files {
+			b.WriteString(i18n.T("patch.markdown.file", file.File, file.Percent(), file.Covered, file.Lines, LineRanges(file.Uncovered)) + "\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// LineRanges formats sorted line numbers compactly, e.g. "3-5, 9"
+func LineRanges(lines []int) string {
+	var parts []string
+	for i := 0; i < len(lines); {
+		j := i
+		for j+1 < len(lines) && lines[j+1] == lines[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, fmt.Sprint(lines[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", lines[i], lines[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,136 @@
+package depgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+)
+
+// Package is a package of the analysed module as reported by go list
+type Package struct {
+	ImportPath string
+	Dir        string
+	// GoFiles are the non-test Go files of the package, relative to Dir
+	GoFiles []string
+	// TestGoFiles and XTestGoFiles are the test files of the package and of
+	// its external _test package, relative to Dir
+	TestGoFiles  []string
+	XTestGoFiles []string
+	Deps         []string
+	TestImports  []string
+	XTestImports []string
+}
+
+// Graph is the import graph of the packages in a module
+type Graph struct {
+	Packages map[string]*Package
+	// Root is the directory go list was run in
+	Root string
+}
+
+// Load lists the packages under dir with go list -json ./...
+func Load(ctx context.Context, dir string) (*Graph, error) {
+	cmd := exec.CommandContext(ctx, "go", "list", "-e", "-json=ImportPath,Dir,GoFiles,TestGoFiles,XTestGoFiles,Deps,TestImports,XTestImports", "./...")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("error listing packages: %s", exitErr.Stderr)
+		}
+		return nil, fmt.Errorf("error listing packages: %w", err)
+	}
+
+	graph := &Graph{Packages: make(map[string]*Package), Root: dir}
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var pkg Package
+		err := decoder.Decode(&pkg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing go list output: %w", err)
+		}
+		graph.Packages[pkg.ImportPath] = &pkg
+	}
+	return graph, nil
+}
+
+// PackageForFile returns the package whose directory contains the file, or
+// nil if the file is not part of a listed package
+func (g *Graph) PackageForFile(file string) *Package {
+	dir := filepath.Dir(file)
+	for _, pkg := range g.Packages {
+		if pkg.Dir == dir {
+			return pkg
+		}
+	}
+	return nil
+}
+
+// Affected returns the import paths of the changed packages and of every
+// package that depends on one of them, directly, transitively or through its
+// tests, sorted
+func (g *Graph) Affected(changed []string) []string {
+	isChanged := make(map[string]bool, len(changed))
+	for _, path := range changed {
+		isChanged[path] = true
+	}
+
+	// dependsOnChanged reports whether importing path pulls in a change
+	dependsOnChanged := func(path string) bool {
+		if isChanged[path] {
+			return true
+		}
+		pkg, ok := g.Packages[path]
+		if !ok {
+			return false
+		}
+		for _, dep := range pkg.Deps {
+			if isChanged[dep] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var affected []string
+	for path, pkg := range g.Packages {
+		hit := dependsOnChanged(path)
+		for _, imp := range append(append([]string(nil), pkg.TestImports...), pkg.XTestImports...) {
+			if hit {
+				break
+			}
+			hit = dependsOnChanged(imp)
+		}
+		if hit {
+			affected = append(affected, path)
+		}
+	}
+	sort.Strings(affected)
+	return affected
+}
+
+// RelDir returns the package directory relative to the graph root in the
+// ./dir form accepted by go tools
+func (g *Graph) RelDir(importPath string) string {
+	pkg, ok := g.Packages[importPath]
+	if !ok {
+		return ""
+	}
+	rel, err := filepath.Rel(g.Root, pkg.Dir)
+	if err != nil {
+		return ""
+	}
+	if rel == "." {
+		return "."
+	}
+	return "./" + filepath.ToSlash(rel)
+}
@@ -0,0 +1,128 @@
+package depgraph
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testGraph mirrors go list output, where Deps is transitive
+func testGraph(root string) *Graph {
+	pkgs := []*Package{
+		{ImportPath: "example.com/m", Dir: root, Deps: []string{"example.com/m/internal/a", "example.com/m/internal/b", "fmt"}},
+		{ImportPath: "example.com/m/internal/a", Dir: filepath.Join(root, "internal", "a"), Deps: []string{"example.com/m/internal/b"}},
+		{ImportPath: "example.com/m/internal/b", Dir: filepath.Join(root, "internal", "b")},
+		{ImportPath: "example.com/m/internal/c", Dir: filepath.Join(root, "internal", "c"), XTestImports: []string{"example.com/m/internal/b"}},
+		{ImportPath: "example.com/m/internal/d", Dir: filepath.Join(root, "internal", "d"), Deps: []string{"fmt"}},
+	}
+	graph := &Graph{Packages: make(map[string]*Package), Root: root}
+	for _, pkg := range pkgs {
+		graph.Packages[pkg.ImportPath] = pkg
+	}
+	return graph
+}
+
+func TestAffected(t *testing.T) {
+	graph := testGraph(t.TempDir())
+
+	got := graph.Affected([]string{"example.com/m/internal/b"})
+	want := []string{
+		"example.com/m",
+		"example.com/m/internal/a",
+		"example.com/m/internal/b",
+		"example.com/m/internal/c",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Affected = %v, want %v", got, want)
+	}
+
+	if got := graph.Affected(nil); got != nil {
+		t.Errorf("Expected nothing affected without changes, got %v", got)
+	}
+}
+
+func TestPackageForFileAndRelDir(t *testing.T) {
+	root := t.TempDir()
+	graph := testGraph(root)
+
+	pkg := graph.PackageForFile(filepath.Join(root, "internal", "a", "a.go"))
+	if pkg == nil || pkg.ImportPath != "example.com/m/internal/a" {
+		t.Fatalf("Unexpected package: %+v", pkg)
+	}
+	if dir := graph.RelDir(pkg.ImportPath); dir != "./internal/a" {
+		t.Errorf("RelDir = %q, want ./internal/a", dir)
+	}
+	if dir := graph.RelDir("example.com/m"); dir != "." {
+		t.Errorf("RelDir of root package = %q, want .", dir)
+	}
+
+	if pkg := graph.PackageForFile(filepath.Join(root, "scripts", "gen.go")); pkg != nil {
+		t.Errorf("Expected no package for file outside the module, got %s", pkg.ImportPath)
+	}
+}
+
+// testGraphJSON mirrors go list -deps -test output for a module where b
+// imports a, c's external tests import b and d is unrelated
+const testGraphJSON = `
+{"ImportPath": "fmt", "Dir": "/go/src/fmt", "GoFiles": ["print.go"], "Standard": true}
+{"ImportPath": "example.com/m/a", "Dir": "ROOT/a", "GoFiles": ["a.go"]}
+{"ImportPath": "example.com/m/b", "Dir": "ROOT/b", "GoFiles": ["b.go"], "Imports": ["example.com/m/a"]}
+{"ImportPath": "example.com/m/b [example.com/m/b.test]", "Dir": "ROOT/b", "GoFiles": ["b.go", "b_test.go"], "Imports": ["example.com/m/a", "fmt"]}
+{"ImportPath": "example.com/m/b.test", "Dir": "ROOT/b", "GoFiles": ["/cache/testmain.go"], "Imports": ["example.com/m/b [example.com/m/b.test]"]}
+{"ImportPath": "example.com/m/c", "Dir": "ROOT/c", "GoFiles": ["c.go"], "XTestGoFiles": ["c_test.go"]}
+{"ImportPath": "example.com/m/c_test [example.com/m/c.test]", "Dir": "ROOT/c", "GoFiles": ["c_test.go"], "Imports": ["example.com/m/b", "example.com/m/c"]}
+{"ImportPath": "example.com/m/c.test", "Dir": "ROOT/c", "GoFiles": ["/cache/testmain.go"], "Imports": ["example.com/m/c_test [example.com/m/c.test]"]}
+{"ImportPath": "example.com/m/d", "Dir": "ROOT/d", "GoFiles": ["d.go"]}
+{"ImportPath": "example.com/m/d [example.com/m/d.test]", "Dir": "ROOT/d", "GoFiles": ["d.go", "d_test.go"], "Imports": ["fmt"]}
+{"ImportPath": "example.com/m/d.test", "Dir": "ROOT/d", "GoFiles": ["/cache/testmain.go"], "Imports": ["example.com/m/d [example.com/m/d.test]"]}
+`
+
+func loadTestGraphFixture(t *testing.T, root string) *TestGraph {
+	t.Helper()
+	graph, err := parseTestGraph(strings.NewReader(strings.ReplaceAll(testGraphJSON, "ROOT", root)), root)
+	if err != nil {
+		t.Fatalf("parseTestGraph failed: %v", err)
+	}
+	return graph
+}
+
+func TestSelectTests(t *testing.T) {
+	root := t.TempDir()
+	graph := loadTestGraphFixture(t, root)
+
+	selection := graph.SelectTests([]string{filepath.Join(root, "a", "a.go")})
+	if len(selection) != 2 {
+		t.Fatalf("Expected b and c to be selected, got %+v", selection)
+	}
+
+	b, c := selection[0], selection[1]
+	if b.Package != "example.com/m/b" || b.Dir != "./b" {
+		t.Errorf("Unexpected selection: %+v", b)
+	}
+	if !reflect.DeepEqual(b.Chain, []string{"example.com/m/b", "example.com/m/a"}) || !reflect.DeepEqual(b.Files, []string{"a/a.go"}) {
+		t.Errorf("Unexpected reason for b: %s", b.Reason())
+	}
+	if c.Package != "example.com/m/c" {
+		t.Errorf("Unexpected selection: %+v", c)
+	}
+	if !reflect.DeepEqual(c.Chain, []string{"example.com/m/c", "example.com/m/b", "example.com/m/a"}) {
+		t.Errorf("Unexpected chain for c: %v", c.Chain)
+	}
+
+	// A change to a test file selects only its own package
+	selection = graph.SelectTests([]string{filepath.Join(root, "d", "d_test.go")})
+	if len(selection) != 1 || selection[0].Package != "example.com/m/d" || len(selection[0].Chain) != 1 {
+		t.Errorf("Unexpected selection for a test file change: %+v", selection)
+	}
+
+	// Module files affect every package with tests
+	selection = graph.SelectTests([]string{filepath.Join(root, "go.mod")})
+	if len(selection) != 3 || !selection[0].ModuleChanged {
+		t.Errorf("Unexpected selection for a go.mod change: %+v", selection)
+	}
+
+	if selection := graph.SelectTests([]string{filepath.Join(root, "README.md")}); len(selection) != 0 {
+		t.Errorf("Expected no tests for a non-Go change, got %+v", selection)
+	}
+}
@@ -0,0 +1,283 @@
+package depgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/fileutil"
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// SelectionFile is the name of the test selection file in the metrics
+// directory
+const SelectionFile = "affected.json"
+
+// testNode is a package of the test graph. Packages compiled for a test
+// appear as separate nodes, e.g. "example.com/m/a [example.com/m/a.test]",
+// whose files include the test files.
+type testNode struct {
+	ImportPath   string
+	Dir          string
+	GoFiles      []string
+	CgoFiles     []string
+	XTestGoFiles []string
+	Imports      []string
+	Standard     bool
+}
+
+// TestGraph is the import graph of the test binaries of a module
+type TestGraph struct {
+	nodes map[string]*testNode
+	root  string
+}
+
+// LoadTestGraph lists the packages under dir together with their test
+// variants and dependencies with go list -deps -test
+func LoadTestGraph(ctx context.Context, dir string) (*TestGraph, error) {
+	cmd := exec.CommandContext(ctx, "go", "list", "-e", "-deps", "-test",
+		"-json=ImportPath,Dir,GoFiles,CgoFiles,XTestGoFiles,Imports,Standard", "./...")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("error listing packages: %s", exitErr.Stderr)
+		}
+		return nil, fmt.Errorf("error listing packages: %w", err)
+	}
+	return parseTestGraph(bytes.NewReader(output), dir)
+}
+
+func parseTestGraph(r io.Reader, dir string) (*TestGraph, error) {
+	graph := &TestGraph{nodes: make(map[string]*testNode), root: dir}
+	decoder := json.NewDecoder(r)
+	for {
+		var node testNode
+		err := decoder.Decode(&node)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing go list output: %w", err)
+		}
+		graph.nodes[node.ImportPath] = &node
+	}
+	return graph, nil
+}
+
+// TestSelection is a package whose tests are affected by a change
+type TestSelection struct {
+	// Package is the import path of the package under test and Dir its
+	// directory in the ./dir form accepted by go tools
+	Package string `json:"package"`
+	Dir     string `json:"dir"`
+	// Chain is the import chain from the package's tests to the changed
+	// package, starting with the package itself
+	Chain []string `json:"chain"`
+	// Files are the changed files of the last package in Chain, relative to
+	// the module root
+	Files []string `json:"files,omitempty"`
+	// ModuleChanged is set when the package was selected because go.mod,
+	// go.sum or go.work changed
+	ModuleChanged bool `json:"moduleChanged,omitempty"`
+}
+
+// Reason explains why the package was selected in the current language
+func (s TestSelection) Reason() string {
+	switch {
+	case s.ModuleChanged:
+		return i18n.T("affected.moduleChanged")
+	case len(s.Chain) <= 1:
+		return i18n.T("affected.changedFiles", strings.Join(s.Files, ", "))
+	default:
+		return i18n.T("affected.dependency",
+			s.Chain[len(s.Chain)-1], strings.Join(s.Chain, " → "), strings.Join(s.Files, ", "))
+	}
+}
+
+// SelectTests returns the packages whose tests transitively depend on one of
+// the changed files, given as absolute paths, sorted by package. A change to
+// a module file selects every package with tests.
+func (g *TestGraph) SelectTests(changed []string) []TestSelection {
+	changedFiles := make(map[string]bool)
+	changedDirs := make(map[string]bool)
+	moduleChanged := false
+	for _, file := range changed {
+		switch filepath.Base(file) {
+		case "go.mod", "go.sum", "go.work":
+			moduleChanged = true
+		}
+		if filepath.Ext(file) != ".go" {
+			continue
+		}
+		changedFiles[file] = true
+		// A deleted file changes its package although no node lists it
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			changedDirs[filepath.Dir(file)] = true
+		}
+	}
+
+	// changedIn returns the changed files of a node relative to the root
+	changedIn := func(node *testNode) []string {
+		var files []string
+		for _, list := range [][]string{node.GoFiles, node.CgoFiles, node.XTestGoFiles} {
+			for _, name := range list {
+				file := filepath.Join(node.Dir, name)
+				if changedFiles[file] {
+					files = append(files, g.rel(file))
+				}
+			}
+		}
+		if changedDirs[node.Dir] {
+			for file := range changedFiles {
+				if filepath.Dir(file) == node.Dir && !contains(files, g.rel(file)) {
+					files = append(files, g.rel(file))
+				}
+			}
+		}
+		sort.Strings(files)
+		return files
+	}
+
+	var selected []TestSelection
+	for path, node := range g.nodes {
+		pkg, ok := testedPackage(path)
+		if !ok || node.Standard {
+			continue
+		}
+		selection := TestSelection{Package: pkg, Dir: g.relDir(node.Dir)}
+		if moduleChanged {
+			selection.Chain = []string{pkg}
+			selection.ModuleChanged = true
+			selected = append(selected, selection)
+			continue
+		}
+		if chain, files := g.findChange(path, changedIn); chain != nil {
+			selection.Chain = chain
+			selection.Files = files
+			selected = append(selected, selection)
+		}
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].Package < selected[j].Package
+	})
+	return selected
+}
+
+// findChange searches the imports of the test binary at start breadth first
+// and returns the shortest import chain to a package with changed files
+func (g *TestGraph) findChange(start string, changedIn func(*testNode) []string) ([]string, []string) {
+	parent := map[string]string{start: ""}
+	queue := []string{start}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		node := g.nodes[path]
+
+		// The test main package itself is generated
+		if path != start {
+			if files := changedIn(node); len(files) > 0 {
+				var chain []string
+				for p := path; p != start; p = parent[p] {
+					chain = append([]string{displayPath(p)}, chain...)
+				}
+				return dedupe(chain), files
+			}
+		}
+
+		for _, imp := range node.Imports {
+			next, ok := g.nodes[imp]
+			if _, seen := parent[imp]; seen || !ok || next.Standard {
+				continue
+			}
+			parent[imp] = path
+			queue = append(queue, imp)
+		}
+	}
+	return nil, nil
+}
+
+// testedPackage returns the package a test binary node tests
+func testedPackage(path string) (string, bool) {
+	if strings.Contains(path, " ") {
+		return "", false
+	}
+	return strings.CutSuffix(path, ".test")
+}
+
+// displayPath strips the test variant suffix and the _test suffix of
+// external test packages from an import path
+func displayPath(path string) string {
+	if i := strings.Index(path, " ["); i >= 0 {
+		path = path[:i]
+	}
+	return strings.TrimSuffix(path, "_test")
+}
+
+// dedupe removes consecutive duplicates, which appear when a package's
+// external tests import its test variant
+func dedupe(chain []string) []string {
+	var result []string
+	for _, path := range chain {
+		if len(result) == 0 || result[len(result)-1] != path {
+			result = append(result, path)
+		}
+	}
+	return result
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *TestGraph) rel(file string) string {
+	rel, err := filepath.Rel(g.root, file)
+	if err != nil {
+		return file
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (g *TestGraph) relDir(dir string) string {
+	rel := g.rel(dir)
+	if rel == "." {
+		return "."
+	}
+	return "./" + rel
+}
+
+// WriteSelection atomically writes the test selection as JSON. The since
+// revision is recorded with it.
+func WriteSelection(name, since string, selection []TestSelection) error {
+	return fileutil.WriteJSON(name, Selection{Since: since, Packages: selection}, "test selection")
+}
+
+// Selection is the content of the test selection file
+type Selection struct {
+	Since    string          `json:"since"`
+	Packages []TestSelection `json:"packages"`
+}
+
+// ReadSelection reads a test selection written by WriteSelection
+func ReadSelection(name string) (*Selection, error) {
+	var selection Selection
+	if err := fileutil.ReadJSON(name, &selection, "test selection"); err != nil {
+		return nil, err
+	}
+	return &selection, nil
+}
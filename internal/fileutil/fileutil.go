@@ -0,0 +1,80 @@
+package fileutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes data to a temporary file next to name and renames it into
+// place, so readers only ever see the previous complete file or the new one
+func WriteFile(name string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	return Commit(tmpName, name)
+}
+
+// WriteJSON atomically writes v as indented JSON. what names the content in
+// errors, e.g. "flaky test report".
+func WriteJSON(name string, v any, what string) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", what, err)
+	}
+	if err := WriteFile(name, data, 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", what, err)
+	}
+	return nil
+}
+
+// ReadJSON decodes the JSON file written by WriteJSON into v. Errors reading
+// the file are returned as they are, so a missing file can be told apart.
+func ReadJSON(name string, v any, what string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("error parsing %s: %w", what, err)
+	}
+	return nil
+}
+
+// TempName returns a path in the same directory as name that an external tool
+// can write to before the result is committed with Commit
+func TempName(name string) string {
+	return filepath.Join(filepath.Dir(name), fmt.Sprintf(".%s.%d.tmp", filepath.Base(name), os.Getpid()))
+}
+
+// Commit atomically replaces name with the file at tmp
+func Commit(tmp, name string) error {
+	if err := os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
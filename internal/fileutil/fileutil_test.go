@@ -0,0 +1,100 @@
+package fileutil
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "report.json")
+
+	if err := os.WriteFile(name, []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to write initial file: %v", err)
+	}
+
+	if err := WriteFile(name, []byte("new"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(data) != "new" {
+		t.Errorf("Expected file content %q, got %q", "new", string(data))
+	}
+
+	// No temporary files should be left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected 1 file in directory, got %d", len(entries))
+	}
+}
+
+func TestWriteReadJSON(t *testing.T) {
+	type report struct {
+		Name  string   `json:"name"`
+		Lines []int    `json:"lines"`
+		Tags  []string `json:"tags,omitempty"`
+	}
+	dir := t.TempDir()
+	name := filepath.Join(dir, "report.json")
+
+	written := report{Name: "a.go", Lines: []int{3, 5}}
+	if err := WriteJSON(name, written, "test report"); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var read report
+	if err := ReadJSON(name, &read, "test report"); err != nil {
+		t.Fatalf("ReadJSON failed: %v", err)
+	}
+	if !reflect.DeepEqual(read, written) {
+		t.Errorf("Expected %+v after a round trip, got %+v", written, read)
+	}
+
+	if err := ReadJSON(filepath.Join(dir, "missing.json"), &read, "test report"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected a missing file error, got %v", err)
+	}
+
+	if err := os.WriteFile(name, []byte("{"), 0644); err != nil {
+		t.Fatalf("Failed to write report.json: %v", err)
+	}
+	if err := ReadJSON(name, &read, "test report"); err == nil || !strings.Contains(err.Error(), "error parsing test report") {
+		t.Errorf("Expected a parse error naming the content, got %v", err)
+	}
+	if err := WriteJSON(name, func() {}, "test report"); err == nil || !strings.Contains(err.Error(), "error encoding test report") {
+		t.Errorf("Expected an encoding error naming the content, got %v", err)
+	}
+}
+
+func TestCommit(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "coverage.out")
+	tmp := TempName(name)
+
+	if filepath.Dir(tmp) != dir {
+		t.Fatalf("Expected temp file in %s, got %s", dir, tmp)
+	}
+	if err := os.WriteFile(tmp, []byte("mode: set\n"), 0644); err != nil {
+		t.Fatalf("Failed to write temp file: %v", err)
+	}
+
+	if err := Commit(tmp, name); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Error("Expected temp file to be renamed")
+	}
+	if _, err := os.Stat(name); err != nil {
+		t.Errorf("Expected committed file to exist: %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package flaky
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/fileutil"
+	"github.com/andro-kes/gokode/internal/gotest"
+)
+
+// File is the name of the flaky test report in the metrics directory
+const File = "flaky.json"
+
+// TestStats aggregates the results of a test over repeated runs
+type TestStats struct {
+	Package string `json:"package"`
+	Name    string `json:"name"`
+	Passed  int    `json:"passed"`
+	Failed  int    `json:"failed"`
+	Skipped int    `json:"skipped"`
+	// FailingSeeds are the -shuffle seeds of the runs in which the test
+	// failed, empty when tests were not shuffled
+	FailingSeeds []int64 `json:"failingSeeds,omitempty"`
+}
+
+// Runs returns the number of runs the test took part in
+func (t TestStats) Runs() int {
+	return t.Passed + t.Failed + t.Skipped
+}
+
+// FailRatio returns the share of runs in which the test failed
+func (t TestStats) FailRatio() float64 {
+	if t.Runs() == 0 {
+		return 0
+	}
+	return float64(t.Failed) / float64(t.Runs())
+}
+
+// Flaky reports whether the test both passed and failed
+func (t TestStats) Flaky() bool {
+	return t.Passed > 0 && t.Failed > 0
+}
+
+// Report is the result of running a test suite repeatedly
+type Report struct {
+	Runs    int  `json:"runs"`
+	Shuffle bool `json:"shuffle"`
+	// Seeds are the -shuffle seeds of the runs in order
+	Seeds []int64     `json:"seeds,omitempty"`
+	Tests []TestStats `json:"tests"`
+}
+
+// Add records the results of one run. seed is ignored unless the run was
+// shuffled.
+func (r *Report) Add(seed int64, summary *gotest.Summary) {
+	r.Runs++
+	if r.Shuffle {
+		r.Seeds = append(r.Seeds, seed)
+	}
+
+	index := make(map[string]int, len(r.Tests))
+	for i, test := range r.Tests {
+		index[test.Package+"\x00"+test.Name] = i
+	}
+	for _, test := range summary.Tests {
+		key := test.Package + "\x00" + test.Name
+		i, ok := index[key]
+		if !ok {
+			i = len(r.Tests)
+			index[key] = i
+			r.Tests = append(r.Tests, TestStats{Package: test.Package, Name: test.Name})
+		}
+
+		stats := &r.Tests[i]
+		switch test.Status {
+		case gotest.StatusPass:
+			stats.Passed++
+		case gotest.StatusFail:
+			stats.Failed++
+			if r.Shuffle {
+				stats.FailingSeeds = append(stats.FailingSeeds, seed)
+			}
+		case gotest.StatusSkip:
+			stats.Skipped++
+		}
+	}
+
+	sort.Slice(r.Tests, func(i, j int) bool {
+		if r.Tests[i].Package != r.Tests[j].Package {
+			return r.Tests[i].Package < r.Tests[j].Package
+		}
+		return r.Tests[i].Name < r.Tests[j].Name
+	})
+}
+
+// Flaky returns the tests with mixed results, most often failing first
+func (r *Report) Flaky() []TestStats {
+	var flaky []TestStats
+	for _, test := range r.Tests {
+		if test.Flaky() {
+			flaky = append(flaky, test)
+		}
+	}
+	sort.SliceStable(flaky, func(i, j int) bool {
+		return flaky[i].FailRatio() > flaky[j].FailRatio()
+	})
+	return flaky
+}
+
+// Write atomically writes the report as JSON
+func Write(name string, report *Report) error {
+	return fileutil.WriteJSON(name, report, "flaky test report")
+}
+
+// Read reads a report written by Write
+func Read(name string) (*Report, error) {
+	var report Report
+	if err := fileutil.ReadJSON(name, &report, "flaky test report"); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Known identifies a test known to be flaky. An empty package matches the
+// test in any package.
+type Known struct {
+	Package string `json:"package,omitempty"`
+	Test    string `json:"test"`
+}
+
+// matches reports whether the known flaky test covers test. Quarantining a
+// test covers its subtests, and quarantining a subtest covers its parents,
+// which fail along with it.
+func (k Known) matches(test gotest.Test) bool {
+	if k.Package != "" && k.Package != test.Package {
+		return false
+	}
+	return test.Name == k.Test ||
+		strings.HasPrefix(test.Name, k.Test+"/") ||
+		strings.HasPrefix(k.Test, test.Name+"/")
+}
+
+// IsKnown reports whether test is in the known flaky list
+func IsKnown(known []Known, test gotest.Test) bool {
+	for _, k := range known {
+		if k.matches(test) {
+			return true
+		}
+	}
+	return false
+}
+
+// Quarantine splits the failed tests of a run into those in the known flaky
+// list and the rest. ok is set when the run failed only because of known
+// flaky tests, so that it can be treated as passing.
+func Quarantine(summary *gotest.Summary, known []Known) (quarantined []gotest.Test, ok bool) {
+	failedTests := make(map[string]bool)
+	ok = true
+	for _, test := range summary.Tests {
+		if test.Status != gotest.StatusFail {
+			continue
+		}
+		failedTests[test.Package] = true
+		if IsKnown(known, test) {
+			quarantined = append(quarantined, test)
+		} else {
+			ok = false
+		}
+	}
+
+	// A package that failed without a failing test, e.g. because it did
+	// not build, cannot be quarantined
+	for _, pkg := range summary.Packages {
+		if pkg.Status == gotest.StatusFail && !failedTests[pkg.Name] {
+			ok = false
+		}
+	}
+	return quarantined, ok
+}
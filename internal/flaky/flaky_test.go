@@ -0,0 +1,128 @@
+package flaky
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/andro-kes/gokode/internal/gotest"
+)
+
+func run(results map[string]string) *gotest.Summary {
+	summary := &gotest.Summary{}
+	for name, status := range results {
+		summary.Tests = append(summary.Tests, gotest.Test{Package: "example.com/m", Name: name, Status: status})
+	}
+	return summary
+}
+
+func TestReport(t *testing.T) {
+	report := &Report{Shuffle: true}
+	report.Add(11, run(map[string]string{"TestStable": gotest.StatusPass, "TestOrder": gotest.StatusPass}))
+	report.Add(22, run(map[string]string{"TestStable": gotest.StatusPass, "TestOrder": gotest.StatusFail}))
+	report.Add(33, run(map[string]string{"TestStable": gotest.StatusPass, "TestOrder": gotest.StatusFail, "TestBroken": gotest.StatusFail}))
+
+	if report.Runs != 3 || !reflect.DeepEqual(report.Seeds, []int64{11, 22, 33}) {
+		t.Errorf("Unexpected runs %d and seeds %v", report.Runs, report.Seeds)
+	}
+	if len(report.Tests) != 3 {
+		t.Fatalf("Expected 3 tests, got %+v", report.Tests)
+	}
+
+	flaky := report.Flaky()
+	if len(flaky) != 1 || flaky[0].Name != "TestOrder" {
+		t.Fatalf("Expected only TestOrder to be flaky, got %+v", flaky)
+	}
+	if flaky[0].Passed != 1 || flaky[0].Failed != 2 || !reflect.DeepEqual(flaky[0].FailingSeeds, []int64{22, 33}) {
+		t.Errorf("Unexpected stats: %+v", flaky[0])
+	}
+
+	name := filepath.Join(t.TempDir(), File)
+	if err := Write(name, report); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	read, err := Read(name)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if !reflect.DeepEqual(read, report) {
+		t.Errorf("Round trip mismatch: %+v", read)
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	known := []Known{{Test: "TestOrder"}, {Package: "example.com/other", Test: "TestNet"}}
+
+	summary := &gotest.Summary{
+		Tests: []gotest.Test{
+			{Package: "example.com/m", Name: "TestOrder", Status: gotest.StatusFail},
+			{Package: "example.com/m", Name: "TestOrder/sub", Status: gotest.StatusFail},
+			{Package: "example.com/m", Name: "TestStable", Status: gotest.StatusPass},
+		},
+		Packages: []gotest.Package{{Name: "example.com/m", Status: gotest.StatusFail}},
+	}
+	quarantined, ok := Quarantine(summary, known)
+	if !ok || len(quarantined) != 2 {
+		t.Errorf("Expected the run to pass with 2 quarantined failures, got %v %+v", ok, quarantined)
+	}
+
+	// The package restriction applies
+	summary.Tests = append(summary.Tests, gotest.Test{Package: "example.com/m", Name: "TestNet", Status: gotest.StatusFail})
+	if _, ok := Quarantine(summary, known); ok {
+		t.Error("Expected TestNet in another package not to be quarantined")
+	}
+
+	// A package that failed to build cannot be quarantined
+	build := &gotest.Summary{Packages: []gotest.Package{{Name: "example.com/m", Status: gotest.StatusFail}}}
+	if _, ok := Quarantine(build, known); ok {
+		t.Error("Expected a build failure not to be quarantined")
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	candidates := []string{"TestA", "TestB", "TestPolluter", "TestC", "TestD", "TestE"}
+
+	runs := 0
+	fails := func(before []string) (bool, error) {
+		runs++
+		for _, name := range before {
+			if name == "TestPolluter" {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	got, err := Minimize(candidates, fails)
+	if err != nil {
+		t.Fatalf("Minimize failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"TestPolluter"}) {
+		t.Errorf("Minimize = %v, want [TestPolluter]", got)
+	}
+	if runs > len(candidates) {
+		t.Errorf("Expected bisection to need fewer runs than candidates, got %d", runs)
+	}
+
+	// A failure caused by two tests together is narrowed down to both
+	both := func(before []string) (bool, error) {
+		seen := make(map[string]bool)
+		for _, name := range before {
+			seen[name] = true
+		}
+		return seen["TestA"] && seen["TestE"], nil
+	}
+	got, err = Minimize(candidates, both)
+	if err != nil {
+		t.Fatalf("Minimize failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"TestA", "TestE"}) {
+		t.Errorf("Minimize = %v, want [TestA TestE]", got)
+	}
+}
+
+func TestOrderDependencyString(t *testing.T) {
+	dep := OrderDependency{Test: "TestB", Reproduced: true, After: []string{"TestA"}}
+	if got := dep.String(); got != "TestB fails when run after TestA" {
+		t.Errorf("Unexpected description: %s", got)
+	}
+}
@@ -0,0 +1,100 @@
+package flaky
+
+import (
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/fileutil"
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// OrderFile is the name of the test order dependency report in the metrics
+// directory
+const OrderFile = "order.json"
+
+// OrderDependency is the outcome of searching for the tests a failing test
+// depends on being run, or not being run, before it
+type OrderDependency struct {
+	Package string `json:"package"`
+	Test    string `json:"test"`
+	Seed    int64  `json:"seed"`
+	// Reproduced is set when the test failed again with the seed
+	Reproduced bool `json:"reproduced"`
+	// FailsAlone is set when the test fails even when run on its own, so
+	// its failure does not depend on other tests
+	FailsAlone bool `json:"failsAlone,omitempty"`
+	// After is the smallest set of tests found that makes the test fail
+	// when run before it, in execution order
+	After []string `json:"after,omitempty"`
+}
+
+// String describes the dependency in a sentence in the current language
+func (d OrderDependency) String() string {
+	switch {
+	case !d.Reproduced:
+		return i18n.T("order.notReproduced", d.Test, d.Seed)
+	case d.FailsAlone:
+		return i18n.T("order.failsAlone", d.Test)
+	case len(d.After) == 0:
+		return i18n.T("order.noCulprit", d.Test, d.Seed)
+	default:
+		return i18n.T("order.after", d.Test, strings.Join(d.After, ", "))
+	}
+}
+
+// Minimize returns a smallest subset of candidates, in their original order,
+// for which fails still reports a failure. fails must report a failure for
+// all candidates. The candidates are bisected first, then removed one at a
+// time, so interactions between several tests are narrowed down as well.
+func Minimize(candidates []string, fails func([]string) (bool, error)) ([]string, error) {
+	for len(candidates) > 1 {
+		half := len(candidates) / 2
+		first, second := candidates[:half], candidates[half:]
+
+		failed, err := fails(first)
+		if err != nil {
+			return nil, err
+		}
+		if failed {
+			candidates = first
+			continue
+		}
+		failed, err = fails(second)
+		if err != nil {
+			return nil, err
+		}
+		if failed {
+			candidates = second
+			continue
+		}
+		// The failure needs tests from both halves
+		break
+	}
+
+	for i := 0; i < len(candidates) && len(candidates) > 1; {
+		without := append(append([]string(nil), candidates[:i]...), candidates[i+1:]...)
+		failed, err := fails(without)
+		if err != nil {
+			return nil, err
+		}
+		if failed {
+			candidates = without
+		} else {
+			i++
+		}
+	}
+	return candidates, nil
+}
+
+// WriteOrder atomically writes the order dependencies as JSON
+func WriteOrder(name string, deps []OrderDependency) error {
+	return fileutil.WriteJSON(name, deps, "test order dependencies")
+}
+
+// ReadOrder reads order dependencies written by WriteOrder
+func ReadOrder(name string) ([]OrderDependency, error) {
+	var deps []OrderDependency
+	if err := fileutil.ReadJSON(name, &deps, "test order dependencies"); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
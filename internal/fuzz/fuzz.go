@@ -0,0 +1,207 @@
+package fuzz
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andro-kes/gokode/internal/fileutil"
+	"github.com/andro-kes/gokode/internal/gotest"
+)
+
+// File is the name of the fuzzing report in the metrics directory
+const File = "fuzz.json"
+
+// maxCrashLines limits the crash output kept per target
+const maxCrashLines = 40
+
+// Input is a failing input written by the fuzzer
+type Input struct {
+	// Path is the file relative to the project, under testdata/fuzz
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// Target is the result of fuzzing a single fuzz target
+type Target struct {
+	Package string `json:"package"`
+	Name    string `json:"name"`
+	// Elapsed is the fuzzing time reported by the fuzzer
+	Elapsed time.Duration `json:"elapsed"`
+	Execs   int64         `json:"execs"`
+	// NewInteresting is the number of inputs added to the generated corpus
+	// in the cache by this run and Corpus the size of the whole corpus
+	NewInteresting int  `json:"newInteresting"`
+	Corpus         int  `json:"corpus"`
+	Failed         bool `json:"failed"`
+	// Message is the first line of the failure and Crash the failure output
+	Message string `json:"message,omitempty"`
+	// PanicSite is the position of the panic as file:line, if the target
+	// panicked
+	PanicSite string `json:"panicSite,omitempty"`
+	Crash     string `json:"crash,omitempty"`
+	// FailingInputs are the inputs added under testdata/fuzz by this run
+	FailingInputs []Input `json:"failingInputs,omitempty"`
+}
+
+// ExecsPerSec returns the average number of executions per second
+func (t Target) ExecsPerSec() float64 {
+	if t.Elapsed <= 0 {
+		return 0
+	}
+	return float64(t.Execs) / t.Elapsed.Seconds()
+}
+
+// Report is the result of fuzzing all targets
+type Report struct {
+	// FuzzTime is the -fuzztime each target was run with
+	FuzzTime string   `json:"fuzzTime"`
+	Targets  []Target `json:"targets"`
+}
+
+// Failed returns the targets for which the fuzzer found a failing input
+func (r *Report) Failed() []Target {
+	var failed []Target
+	for _, target := range r.Targets {
+		if target.Failed {
+			failed = append(failed, target)
+		}
+	}
+	return failed
+}
+
+// fuzzName matches the names printed by go test -list '^Fuzz'
+var fuzzName = regexp.MustCompile(`^Fuzz\w*$`)
+
+// ParseList parses go test -json -list '^Fuzz' output into the fuzz targets
+// of each package, in the order they were listed
+func ParseList(r io.Reader) (map[string][]string, error) {
+	targets := make(map[string][]string)
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if event, ok := gotest.DecodeEvent(line); ok && event.Action == "output" {
+			if name := strings.TrimSpace(event.Output); fuzzName.MatchString(name) {
+				targets[event.Package] = append(targets[event.Package], name)
+			}
+		}
+		if err == io.EOF {
+			return targets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// testingPanic matches the prefix the testing package adds to panics it
+// recovers, which points into the testing package rather than at the panic
+var testingPanic = regexp.MustCompile(`^testing\.go:\d+: (panic: .*)$`)
+
+// progressLine matches the fuzzer status lines, e.g.
+// fuzz: elapsed: 3s, execs: 86331 (28776/sec), new interesting: 1 (total: 2)
+var progressLine = regexp.MustCompile(`^fuzz: elapsed: (\S+), execs: (\d+) \(\d+/sec\), new interesting: (\d+) \(total: (\d+)\)`)
+
+// ParseOutput fills the target from the output of a go test -fuzz run: the
+// last status line of the fuzzer and, when the target failed, the failure
+// output
+func ParseOutput(r io.Reader, target *Target) error {
+	var crash []string
+	inCrash := false
+	// afterPanic counts the stack lines since the panic frame; the frame
+	// after it, a function and a position line, is where the panic happened
+	afterPanic := -1
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := progressLine.FindStringSubmatch(line); m != nil {
+			if elapsed, err := time.ParseDuration(m[1]); err == nil {
+				target.Elapsed = elapsed
+			}
+			target.Execs, _ = strconv.ParseInt(m[2], 10, 64)
+			target.NewInteresting, _ = strconv.Atoi(m[3])
+			target.Corpus, _ = strconv.Atoi(m[4])
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "--- FAIL: "+target.Name) {
+			target.Failed = true
+			inCrash = true
+			continue
+		}
+		if !inCrash {
+			continue
+		}
+		// The failure output ends where go test explains how to re-run it
+		if strings.HasPrefix(trimmed, "Failing input written to") || trimmed == "FAIL" {
+			inCrash = false
+			continue
+		}
+		if target.Message == "" && trimmed != "" {
+			target.Message = trimmed
+			if m := testingPanic.FindStringSubmatch(trimmed); m != nil {
+				target.Message = m[1]
+			}
+		}
+		switch {
+		case strings.HasPrefix(trimmed, "panic(") && target.PanicSite == "":
+			afterPanic = 0
+		case afterPanic >= 0:
+			afterPanic++
+			if afterPanic == 3 {
+				// Drop the program counter offset, e.g. +0x1ac
+				site, _, _ := strings.Cut(trimmed, " ")
+				target.PanicSite = site
+				afterPanic = -1
+			}
+		}
+		if len(crash) < maxCrashLines {
+			crash = append(crash, line)
+		}
+	}
+	target.Crash = strings.TrimRight(dedent(crash), "\n ")
+	return scanner.Err()
+}
+
+// dedent removes the indentation common to all non-empty lines
+func dedent(lines []string) string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	var b strings.Builder
+	for _, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			line = line[indent:]
+		}
+		b.WriteString(strings.TrimRight(line, " \t"))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Write atomically writes the report as JSON
+func Write(name string, report *Report) error {
+	return fileutil.WriteJSON(name, report, "fuzzing report")
+}
+
+// Read reads a report written by Write
+func Read(name string) (*Report, error) {
+	var report Report
+	if err := fileutil.ReadJSON(name, &report, "fuzzing report"); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
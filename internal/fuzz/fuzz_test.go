@@ -0,0 +1,120 @@
+package fuzz
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseList(t *testing.T) {
+	output := `{"Action":"start","Package":"ex/a"}
+{"Action":"output","Package":"ex/a","Output":"FuzzParse\n"}
+{"Action":"output","Package":"ex/a","Output":"FuzzDecode\n"}
+{"Action":"output","Package":"ex/a","Output":"ok  \tex/a\t0.003s\n"}
+{"Action":"pass","Package":"ex/a","Elapsed":0.003}
+{"Action":"output","Package":"ex/b","Output":"?   \tex/b\t[no test files]\n"}
+`
+	targets, err := ParseList(strings.NewReader(output))
+	if err != nil {
+		t.Fatalf("ParseList failed: %v", err)
+	}
+	want := map[string][]string{"ex/a": {"FuzzParse", "FuzzDecode"}}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("ParseList = %v, want %v", targets, want)
+	}
+}
+
+func TestParseOutputPassed(t *testing.T) {
+	output := `fuzz: elapsed: 0s, gathering baseline coverage: 0/3 completed
+fuzz: elapsed: 0s, gathering baseline coverage: 3/3 completed, now fuzzing with 8 workers
+fuzz: elapsed: 3s, execs: 86331 (28776/sec), new interesting: 1 (total: 4)
+fuzz: elapsed: 10s, execs: 300000 (30000/sec), new interesting: 3 (total: 6)
+PASS
+ok  	ex/a	10.030s
+`
+	target := Target{Package: "ex/a", Name: "FuzzParse"}
+	if err := ParseOutput(strings.NewReader(output), &target); err != nil {
+		t.Fatalf("ParseOutput failed: %v", err)
+	}
+	if target.Failed || target.Execs != 300000 || target.NewInteresting != 3 || target.Corpus != 6 {
+		t.Errorf("Unexpected target: %+v", target)
+	}
+	if target.Elapsed != 10*time.Second || target.ExecsPerSec() != 30000 {
+		t.Errorf("Expected 30000 execs/sec over 10s, got %v over %v", target.ExecsPerSec(), target.Elapsed)
+	}
+}
+
+func TestParseOutputPanic(t *testing.T) {
+	output := `fuzz: elapsed: 0s, gathering baseline coverage: 3/3 completed, now fuzzing with 1 workers
+fuzz: minimizing 31-byte failing input file
+fuzz: elapsed: 0s, minimizing
+--- FAIL: FuzzParse (0.12s)
+    --- FAIL: FuzzParse (0.00s)
+        testing.go:2076: panic: bad input
+            goroutine 3477 [running]:
+            runtime/debug.Stack()
+            	/usr/local/go/src/runtime/debug/stack.go:26 +0x9b
+            panic({0x83b6c0?, 0x65fc30?})
+            	/usr/local/go/src/runtime/panic.go:859 +0x125
+            ex/a.Parse(...)
+            	/src/ex/a/a.go:5
+            ex/a.FuzzParse.func1(0x0?, {0x2634b689f390, 0x4})
+            	/src/ex/a/a_test.go:8 +0x1ac
+
+    Failing input written to testdata/fuzz/FuzzParse/ec2765b762701d7d
+    To re-run:
+    go test -run=FuzzParse/ec2765b762701d7d
+FAIL
+exit status 1
+FAIL	ex/a	1.354s
+`
+	target := Target{Package: "ex/a", Name: "FuzzParse"}
+	if err := ParseOutput(strings.NewReader(output), &target); err != nil {
+		t.Fatalf("ParseOutput failed: %v", err)
+	}
+	if !target.Failed {
+		t.Fatal("Expected the target to have failed")
+	}
+	if target.Message != "panic: bad input" {
+		t.Errorf("Unexpected message: %q", target.Message)
+	}
+	if target.PanicSite != "/src/ex/a/a.go:5" {
+		t.Errorf("Unexpected panic site: %q", target.PanicSite)
+	}
+	if !strings.HasPrefix(target.Crash, "testing.go:2076: panic: bad input\n    goroutine 3477") {
+		t.Errorf("Expected the crash output to be dedented, got %q", target.Crash)
+	}
+	if strings.Contains(target.Crash, "To re-run") {
+		t.Errorf("Expected the crash output to end before the re-run hint, got %q", target.Crash)
+	}
+}
+
+func TestParseOutputFatal(t *testing.T) {
+	output := `--- FAIL: FuzzDecode (0.01s)
+    --- FAIL: FuzzDecode (0.00s)
+        a_test.go:21: decoded 3 bytes, want 4
+
+    Failing input written to testdata/fuzz/FuzzDecode/0a1b2c
+FAIL
+`
+	target := Target{Package: "ex/a", Name: "FuzzDecode"}
+	if err := ParseOutput(strings.NewReader(output), &target); err != nil {
+		t.Fatalf("ParseOutput failed: %v", err)
+	}
+	if !target.Failed || target.Message != "a_test.go:21: decoded 3 bytes, want 4" || target.PanicSite != "" {
+		t.Errorf("Unexpected target: %+v", target)
+	}
+}
+
+func TestFailed(t *testing.T) {
+	report := &Report{FuzzTime: "10s", Targets: []Target{
+		{Package: "ex/a", Name: "FuzzParse", Elapsed: 10 * time.Second, Execs: 1000, NewInteresting: 2, Corpus: 5},
+		{Package: "ex/a", Name: "FuzzDecode", Failed: true, Message: "boom", FailingInputs: []Input{
+			{Path: "a/testdata/fuzz/FuzzDecode/0a1b2c", Content: "go test fuzz v1\n[]byte(\"x\")\n"},
+		}},
+	}}
+	if got := report.Failed(); len(got) != 1 || got[0].Name != "FuzzDecode" {
+		t.Errorf("Unexpected failed targets: %+v", got)
+	}
+}
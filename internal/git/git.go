@@ -0,0 +1,236 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// run executes git in dir and returns its trimmed standard output
+func run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ShortCommit returns the abbreviated hash of HEAD in the repository
+// containing dir
+func ShortCommit(ctx context.Context, dir string) (string, error) {
+	return run(ctx, dir, "rev-parse", "--short", "HEAD")
+}
+
+// RepoRoot returns the top-level directory of the repository containing dir
+func RepoRoot(ctx context.Context, dir string) (string, error) {
+	return run(ctx, dir, "rev-parse", "--show-toplevel")
+}
+
+// AddWorktree checks out rev into a new detached worktree at path
+func AddWorktree(ctx context.Context, repoDir, path, rev string) error {
+	_, err := run(ctx, repoDir, "worktree", "add", "--detach", path, rev)
+	return err
+}
+
+// RemoveWorktree removes the worktree at path, discarding any changes made in
+// it, and prunes its administrative files
+func RemoveWorktree(ctx context.Context, repoDir, path string) error {
+	if _, err := run(ctx, repoDir, "worktree", "remove", "--force", path); err != nil {
+		return err
+	}
+	return PruneWorktrees(ctx, repoDir)
+}
+
+// PruneWorktrees removes the administrative files of worktrees whose
+// directories no longer exist
+func PruneWorktrees(ctx context.Context, repoDir string) error {
+	_, err := run(ctx, repoDir, "worktree", "prune")
+	return err
+}
+
+// ChangedFiles returns the absolute paths of files that differ between rev
+// and the working tree of the repository containing dir, including untracked
+// files. Deleted files are included.
+func ChangedFiles(ctx context.Context, dir, rev string) ([]string, error) {
+	root, err := RepoRoot(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := run(ctx, root, "diff", "--name-only", "-z", rev, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := run(ctx, root, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, name := range append(strings.Split(diff, "\x00"), strings.Split(untracked, "\x00")...) {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		files = append(files, filepath.Join(root, filepath.FromSlash(name)))
+	}
+	return files, nil
+}
+
+// ListFiles returns the tracked and untracked files under dir that git does
+// not ignore, relative to dir with forward slashes. Tracked files deleted
+// from the working tree are included.
+func ListFiles(ctx context.Context, dir string) ([]string, error) {
+	output, err := run(ctx, dir, "ls-files", "--cached", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(output, "\x00") {
+		// Files with merge conflicts are listed once per stage
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		files = append(files, name)
+	}
+	return files, nil
+}
+
+// ResolveRev returns the full commit hash rev refers to
+func ResolveRev(ctx context.Context, dir, rev string) (string, error) {
+	return run(ctx, dir, "rev-parse", "--verify", rev+"^{commit}")
+}
+
+// ChangedLines returns the lines added or modified since rev in the working
+// tree of the repository containing dir, keyed by absolute file path. Every
+// line of an untracked file counts as added.
+func ChangedLines(ctx context.Context, dir, rev string) (map[string][]int, error) {
+	root, err := RepoRoot(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Non-ASCII names are written as they are; ParseDiff unquotes the rest
+	diff, err := run(ctx, root, "-c", "core.quotePath=false", "diff", "-U0", "--no-color", "--no-ext-diff", rev, "--")
+	if err != nil {
+		return nil, err
+	}
+	relLines, err := ParseDiff(strings.NewReader(diff))
+	if err != nil {
+		return nil, err
+	}
+
+	untracked, err := run(ctx, root, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(untracked, "\x00") {
+		if name == "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil {
+			continue
+		}
+		n := bytes.Count(data, []byte("\n"))
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			n++
+		}
+		lines := make([]int, n)
+		for i := range lines {
+			lines[i] = i + 1
+		}
+		relLines[name] = lines
+	}
+
+	lines := make(map[string][]int, len(relLines))
+	for name, changed := range relLines {
+		lines[filepath.Join(root, filepath.FromSlash(name))] = changed
+	}
+	return lines, nil
+}
+
+// ParseDiff parses a unified diff produced with -U0 and returns the added
+// or modified lines of each file in the new version, keyed by the file's
+// slash-separated path. Names git quotes are unquoted. Deleted files are
+// omitted.
+func ParseDiff(r io.Reader) (map[string][]int, error) {
+	lines := make(map[string][]int)
+	file := ""
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			file = diffFileName(strings.TrimPrefix(line, "+++ "))
+		case strings.HasPrefix(line, "@@ ") && file != "":
+			start, count, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			for i := 0; i < count; i++ {
+				lines[file] = append(lines[file], start+i)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// diffFileName returns the path of a file header name such as b/main.go or
+// "b/d\321\200.go", or an empty string for /dev/null and unparseable names
+func diffFileName(name string) string {
+	name = strings.TrimSuffix(name, "\t")
+	if strings.HasPrefix(name, `"`) {
+		unquoted, err := strconv.Unquote(name)
+		if err != nil {
+			return ""
+		}
+		name = unquoted
+	}
+	file, ok := strings.CutPrefix(name, "b/")
+	if !ok {
+		return ""
+	}
+	return file
+}
+
+// parseHunkHeader returns the new-file range of a hunk header of the form
+// @@ -12,3 +14,5 @@
+func parseHunkHeader(line string) (start, count int, err error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, fmt.Errorf("malformed hunk header %q", line)
+	}
+	added := strings.TrimPrefix(fields[2], "+")
+	count = 1
+	if i := strings.Index(added, ","); i >= 0 {
+		if count, err = strconv.Atoi(added[i+1:]); err != nil {
+			return 0, 0, fmt.Errorf("malformed hunk header %q", line)
+		}
+		added = added[:i]
+	}
+	if start, err = strconv.Atoi(added); err != nil {
+		return 0, 0, fmt.Errorf("malformed hunk header %q", line)
+	}
+	return start, count, nil
+}
@@ -0,0 +1,83 @@
+package git
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDiff(t *testing.T) {
+	diff := `diff --git a/pkg/a.go b/pkg/a.go
+index 1111111..2222222 100644
+--- a/pkg/a.go
++++ b/pkg/a.go
+@@ -3,0 +4,2 @@ func A() {
++	x := 1
++	_ = x
+@@ -10 +12 @@ func B() {
+-	return 1
++	return 2
+@@ -20,3 +21,0 @@ func C() {
+-	a()
+-	b()
+-	c()
+diff --git a/new.go b/new.go
+new file mode 100644
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1,3 @@
++package main
++
++func main() {}
+diff --git a/old.go b/old.go
+deleted file mode 100644
+--- a/old.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-package main
+-
+`
+	got, err := ParseDiff(strings.NewReader(diff))
+	if err != nil {
+		t.Fatalf("ParseDiff failed: %v", err)
+	}
+	want := map[string][]int{
+		"pkg/a.go": {4, 5, 12},
+		"new.go":   {1, 2, 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDiff = %v, want %v", got, want)
+	}
+}
+
+func TestParseDiffQuotedNames(t *testing.T) {
+	diff := "diff --git \"a/d\\321\\200.go\" \"b/d\\321\\200.go\"\n" +
+		"--- \"a/d\\321\\200.go\"\n" +
+		"+++ \"b/d\\321\\200.go\"\n" +
+		"@@ -1 +1 @@\n" +
+		"-package a\n" +
+		"+package d\n" +
+		"diff --git a/with space.go b/with space.go\n" +
+		"--- a/with space.go\t\n" +
+		"+++ b/with space.go\t\n" +
+		"@@ -2,0 +3 @@\n" +
+		"+func F() {}\n"
+	got, err := ParseDiff(strings.NewReader(diff))
+	if err != nil {
+		t.Fatalf("ParseDiff failed: %v", err)
+	}
+	want := map[string][]int{
+		"dр.go":         {1},
+		"with space.go": {3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDiff = %v, want %v", got, want)
+	}
+}
+
+func TestParseDiffMalformedHunk(t *testing.T) {
+	diff := "+++ b/a.go\n@@ -1 +x @@\n"
+	if _, err := ParseDiff(strings.NewReader(diff)); err == nil {
+		t.Error("Expected an error for a malformed hunk header")
+	}
+}
@@ -0,0 +1,131 @@
+package gotest
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Test and package statuses
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// Test is the result of a single test or subtest
+type Test struct {
+	Package string        `json:"package"`
+	Name    string        `json:"name"`
+	Status  string        `json:"status"`
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// Package is the result of testing a single package
+type Package struct {
+	Name    string        `json:"name"`
+	Status  string        `json:"status"`
+	Elapsed time.Duration `json:"elapsed"`
+	Cached  bool          `json:"cached,omitempty"`
+}
+
+// Summary contains the results of a go test run
+type Summary struct {
+	Tests    []Test    `json:"tests"`
+	Packages []Package `json:"packages"`
+}
+
+// Count returns the number of tests with the given status
+func (s *Summary) Count(status string) int {
+	n := 0
+	for _, test := range s.Tests {
+		if test.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Elapsed returns the total time spent testing packages
+func (s *Summary) Elapsed() time.Duration {
+	var total time.Duration
+	for _, pkg := range s.Packages {
+		total += pkg.Elapsed
+	}
+	return total
+}
+
+var (
+	// --- PASS: TestName (0.00s), indented for subtests
+	testLine = regexp.MustCompile(`^\s*--- (PASS|FAIL|SKIP): (\S+) \(([\d.]+)s\)`)
+	// ok  	example.com/pkg	0.013s or FAIL	example.com/pkg	0.013s
+	packageLine = regexp.MustCompile(`^(ok|FAIL)\s+(\S+)\s+(\(cached\)|[\d.]+s)`)
+)
+
+// ParseVerboseFile parses the go test -v output stored at name
+func ParseVerboseFile(name string) (*Summary, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseVerbose(f)
+}
+
+// ParseVerbose parses go test -v output. Tests are attributed to the package
+// whose summary line follows them.
+func ParseVerbose(r io.Reader) (*Summary, error) {
+	summary := &Summary{}
+	pending := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if m := testLine.FindStringSubmatch(line); m != nil {
+			summary.Tests = append(summary.Tests, Test{
+				Name:    m[2],
+				Status:  strings.ToLower(m[1]),
+				Elapsed: parseSeconds(m[3]),
+			})
+			pending++
+			continue
+		}
+
+		if m := packageLine.FindStringSubmatch(line); m != nil {
+			pkg := Package{Name: m[2], Status: StatusPass}
+			if m[1] == "FAIL" {
+				pkg.Status = StatusFail
+			}
+			if m[3] == "(cached)" {
+				pkg.Cached = true
+			} else {
+				pkg.Elapsed = parseSeconds(strings.TrimSuffix(m[3], "s"))
+			}
+			summary.Packages = append(summary.Packages, pkg)
+
+			for i := len(summary.Tests) - pending; i < len(summary.Tests); i++ {
+				summary.Tests[i].Package = pkg.Name
+			}
+			pending = 0
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+func parseSeconds(s string) time.Duration {
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
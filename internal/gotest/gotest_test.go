@@ -0,0 +1,97 @@
+package gotest
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleOutput = `=== RUN   TestAdd
+--- PASS: TestAdd (0.00s)
+=== RUN   TestTable
+=== RUN   TestTable/case
+--- FAIL: TestTable (0.01s)
+    --- FAIL: TestTable/case (0.01s)
+FAIL
+FAIL	example.com/proj	0.013s
+=== RUN   TestSkipped
+--- SKIP: TestSkipped (0.00s)
+ok  	example.com/proj/sub	(cached)
+?   	example.com/proj/cmd	[no test files]
+`
+
+func TestParseVerbose(t *testing.T) {
+	summary, err := ParseVerbose(strings.NewReader(sampleOutput))
+	if err != nil {
+		t.Fatalf("ParseVerbose failed: %v", err)
+	}
+
+	if len(summary.Tests) != 4 {
+		t.Fatalf("Expected 4 tests, got %d", len(summary.Tests))
+	}
+	if summary.Count(StatusPass) != 1 || summary.Count(StatusFail) != 2 || summary.Count(StatusSkip) != 1 {
+		t.Errorf("Unexpected counts: pass=%d fail=%d skip=%d",
+			summary.Count(StatusPass), summary.Count(StatusFail), summary.Count(StatusSkip))
+	}
+
+	if summary.Tests[2].Name != "TestTable/case" || summary.Tests[2].Package != "example.com/proj" {
+		t.Errorf("Unexpected subtest: %+v", summary.Tests[2])
+	}
+	if summary.Tests[3].Package != "example.com/proj/sub" {
+		t.Errorf("Expected TestSkipped in example.com/proj/sub, got %s", summary.Tests[3].Package)
+	}
+
+	if len(summary.Packages) != 2 {
+		t.Fatalf("Expected 2 packages, got %d", len(summary.Packages))
+	}
+	if summary.Packages[0].Status != StatusFail || summary.Packages[0].Elapsed != 13*time.Millisecond {
+		t.Errorf("Unexpected package: %+v", summary.Packages[0])
+	}
+	if !summary.Packages[1].Cached {
+		t.Errorf("Expected cached package: %+v", summary.Packages[1])
+	}
+}
+
+const sampleEvents = `{"Action":"start","Package":"example.com/proj"}
+{"Action":"run","Package":"example.com/proj","Test":"TestAdd"}
+{"Action":"output","Package":"example.com/proj","Test":"TestAdd","Output":"--- PASS: TestAdd (0.00s)\n"}
+{"Action":"pass","Package":"example.com/proj","Test":"TestAdd","Elapsed":0}
+{"Action":"run","Package":"example.com/proj","Test":"TestTable/case"}
+{"Action":"fail","Package":"example.com/proj","Test":"TestTable/case","Elapsed":0.01}
+{"Action":"fail","Package":"example.com/proj","Test":"TestTable","Elapsed":0.01}
+{"Action":"output","Package":"example.com/proj","Output":"FAIL\texample.com/proj\t0.013s\n"}
+{"Action":"fail","Package":"example.com/proj","Elapsed":0.013}
+{"Action":"skip","Package":"example.com/proj/sub","Test":"TestSkipped","Elapsed":0}
+{"Action":"output","Package":"example.com/proj/sub","Output":"ok  \texample.com/proj/sub\t(cached)\n"}
+{"Action":"pass","Package":"example.com/proj/sub","Elapsed":0}
+{"Action":"output","Package":"example.com/proj/cmd","Output":"?   \texample.com/proj/cmd\t[no test files]\n"}
+{"Action":"skip","Package":"example.com/proj/cmd","Elapsed":0}
+`
+
+func TestParseJSON(t *testing.T) {
+	summary, err := ParseJSON(strings.NewReader(sampleEvents + "not an event\n"))
+	if err != nil {
+		t.Fatalf("ParseJSON failed: %v", err)
+	}
+
+	if len(summary.Tests) != 4 {
+		t.Fatalf("Expected 4 tests, got %d", len(summary.Tests))
+	}
+	if summary.Count(StatusPass) != 1 || summary.Count(StatusFail) != 2 || summary.Count(StatusSkip) != 1 {
+		t.Errorf("Unexpected counts: pass=%d fail=%d skip=%d",
+			summary.Count(StatusPass), summary.Count(StatusFail), summary.Count(StatusSkip))
+	}
+	if summary.Tests[3].Package != "example.com/proj/sub" {
+		t.Errorf("Unexpected package for TestSkipped: %s", summary.Tests[3].Package)
+	}
+
+	if len(summary.Packages) != 2 {
+		t.Fatalf("Expected 2 packages, got %d: %+v", len(summary.Packages), summary.Packages)
+	}
+	if summary.Packages[0].Status != StatusFail || summary.Packages[0].Elapsed != 13*time.Millisecond {
+		t.Errorf("Unexpected first package: %+v", summary.Packages[0])
+	}
+	if !summary.Packages[1].Cached {
+		t.Errorf("Expected second package to be cached: %+v", summary.Packages[1])
+	}
+}
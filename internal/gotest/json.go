@@ -0,0 +1,96 @@
+package gotest
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Event is a single go test -json event as produced by test2json
+type Event struct {
+	Time    time.Time `json:"Time"`
+	Action  string    `json:"Action"`
+	Package string    `json:"Package"`
+	Test    string    `json:"Test"`
+	Elapsed float64   `json:"Elapsed"`
+	Output  string    `json:"Output"`
+}
+
+// DecodeEvent decodes a line of go test -json output. Lines that are not
+// events, e.g. build errors printed by older toolchains, are reported as not
+// ok.
+func DecodeEvent(line []byte) (Event, bool) {
+	var event Event
+	if err := json.Unmarshal(line, &event); err != nil || event.Action == "" {
+		return Event{}, false
+	}
+	return event, true
+}
+
+// ParseJSONFile parses the go test -json output stored at name
+func ParseJSONFile(name string) (*Summary, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseJSON(f)
+}
+
+// ParseJSON parses go test -json output. Packages without test files are
+// left out, as they are in go test -v output.
+func ParseJSON(r io.Reader) (*Summary, error) {
+	summary := &Summary{}
+	cached := make(map[string]bool)
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if event, ok := DecodeEvent(line); ok {
+				addEvent(summary, cached, event)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return summary, nil
+}
+
+func addEvent(summary *Summary, cached map[string]bool, event Event) {
+	elapsed := time.Duration(event.Elapsed * float64(time.Second))
+
+	switch event.Action {
+	case "output":
+		// The package summary line is the only place a cached result shows
+		if event.Test == "" && strings.Contains(event.Output, "\t(cached)") {
+			cached[event.Package] = true
+		}
+	case StatusPass, StatusFail, StatusSkip:
+		if event.Test != "" {
+			summary.Tests = append(summary.Tests, Test{
+				Package: event.Package,
+				Name:    event.Test,
+				Status:  event.Action,
+				Elapsed: elapsed,
+			})
+			return
+		}
+		if event.Action == StatusSkip {
+			return
+		}
+		pkg := Package{Name: event.Package, Status: event.Action, Cached: cached[event.Package]}
+		if !pkg.Cached {
+			pkg.Elapsed = elapsed
+		}
+		summary.Packages = append(summary.Packages, pkg)
+	}
+}
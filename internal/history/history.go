@@ -0,0 +1,115 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// File is the name of the history file in the output directory
+const File = "history.jsonl"
+
+// Entry contains the key numbers of a single analysis run
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Commit    string    `json:"commit,omitempty"`
+	// Coverage is the statement coverage in percent
+	Coverage float64 `json:"coverage"`
+	// Issues is the number of issues reported by each tool
+	Issues        map[string]int `json:"issues"`
+	AvgComplexity float64        `json:"avgComplexity"`
+	MaxComplexity int            `json:"maxComplexity"`
+	LOC           int            `json:"loc"`
+	Tests         int            `json:"tests"`
+	// Duration is the wall time of the run in seconds
+	Duration float64 `json:"duration"`
+}
+
+// Append adds entry as a new line to the history file at name
+func Append(name string, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error encoding history entry: %w", err)
+	}
+
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening history: %w", err)
+	}
+	// A single write keeps concurrent appends from interleaving
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing history: %w", err)
+	}
+	return f.Close()
+}
+
+// Load reads all entries from the history file at name. Malformed lines, such
+// as one truncated by a crash, are skipped.
+func Load(name string) ([]Entry, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading history: %w", err)
+	}
+	return entries, nil
+}
+
+// CountLOC counts the non-blank lines of Go code under dir. Directories in
+// skipDirs and those ignored by the go tool are not counted.
+func CountLOC(dir string, skipDirs ...string) (int, error) {
+	skip := make(map[string]bool, len(skipDirs))
+	for _, d := range skipDirs {
+		skip[filepath.Clean(d)] = true
+	}
+
+	loc := 0
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (skip[path] || d.Name() == "vendor" || d.Name() == "testdata" ||
+				strings.HasPrefix(d.Name(), ".") || strings.HasPrefix(d.Name(), "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".go" {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.TrimSpace(line) != "" {
+				loc++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error counting lines of code: %w", err)
+	}
+	return loc, nil
+}
@@ -0,0 +1,61 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendLoad(t *testing.T) {
+	name := filepath.Join(t.TempDir(), File)
+
+	first := Entry{Timestamp: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC), Commit: "abc", Coverage: 50, Tests: 3}
+	second := Entry{Timestamp: time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC), Commit: "def", Coverage: 62.5, Tests: 5}
+	for _, entry := range []Entry{first, second} {
+		if err := Append(name, entry); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	// A truncated line from an interrupted write is skipped
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open history: %v", err)
+	}
+	f.WriteString(`{"timestamp":"2026-10`)
+	f.Close()
+
+	entries, err := Load(name)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[1].Commit != "def" || entries[1].Coverage != 62.5 || entries[1].Tests != 5 {
+		t.Errorf("Unexpected entry: %+v", entries[1])
+	}
+}
+
+func TestCountLOC(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write main.go: %v", err)
+	}
+	metricsDir := filepath.Join(dir, "metrics")
+	if err := os.MkdirAll(metricsDir, 0755); err != nil {
+		t.Fatalf("Failed to create metrics dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(metricsDir, "skip.go"), []byte("package skip\n"), 0644); err != nil {
+		t.Fatalf("Failed to write skip.go: %v", err)
+	}
+
+	loc, err := CountLOC(dir, metricsDir)
+	if err != nil {
+		t.Fatalf("CountLOC failed: %v", err)
+	}
+	if loc != 3 {
+		t.Errorf("Expected 3 lines of code, got %d", loc)
+	}
+}
@@ -0,0 +1,302 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Default is the language used when none is selected or the selected one has
+// no catalog
+const Default = "en"
+
+// catalogs holds a JSON message catalog per language, named after it
+//
+//go:embed catalog/*.json
+var catalogs embed.FS
+
+// Format describes how a language writes numbers and dates
+type Format struct {
+	// Decimal separates the fraction and Group the thousands of a number
+	Decimal string `json:"decimal"`
+	Group   string `json:"group"`
+	// MinGroupDigits is the number of integer digits from which thousands
+	// are grouped, e.g. 5 to write 1234 but 12 345
+	MinGroupDigits int `json:"minGroupDigits"`
+	// Percent is the format of a percentage given the formatted number
+	Percent string `json:"percent"`
+	// DateTime and ShortDateTime are time layouts
+	DateTime      string `json:"dateTime"`
+	ShortDateTime string `json:"shortDateTime"`
+}
+
+// catalog is the content of a catalog file
+type catalog struct {
+	Format   Format            `json:"format"`
+	Messages map[string]string `json:"messages"`
+}
+
+// Locale translates messages and formats numbers and dates for a language
+type Locale struct {
+	// Tag is the language code, e.g. en
+	Tag      string
+	format   Format
+	messages map[string]string
+	// fallback provides the messages missing from the catalog
+	fallback *Locale
+}
+
+var (
+	locales = loadCatalogs()
+	current = locales[Default]
+)
+
+// loadCatalogs parses the embedded catalogs. They are part of the binary, so
+// a broken one is a programming error.
+func loadCatalogs() map[string]*Locale {
+	files, err := catalogs.ReadDir("catalog")
+	if err != nil {
+		panic(err)
+	}
+	loaded := make(map[string]*Locale, len(files))
+	for _, file := range files {
+		data, err := catalogs.ReadFile(path.Join("catalog", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var c catalog
+		if err := json.Unmarshal(data, &c); err != nil {
+			panic(fmt.Sprintf("error parsing catalog %s: %v", file.Name(), err))
+		}
+		tag := strings.TrimSuffix(file.Name(), ".json")
+		loaded[tag] = &Locale{Tag: tag, format: c.Format, messages: c.Messages}
+	}
+	for tag, l := range loaded {
+		if tag != Default {
+			l.fallback = loaded[Default]
+		}
+	}
+	return loaded
+}
+
+// Languages returns the codes of the languages with a catalog, sorted
+func Languages() []string {
+	tags := make([]string, 0, len(locales))
+	for tag := range locales {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Lookup returns the locale of a language code or POSIX locale name such as
+// ru, en-US or ru_RU.UTF-8
+func Lookup(name string) (*Locale, bool) {
+	name = strings.ToLower(name)
+	if i := strings.IndexAny(name, ".@"); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.IndexAny(name, "_-"); i >= 0 {
+		name = name[:i]
+	}
+	l, ok := locales[name]
+	return l, ok
+}
+
+// FromEnv returns the locale name of the environment, looking at LC_ALL,
+// LC_MESSAGES and LANG in the order POSIX gives them precedence
+func FromEnv() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Set makes l the locale of the package-level functions
+func Set(l *Locale) {
+	current = l
+}
+
+// Current returns the locale set with Set, English by default
+func Current() *Locale {
+	return current
+}
+
+// T translates a message into the current language
+func T(key string, args ...any) string {
+	return current.T(key, args...)
+}
+
+// Println prints a translated message and a newline to standard output
+func Println(key string, args ...any) {
+	fmt.Println(current.T(key, args...))
+}
+
+// Fprintln writes a translated message and a newline to w
+func Fprintln(w io.Writer, key string, args ...any) {
+	fmt.Fprintln(w, current.T(key, args...))
+}
+
+// T returns the message with the key formatted with args like fmt.Sprintf.
+// Floating point arguments are written with the language's separators. A
+// missing message falls back to English and then to the key itself.
+func (l *Locale) T(key string, args ...any) string {
+	message, ok := l.lookup(key)
+	if !ok {
+		return key
+	}
+	localized := make([]any, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case float64:
+			localized[i] = number{l, v}
+		case float32:
+			localized[i] = number{l, float64(v)}
+		default:
+			localized[i] = arg
+		}
+	}
+	return fmt.Sprintf(message, localized...)
+}
+
+// Name translates the name of a kind of thing, such as an analysis step or a
+// mutation operator, from the message kind.name. Names without a message are
+// returned as they are.
+func (l *Locale) Name(kind, name string) string {
+	if message, ok := l.lookup(kind + "." + name); ok {
+		return message
+	}
+	return name
+}
+
+func (l *Locale) lookup(key string) (string, bool) {
+	if message, ok := l.messages[key]; ok {
+		return message, true
+	}
+	if l.fallback != nil {
+		return l.fallback.lookup(key)
+	}
+	return "", false
+}
+
+// Number formats v with the given number of decimals
+func (l *Locale) Number(v float64, decimals int) string {
+	return l.Localize(strconv.FormatFloat(v, 'f', decimals, 64))
+}
+
+// Int formats an integer of any type with grouped thousands
+func (l *Locale) Int(n any) string {
+	return l.Localize(fmt.Sprint(n))
+}
+
+// Percent formats a percentage with one decimal
+func (l *Locale) Percent(v float64) string {
+	return fmt.Sprintf(l.format.Percent, l.Number(v, 1))
+}
+
+// DateTime formats a date and time
+func (l *Locale) DateTime(t time.Time) string {
+	return t.Format(l.format.DateTime)
+}
+
+// ShortDateTime formats a date and time without the year and seconds
+func (l *Locale) ShortDateTime(t time.Time) string {
+	return t.Format(l.format.ShortDateTime)
+}
+
+// Duration formats d rounded to milliseconds below a second, tenths of a
+// second below a minute and seconds above
+func (l *Locale) Duration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return l.T("duration.ms", int(d.Round(time.Millisecond)/time.Millisecond))
+	case d < time.Minute:
+		return l.T("duration.s", d.Seconds())
+	case d < time.Hour:
+		d = d.Round(time.Second)
+		return l.T("duration.min", int(d/time.Minute), int(d%time.Minute/time.Second))
+	}
+	d = d.Round(time.Minute)
+	return l.T("duration.h", int(d/time.Hour), int(d%time.Hour/time.Minute))
+}
+
+// Funcs returns template functions for translated and localized output
+func (l *Locale) Funcs() template.FuncMap {
+	return template.FuncMap{
+		"lang":     func() string { return l.Tag },
+		"t":        l.T,
+		"name":     l.Name,
+		"num":      l.Number,
+		"int":      l.Int,
+		"percent":  l.Percent,
+		"duration": l.Duration,
+	}
+}
+
+// Localize replaces the separators of a number formatted by strconv or fmt
+// with the language's and groups the thousands of its integer part
+func (l *Locale) Localize(s string) string {
+	sign := ""
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		sign, s = s[:1], s[1:]
+	}
+	integer, fraction, hasFraction := strings.Cut(s, ".")
+	if len(integer) >= l.format.MinGroupDigits && strings.Trim(integer, "0123456789") == "" {
+		var b strings.Builder
+		for i, digit := range integer {
+			if i > 0 && (len(integer)-i)%3 == 0 {
+				b.WriteString(l.format.Group)
+			}
+			b.WriteRune(digit)
+		}
+		integer = b.String()
+	}
+	if hasFraction {
+		return sign + integer + l.format.Decimal + fraction
+	}
+	return sign + integer
+}
+
+// number formats a floating point argument of T like fmt, with the
+// separators of the locale
+type number struct {
+	l *Locale
+	v float64
+}
+
+func (n number) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'f', 'F', 'g', 'G', 'v':
+	default:
+		fmt.Fprintf(f, fmt.FormatString(f, verb), n.v)
+		return
+	}
+	spec := "%"
+	if f.Flag('+') {
+		spec += "+"
+	}
+	if precision, ok := f.Precision(); ok {
+		spec += "." + strconv.Itoa(precision)
+	}
+	s := n.l.Localize(fmt.Sprintf(spec+string(verb), n.v))
+	if width, ok := f.Width(); ok && utf8.RuneCountInString(s) < width {
+		padding := strings.Repeat(" ", width-utf8.RuneCountInString(s))
+		if f.Flag('-') {
+			s += padding
+		} else {
+			s = padding + s
+		}
+	}
+	io.WriteString(f, s)
+}
@@ -0,0 +1,170 @@
+package i18n
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// verb matches a formatting verb of a message, with an optional argument
+// index
+var verb = regexp.MustCompile(`%(\[(\d+)\])?[-+# 0]*\d*(\.\d+)?([a-zA-Z%])`)
+
+// sampleArgs returns arguments of the types the verbs of an English message
+// expect
+func sampleArgs(message string) []any {
+	var args []any
+	for _, m := range verb.FindAllStringSubmatch(message, -1) {
+		switch m[4] {
+		case "%":
+		case "d":
+			args = append(args, 7)
+		case "f", "g":
+			args = append(args, 1.5)
+		default:
+			args = append(args, "x")
+		}
+	}
+	return args
+}
+
+func TestCatalogsHaveSameMessages(t *testing.T) {
+	en := locales[Default]
+	for _, tag := range Languages() {
+		l := locales[tag]
+		for key := range en.messages {
+			if _, ok := l.messages[key]; !ok {
+				t.Errorf("%s: missing message %s", tag, key)
+			}
+		}
+		for key := range l.messages {
+			if _, ok := en.messages[key]; !ok {
+				t.Errorf("%s: message %s is not in the %s catalog", tag, key, Default)
+			}
+		}
+	}
+}
+
+func TestCatalogsFormat(t *testing.T) {
+	for key, message := range locales[Default].messages {
+		args := sampleArgs(message)
+		for _, tag := range Languages() {
+			if got := locales[tag].T(key, args...); strings.Contains(got, "%!") {
+				t.Errorf("%s: message %s does not match its arguments: %s", tag, key, got)
+			}
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for name, expected := range map[string]string{
+		"ru":          "ru",
+		"en-US":       "en",
+		"ru_RU.UTF-8": "ru",
+		"RU_ru":       "ru",
+		"en_GB@euro":  "en",
+	} {
+		l, ok := Lookup(name)
+		if !ok || l.Tag != expected {
+			t.Errorf("Lookup(%q) = %v, %v, expected %s", name, l, ok, expected)
+		}
+	}
+	for _, name := range []string{"", "C", "C.UTF-8", "de_DE.UTF-8"} {
+		if _, ok := Lookup(name); ok {
+			t.Errorf("Expected no catalog for %q", name)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	en, _ := Lookup("en")
+	ru, _ := Lookup("ru")
+
+	for _, tc := range []struct {
+		got, expected string
+	}{
+		{en.Number(12345.678, 2), "12,345.68"},
+		{en.Number(-1234, 0), "-1,234"},
+		{en.Number(999.5, 1), "999.5"},
+		{en.Int(int64(25000)), "25,000"},
+		{en.Percent(66.666), "66.7%"},
+		{ru.Number(12345.678, 2), "12 345,68"},
+		{ru.Number(1234.5, 1), "1234,5"},
+		{ru.Int(1234567), "1 234 567"},
+		{ru.Percent(66.666), "66,7 %"},
+		{ru.Localize("1.5e-05"), "1,5e-05"},
+	} {
+		if tc.got != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, tc.got)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	en, _ := Lookup("en")
+	ru, _ := Lookup("ru")
+
+	if got := ru.T("gate.scoreFunc", 31.4, "calc", "Sum"); got != "31,4 (calc.Sum)" {
+		t.Errorf("Expected a decimal comma, got %q", got)
+	}
+	if got := en.T("gate.change", 5.0); got != "+5.0%" {
+		t.Errorf("Expected a signed percentage, got %q", got)
+	}
+	if got := ru.T("cache.step", "Vet", 3, "1 КиБ"); !strings.Contains(got, "Vet") || !strings.Contains(got, "    3") {
+		t.Errorf("Expected padded arguments, got %q", got)
+	}
+	if got := en.T("no.such.message"); got != "no.such.message" {
+		t.Errorf("Expected the key of a missing message, got %q", got)
+	}
+
+	partial := &Locale{Tag: "xx", format: ru.format, messages: map[string]string{}, fallback: en}
+	if got := partial.T("report.title"); got != en.T("report.title") {
+		t.Errorf("Expected the English message as a fallback, got %q", got)
+	}
+}
+
+func TestName(t *testing.T) {
+	ru, _ := Lookup("ru")
+	if got := ru.Name("status", "skipped"); got != "пропущен" {
+		t.Errorf("Expected a translated status, got %q", got)
+	}
+	if got := ru.Name("step", "Custom step"); got != "Custom step" {
+		t.Errorf("Expected an unknown name as it is, got %q", got)
+	}
+}
+
+func TestDates(t *testing.T) {
+	en, _ := Lookup("en")
+	ru, _ := Lookup("ru")
+	date := time.Date(2026, 10, 18, 9, 5, 3, 0, time.UTC)
+
+	if got := en.DateTime(date); got != "Oct 18, 2026 09:05:03" {
+		t.Errorf("Unexpected English date %q", got)
+	}
+	if got := ru.DateTime(date); got != "18.10.2026 09:05:03" {
+		t.Errorf("Unexpected Russian date %q", got)
+	}
+	if got := ru.ShortDateTime(date); got != "18.10 09:05" {
+		t.Errorf("Unexpected Russian short date %q", got)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	en, _ := Lookup("en")
+	ru, _ := Lookup("ru")
+
+	for _, tc := range []struct {
+		got, expected string
+	}{
+		{en.Duration(250 * time.Millisecond), "250 ms"},
+		{en.Duration(1500 * time.Millisecond), "1.5 s"},
+		{en.Duration(90 * time.Second), "1 min 30 s"},
+		{en.Duration(2*time.Hour + 5*time.Minute), "2 h 5 min"},
+		{ru.Duration(1500 * time.Millisecond), "1,5 с"},
+	} {
+		if tc.got != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, tc.got)
+		}
+	}
+}
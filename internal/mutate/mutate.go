@@ -0,0 +1,213 @@
+package mutate
+
+import (
+	"sort"
+
+	"github.com/andro-kes/gokode/internal/fileutil"
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// File is the name of the mutation testing report in the metrics directory
+const File = "mutation.json"
+
+// Mutation operators
+const (
+	OperatorConditional = "conditional"
+	OperatorArithmetic  = "arithmetic"
+	OperatorCall        = "call"
+	OperatorReturn      = "return"
+)
+
+// Status is the outcome of running the tests against a mutant
+type Status string
+
+const (
+	// StatusKilled means a test failed with the mutant applied
+	StatusKilled Status = "killed"
+	// StatusTimeout means the tests did not finish in time, usually because
+	// the mutant loops forever; it counts as killed
+	StatusTimeout Status = "timeout"
+	// StatusSurvived means all tests passed with the mutant applied
+	StatusSurvived Status = "survived"
+	// StatusNotViable means the mutant did not compile; it is left out of the
+	// score
+	StatusNotViable Status = "not_viable"
+	// StatusNotCovered means no test depends on the mutated file, so the
+	// mutant was not run; it is left out of the score
+	StatusNotCovered Status = "not_covered"
+)
+
+// Mutant is a single change to a source file
+type Mutant struct {
+	// File is relative to the project
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// Func is the function containing the mutant as gocyclo names it, e.g.
+	// (*Parser).Parse for methods
+	Func     string `json:"func"`
+	Operator string `json:"operator"`
+	// Original and Mutated are the mutated expression before and after
+	Original string `json:"original"`
+	Mutated  string `json:"mutated"`
+	Status   Status `json:"status,omitempty"`
+	// KilledBy is the first test that failed with the mutant applied
+	KilledBy string `json:"killedBy,omitempty"`
+
+	// Offset and End are the byte range in the file replaced by Replacement
+	Offset      int    `json:"-"`
+	End         int    `json:"-"`
+	Replacement string `json:"-"`
+}
+
+// Apply returns src with the mutant applied
+func (m Mutant) Apply(src []byte) []byte {
+	mutated := make([]byte, 0, len(src)-(m.End-m.Offset)+len(m.Replacement))
+	mutated = append(mutated, src[:m.Offset]...)
+	mutated = append(mutated, m.Replacement...)
+	return append(mutated, src[m.End:]...)
+}
+
+// Change describes the mutant, e.g. "a > b → a <= b"
+func (m Mutant) Change() string {
+	if m.Operator == OperatorCall {
+		return i18n.T("mutate.removed", m.Original)
+	}
+	return m.Original + " → " + m.Mutated
+}
+
+// Detected reports whether the tests noticed the mutant
+func (m Mutant) Detected() bool {
+	return m.Status == StatusKilled || m.Status == StatusTimeout
+}
+
+// Skip is a file that was not mutated
+type Skip struct {
+	File   string `json:"file"`
+	Reason string `json:"reason"`
+}
+
+// Report is the result of mutation testing
+type Report struct {
+	Mutants []Mutant `json:"mutants"`
+	Skipped []Skip   `json:"skipped,omitempty"`
+}
+
+// Counts returns the number of detected, surviving, not viable and not
+// covered mutants
+func (r *Report) Counts() (detected, survived, notViable, notCovered int) {
+	for _, m := range r.Mutants {
+		switch {
+		case m.Detected():
+			detected++
+		case m.Status == StatusSurvived:
+			survived++
+		case m.Status == StatusNotViable:
+			notViable++
+		case m.Status == StatusNotCovered:
+			notCovered++
+		}
+	}
+	return detected, survived, notViable, notCovered
+}
+
+// Score returns the percentage of viable mutants covered by tests that the
+// tests detected
+func (r *Report) Score() float64 {
+	detected, survived, _, _ := r.Counts()
+	return score(detected, survived)
+}
+
+// FileCount is the number of mutants in a file
+type FileCount struct {
+	File    string
+	Mutants int
+}
+
+// NotCovered returns the files whose mutants no test depends on, sorted
+func (r *Report) NotCovered() []FileCount {
+	var files []FileCount
+	index := make(map[string]int)
+	for _, m := range r.Mutants {
+		if m.Status != StatusNotCovered {
+			continue
+		}
+		i, ok := index[m.File]
+		if !ok {
+			i = len(files)
+			index[m.File] = i
+			files = append(files, FileCount{File: m.File})
+		}
+		files[i].Mutants++
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].File < files[j].File })
+	return files
+}
+
+func score(detected, survived int) float64 {
+	if detected+survived == 0 {
+		return 0
+	}
+	return float64(detected) / float64(detected+survived) * 100
+}
+
+// FunctionScore is the mutation score of a single function
+type FunctionScore struct {
+	File      string
+	Func      string
+	Detected  int
+	Survivors []Mutant
+}
+
+// Score returns the percentage of viable mutants of the function detected
+func (f FunctionScore) Score() float64 {
+	return score(f.Detected, len(f.Survivors))
+}
+
+// Functions returns the scores of the functions with viable mutants, those
+// with the most survivors first
+func (r *Report) Functions() []FunctionScore {
+	var functions []FunctionScore
+	index := make(map[string]int)
+	for _, m := range r.Mutants {
+		if !m.Detected() && m.Status != StatusSurvived {
+			continue
+		}
+		key := m.File + "\x00" + m.Func
+		i, ok := index[key]
+		if !ok {
+			i = len(functions)
+			index[key] = i
+			functions = append(functions, FunctionScore{File: m.File, Func: m.Func})
+		}
+		if m.Detected() {
+			functions[i].Detected++
+		} else {
+			functions[i].Survivors = append(functions[i].Survivors, m)
+		}
+	}
+	sort.SliceStable(functions, func(i, j int) bool {
+		if len(functions[i].Survivors) != len(functions[j].Survivors) {
+			return len(functions[i].Survivors) > len(functions[j].Survivors)
+		}
+		if functions[i].File != functions[j].File {
+			return functions[i].File < functions[j].File
+		}
+		return functions[i].Func < functions[j].Func
+	})
+	return functions
+}
+
+// Write atomically writes the report as JSON
+func Write(name string, report *Report) error {
+	return fileutil.WriteJSON(name, report, "mutation testing report")
+}
+
+// Read reads a report written by Write
+func Read(name string) (*Report, error) {
+	var report Report
+	if err := fileutil.ReadJSON(name, &report, "mutation testing report"); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,123 @@
+package mutate
+
+import (
+	"reflect"
+	"testing"
+)
+
+const sampleSource = `package calc
+
+func Max(a, b int) int {
+	if a > b && !equal(a, b) {
+		return a
+	}
+	return b
+}
+
+func (c *Counter) Add(x int) error {
+	c.n += x
+	c.log()
+	f := func() bool { return c.n == 0 }
+	_ = f
+	return c.err
+}
+
+func Join(a, b string) string {
+	return a + "," + b
+}
+`
+
+func TestGenerate(t *testing.T) {
+	mutants, err := Generate("calc/calc.go", []byte(sampleSource))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	var got []string
+	for _, m := range mutants {
+		got = append(got, m.Func+": "+m.Operator+": "+m.Change())
+	}
+	want := []string{
+		"Max: conditional: a > b && !equal(a, b) → a > b || !equal(a, b)",
+		"Max: conditional: a > b → a <= b",
+		"Max: conditional: !equal(a, b) → equal(a, b)",
+		"Max: return: a → 0",
+		"Max: return: b → 0",
+		"(*Counter).Add: arithmetic: c.n += x → c.n -= x",
+		"(*Counter).Add: call: removed c.log()",
+		"(*Counter).Add: return: c.n == 0 → !(c.n == 0)",
+		"(*Counter).Add: conditional: c.n == 0 → c.n != 0",
+		"(*Counter).Add: return: c.err → nil",
+		"Join: return: a + \",\" + b → \"\"",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Generate =\n%q\nwant\n%q", got, want)
+	}
+
+	if m := mutants[1]; m.File != "calc/calc.go" || m.Line != 4 || m.Column != 7 {
+		t.Errorf("Unexpected position %s:%d:%d", m.File, m.Line, m.Column)
+	}
+}
+
+func TestApply(t *testing.T) {
+	src := []byte("package p\n\nfunc f(a, b int) bool { return a < b }\n")
+	mutants, err := Generate("p.go", src)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(mutants) != 2 {
+		t.Fatalf("Expected 2 mutants, got %d", len(mutants))
+	}
+	if got := string(mutants[0].Apply(src)); got != "package p\n\nfunc f(a, b int) bool { return !(a < b) }\n" {
+		t.Errorf("Unexpected return mutant: %q", got)
+	}
+	if got := string(mutants[1].Apply(src)); got != "package p\n\nfunc f(a, b int) bool { return a >= b }\n" {
+		t.Errorf("Unexpected conditional mutant: %q", got)
+	}
+}
+
+func TestGenerateSkipsGeneratedFiles(t *testing.T) {
+	src := "// Code generated by stringer. DO NOT EDIT.\n\npackage p\n\nfunc f(a int) bool { return a > 0 }\n"
+	mutants, err := Generate("p_string.go", []byte(src))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(mutants) != 0 {
+		t.Errorf("Expected no mutants in a generated file, got %d", len(mutants))
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	report := &Report{Mutants: []Mutant{
+		{File: "a.go", Func: "Max", Status: StatusKilled},
+		{File: "a.go", Func: "Max", Status: StatusTimeout},
+		{File: "a.go", Func: "Sum", Status: StatusKilled},
+		{File: "a.go", Func: "Sum", Status: StatusSurvived, Line: 3},
+		{File: "a.go", Func: "Sum", Status: StatusNotViable},
+		{File: "a.go", Func: "Broken", Status: StatusNotViable},
+		{File: "b.go", Func: "Untested", Status: StatusNotCovered},
+		{File: "b.go", Func: "Untested", Status: StatusNotCovered},
+	}}
+
+	detected, survived, notViable, notCovered := report.Counts()
+	if detected != 3 || survived != 1 || notViable != 2 || notCovered != 2 {
+		t.Errorf("Counts = %d, %d, %d, %d, want 3, 1, 2, 2", detected, survived, notViable, notCovered)
+	}
+	if report.Score() != 75 {
+		t.Errorf("Score = %v, want 75", report.Score())
+	}
+
+	functions := report.Functions()
+	if len(functions) != 2 {
+		t.Fatalf("Expected 2 functions with viable mutants, got %+v", functions)
+	}
+	if functions[0].Func != "Sum" || functions[0].Score() != 50 || len(functions[0].Survivors) != 1 || functions[0].Survivors[0].Line != 3 {
+		t.Errorf("Expected Sum first with one survivor, got %+v", functions[0])
+	}
+	if functions[1].Func != "Max" || functions[1].Score() != 100 {
+		t.Errorf("Expected Max fully detected, got %+v", functions[1])
+	}
+	if files := report.NotCovered(); len(files) != 1 || files[0] != (FileCount{File: "b.go", Mutants: 2}) {
+		t.Errorf("Expected b.go with 2 mutants not covered, got %+v", files)
+	}
+}
@@ -0,0 +1,280 @@
+package mutate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// maxSnippet limits the length of the expressions kept in the report
+const maxSnippet = 80
+
+// conditionals maps comparison and logical operators to their mutants:
+// negated comparisons and swapped logical operators
+var conditionals = map[token.Token]token.Token{
+	token.EQL:  token.NEQ,
+	token.NEQ:  token.EQL,
+	token.LSS:  token.GEQ,
+	token.LEQ:  token.GTR,
+	token.GTR:  token.LEQ,
+	token.GEQ:  token.LSS,
+	token.LAND: token.LOR,
+	token.LOR:  token.LAND,
+}
+
+// arithmetic maps arithmetic operators to their mutants
+var arithmetic = map[token.Token]token.Token{
+	token.ADD:        token.SUB,
+	token.SUB:        token.ADD,
+	token.MUL:        token.QUO,
+	token.QUO:        token.MUL,
+	token.REM:        token.MUL,
+	token.ADD_ASSIGN: token.SUB_ASSIGN,
+	token.SUB_ASSIGN: token.ADD_ASSIGN,
+	token.MUL_ASSIGN: token.QUO_ASSIGN,
+	token.QUO_ASSIGN: token.MUL_ASSIGN,
+	token.INC:        token.DEC,
+	token.DEC:        token.INC,
+}
+
+// numericTypes are the predeclared types whose zero value is 0
+var numericTypes = map[string]bool{
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"uintptr": true, "float32": true, "float64": true, "byte": true, "rune": true,
+}
+
+// Generate returns the mutants of the function bodies in a Go source file,
+// in source order. name is the file name recorded in the mutants. Mutations
+// are chosen from the syntax alone, so some of them do not compile, e.g.
+// subtracting strings; running them reports them as not viable. Generated
+// files and cgo files have no mutants.
+func Generate(name string, src []byte) ([]Mutant, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", name, err)
+	}
+	if ast.IsGenerated(file) {
+		return nil, nil
+	}
+	for _, imp := range file.Imports {
+		if imp.Path.Value == `"C"` {
+			return nil, nil
+		}
+	}
+
+	g := &generator{fset: fset, src: src, name: name}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		g.funcName = funcName(fn)
+		g.results = [][]ast.Expr{resultTypes(fn.Type)}
+		g.stack = nil
+		ast.Inspect(fn.Body, g.visit)
+	}
+	return g.mutants, nil
+}
+
+// generator collects the mutants of a file
+type generator struct {
+	fset    *token.FileSet
+	src     []byte
+	name    string
+	mutants []Mutant
+
+	funcName string
+	// results are the result types of the enclosing functions, innermost
+	// last, and stack the nodes being visited
+	results [][]ast.Expr
+	stack   []ast.Node
+}
+
+func (g *generator) visit(n ast.Node) bool {
+	if n == nil {
+		top := g.stack[len(g.stack)-1]
+		g.stack = g.stack[:len(g.stack)-1]
+		if _, ok := top.(*ast.FuncLit); ok {
+			g.results = g.results[:len(g.results)-1]
+		}
+		return false
+	}
+	g.stack = append(g.stack, n)
+
+	switch n := n.(type) {
+	case *ast.FuncLit:
+		g.results = append(g.results, resultTypes(n.Type))
+	case *ast.BinaryExpr:
+		if op, ok := conditionals[n.Op]; ok {
+			g.replaceOp(OperatorConditional, n, n.OpPos, n.Op, op)
+		} else if op, ok := arithmetic[n.Op]; ok && !isString(n.X) && !isString(n.Y) {
+			g.replaceOp(OperatorArithmetic, n, n.OpPos, n.Op, op)
+		}
+	case *ast.UnaryExpr:
+		if n.Op == token.NOT {
+			g.add(OperatorConditional, n, n.OpPos, n.X.Pos(), "")
+		}
+	case *ast.AssignStmt:
+		if op, ok := arithmetic[n.Tok]; ok {
+			g.replaceOp(OperatorArithmetic, n, n.TokPos, n.Tok, op)
+		}
+	case *ast.IncDecStmt:
+		g.replaceOp(OperatorArithmetic, n, n.TokPos, n.Tok, arithmetic[n.Tok])
+	case *ast.ExprStmt:
+		if call, ok := n.X.(*ast.CallExpr); ok {
+			g.add(OperatorCall, call, call.Pos(), call.End(), "")
+		}
+	case *ast.ReturnStmt:
+		g.returnValues(n)
+	}
+	return true
+}
+
+// replaceOp adds a mutant replacing the operator at pos in node
+func (g *generator) replaceOp(operator string, node ast.Node, pos token.Pos, from, to token.Token) {
+	g.add(operator, node, pos, pos+token.Pos(len(from.String())), to.String())
+}
+
+// returnValues adds a mutant for every returned value that can be replaced
+// with a different value of the result type
+func (g *generator) returnValues(ret *ast.ReturnStmt) {
+	types := g.results[len(g.results)-1]
+	if len(ret.Results) != len(types) {
+		// A bare return or the results of a multi-value call
+		return
+	}
+	for i, expr := range ret.Results {
+		if replacement, ok := g.replaceValue(types[i], expr); ok {
+			g.add(OperatorReturn, expr, expr.Pos(), expr.End(), replacement)
+		}
+	}
+}
+
+// replaceValue returns a value of type typ different from expr, judged from
+// the syntax alone
+func (g *generator) replaceValue(typ, expr ast.Expr) (string, bool) {
+	text := g.text(expr.Pos(), expr.End())
+	switch typ := typ.(type) {
+	case *ast.Ident:
+		switch {
+		case typ.Name == "bool":
+			switch text {
+			case "true":
+				return "false", true
+			case "false":
+				return "true", true
+			}
+			return "!(" + text + ")", true
+		case typ.Name == "error" || typ.Name == "any":
+			return "nil", text != "nil"
+		case typ.Name == "string":
+			if text == `""` {
+				return `"mutant"`, true
+			}
+			return `""`, true
+		case numericTypes[typ.Name]:
+			if text == "0" {
+				return "1", true
+			}
+			return "0", true
+		}
+	case *ast.StarExpr, *ast.MapType, *ast.ChanType, *ast.FuncType, *ast.InterfaceType:
+		return "nil", text != "nil"
+	case *ast.ArrayType:
+		// Only slices can be nil
+		return "nil", typ.Len == nil && text != "nil"
+	}
+	return "", false
+}
+
+// add records a mutant replacing the source between pos and end, which lies
+// within node, with replacement
+func (g *generator) add(operator string, node ast.Node, pos, end token.Pos, replacement string) {
+	position := g.fset.Position(pos)
+	offset, endOffset := position.Offset, g.fset.Position(end).Offset
+	nodeOffset, nodeEnd := g.fset.Position(node.Pos()).Offset, g.fset.Position(node.End()).Offset
+
+	original := string(g.src[nodeOffset:nodeEnd])
+	mutated := string(g.src[nodeOffset:offset]) + replacement + string(g.src[endOffset:nodeEnd])
+	g.mutants = append(g.mutants, Mutant{
+		File:        g.name,
+		Line:        position.Line,
+		Column:      position.Column,
+		Func:        g.funcName,
+		Operator:    operator,
+		Original:    snippet(original),
+		Mutated:     snippet(mutated),
+		Offset:      offset,
+		End:         endOffset,
+		Replacement: replacement,
+	})
+}
+
+// text returns the source between pos and end
+func (g *generator) text(pos, end token.Pos) string {
+	return string(g.src[g.fset.Position(pos).Offset:g.fset.Position(end).Offset])
+}
+
+// snippet collapses whitespace in source text and shortens it for display
+func snippet(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > maxSnippet {
+		return string(runes[:maxSnippet-1]) + "…"
+	}
+	return s
+}
+
+// isString reports whether expr is a string literal or a concatenation with
+// one, which makes + a concatenation too
+func isString(expr ast.Expr) bool {
+	switch expr := ast.Unparen(expr).(type) {
+	case *ast.BasicLit:
+		return expr.Kind == token.STRING
+	case *ast.BinaryExpr:
+		return expr.Op == token.ADD && (isString(expr.X) || isString(expr.Y))
+	}
+	return false
+}
+
+// resultTypes returns the type of every result of a function, one per
+// result even when several share a type
+func resultTypes(fn *ast.FuncType) []ast.Expr {
+	if fn.Results == nil {
+		return nil
+	}
+	var types []ast.Expr
+	for _, field := range fn.Results.List {
+		n := max(len(field.Names), 1)
+		for range n {
+			types = append(types, field.Type)
+		}
+	}
+	return types
+}
+
+// funcName returns the name of a function the way gocyclo prints it, e.g.
+// (*Parser).Parse for methods
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	return fmt.Sprintf("(%s).%s", recvString(fn.Recv.List[0].Type), fn.Name.Name)
+}
+
+func recvString(recv ast.Expr) string {
+	switch t := recv.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return "*" + recvString(t.X)
+	case *ast.IndexExpr:
+		return recvString(t.X)
+	case *ast.IndexListExpr:
+		return recvString(t.X)
+	}
+	return "?"
+}
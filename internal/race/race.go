@@ -0,0 +1,249 @@
+package race
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/fileutil"
+)
+
+// File is the name of the data race report in the metrics directory
+const File = "race.json"
+
+// Frame is a single function call in a stack
+type Frame struct {
+	Func string `json:"func"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// String returns the position of the frame as file:line
+func (f Frame) String() string {
+	return f.File + ":" + strconv.Itoa(f.Line)
+}
+
+// Access is one side of a data race
+type Access struct {
+	// Op is the kind of access, e.g. "write" or "atomic read"
+	Op string `json:"op"`
+	// Goroutine is the ID of the goroutine that made the access, 0 for the
+	// main goroutine
+	Goroutine int     `json:"goroutine"`
+	Stack     []Frame `json:"stack"`
+	// Created is the stack of the go statement that started the goroutine,
+	// empty for the main goroutine
+	Created []Frame `json:"created,omitempty"`
+}
+
+// Site returns the frame where the access happened
+func (a Access) Site() Frame {
+	if len(a.Stack) == 0 {
+		return Frame{}
+	}
+	return a.Stack[0]
+}
+
+// Race is a data race reported by the race detector. Reports with the same
+// signature are merged into one race.
+type Race struct {
+	Package string `json:"package,omitempty"`
+	// Tests are the tests during which the race was detected
+	Tests []string `json:"tests,omitempty"`
+	// Access is the access that detected the race and Previous the earlier
+	// conflicting access
+	Access   Access `json:"access"`
+	Previous Access `json:"previous"`
+	// Location describes the memory involved when the detector knows it,
+	// e.g. "global 'counter' of size 8"
+	Location string `json:"location,omitempty"`
+	// Count is how many times the race was reported
+	Count int `json:"count"`
+}
+
+// Signature identifies the race by the positions of both accesses. The same
+// race reached through different callers, or detected from either side,
+// has the same signature.
+func (r Race) Signature() string {
+	first := r.Access.Op + " " + r.Access.Site().Func + " " + r.Access.Site().String()
+	second := r.Previous.Op + " " + r.Previous.Site().Func + " " + r.Previous.Site().String()
+	if second < first {
+		first, second = second, first
+	}
+	return first + "\x00" + second
+}
+
+var (
+	accessLine    = regexp.MustCompile(`^(Previous )?((?i:atomic )?(?i:read|write)) at 0x[0-9a-f]+ by (?:goroutine (\d+)|main goroutine):$`)
+	createdLine   = regexp.MustCompile(`^Goroutine (\d+) \([^)]*\) created at:$`)
+	frameFileLine = regexp.MustCompile(`^\s+(.+):(\d+)(?: \+0x[0-9a-f]+)?$`)
+)
+
+// Parser collects data races from race detector output fed line by line
+type Parser struct {
+	races []Race
+	index map[string]int
+
+	current *Race
+	// created holds the creation stacks of the goroutines in the current
+	// report by goroutine ID
+	created map[int]*[]Frame
+	// stack is the stack being read and fn the function of its last frame
+	// until the position line follows
+	stack *[]Frame
+	fn    string
+}
+
+// NewParser returns an empty parser
+func NewParser() *Parser {
+	return &Parser{index: make(map[string]int)}
+}
+
+// Line feeds a line of output printed while the test of the package was
+// running. Either may be empty when unknown.
+func (p *Parser) Line(pkg, test, line string) {
+	line = strings.TrimRight(line, "\r\n")
+
+	if line == "WARNING: DATA RACE" {
+		p.finish()
+		p.current = &Race{Package: pkg, Count: 1}
+		if test != "" {
+			p.current.Tests = []string{test}
+		}
+		p.created = make(map[int]*[]Frame)
+		return
+	}
+	if p.current == nil {
+		return
+	}
+	if strings.HasPrefix(line, "==================") {
+		p.finish()
+		return
+	}
+
+	if m := accessLine.FindStringSubmatch(line); m != nil {
+		access := &p.current.Access
+		if m[1] != "" {
+			access = &p.current.Previous
+		}
+		access.Op = strings.ToLower(m[2])
+		access.Goroutine, _ = strconv.Atoi(m[3])
+		p.stack, p.fn = &access.Stack, ""
+		return
+	}
+	if m := createdLine.FindStringSubmatch(line); m != nil {
+		id, _ := strconv.Atoi(m[1])
+		p.stack, p.fn = new([]Frame), ""
+		p.created[id] = p.stack
+		return
+	}
+	if location, ok := strings.CutPrefix(line, "Location is "); ok {
+		p.current.Location = location
+		p.stack = nil
+		return
+	}
+	if p.stack == nil || strings.TrimSpace(line) == "" {
+		p.stack = nil
+		return
+	}
+
+	if p.fn != "" {
+		if m := frameFileLine.FindStringSubmatch(line); m != nil {
+			lineNo, _ := strconv.Atoi(m[2])
+			*p.stack = append(*p.stack, Frame{Func: p.fn, File: m[1], Line: lineNo})
+			p.fn = ""
+			return
+		}
+	}
+	p.fn = strings.TrimSuffix(strings.TrimSpace(line), "()")
+}
+
+// finish completes the current report and merges it into a race with the
+// same signature
+func (p *Parser) finish() {
+	race := p.current
+	p.current, p.stack, p.fn = nil, nil, ""
+	if race == nil || len(race.Access.Stack) == 0 {
+		return
+	}
+	for _, access := range []*Access{&race.Access, &race.Previous} {
+		if created, ok := p.created[access.Goroutine]; ok && access.Goroutine != 0 {
+			access.Created = *created
+		}
+	}
+
+	signature := race.Signature()
+	i, ok := p.index[signature]
+	if !ok {
+		p.index[signature] = len(p.races)
+		p.races = append(p.races, *race)
+		return
+	}
+	existing := &p.races[i]
+	existing.Count++
+	for _, test := range race.Tests {
+		if !contains(existing.Tests, test) {
+			existing.Tests = append(existing.Tests, test)
+		}
+	}
+}
+
+// Races returns the races found so far, in the order they were first
+// reported
+func (p *Parser) Races() []Race {
+	p.finish()
+	return p.races
+}
+
+// Parse parses race detector output, e.g. of go test -race without -json
+func Parse(r io.Reader) ([]Race, error) {
+	p := NewParser()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		p.Line("", "", scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p.Races(), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Report is the result of a run of the tests with the race detector
+type Report struct {
+	Races []Race `json:"races"`
+}
+
+// Reports returns the number of race reports before they were merged
+func (r *Report) Reports() int {
+	total := 0
+	for _, race := range r.Races {
+		total += race.Count
+	}
+	return total
+}
+
+// Write atomically writes the report as JSON
+func Write(name string, report *Report) error {
+	return fileutil.WriteJSON(name, report, "data race report")
+}
+
+// Read reads a report written by Write
+func Read(name string) (*Report, error) {
+	var report Report
+	if err := fileutil.ReadJSON(name, &report, "data race report"); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,167 @@
+package race
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleOutput = `=== RUN   TestInc
+==================
+WARNING: DATA RACE
+Read at 0x0000008314e8 by goroutine 8:
+  rc.Inc.func1()
+      /src/rc/rc.go:8 +0x30
+
+Previous write at 0x0000008314e8 by goroutine 7:
+  rc.Inc()
+      /src/rc/rc.go:11 +0xbd
+  rc.TestInc()
+      /src/rc/rc_test.go:6 +0x1c
+  testing.tRunner()
+      /usr/local/go/src/testing/testing.go:2193 +0x21c
+
+Goroutine 8 (running) created at:
+  rc.Inc()
+      /src/rc/rc.go:7 +0x99
+  rc.TestInc()
+      /src/rc/rc_test.go:6 +0x1c
+
+Goroutine 7 (running) created at:
+  testing.(*T).Run()
+      /usr/local/go/src/testing/testing.go:2258 +0xb12
+  main.main()
+      _testmain.go:48 +0x164
+==================
+==================
+WARNING: DATA RACE
+Read at 0x0000008314e8 by goroutine 9:
+  rc.Inc.func1()
+      /src/rc/rc.go:8 +0x30
+
+Previous write at 0x0000008314e8 by goroutine 7:
+  rc.Inc()
+      /src/rc/rc.go:11 +0xbd
+  rc.TestInc()
+      /src/rc/rc_test.go:7 +0x24
+
+Goroutine 9 (running) created at:
+  rc.Inc()
+      /src/rc/rc.go:7 +0x99
+==================
+==================
+WARNING: DATA RACE
+Write at 0x00c000012345 by main goroutine:
+  main.main()
+      /src/app/main.go:12 +0x55
+
+Previous read at 0x00c000012345 by goroutine 6:
+  main.main.func1()
+      /src/app/main.go:9 +0x3a
+
+Location is global 'state' of size 8 at 0x00c000012345 (app+0x1234)
+
+Goroutine 6 (finished) created at:
+  main.main()
+      /src/app/main.go:8 +0x44
+==================
+    testing.go:1865: race detected during execution of test
+--- FAIL: TestInc (0.00s)
+`
+
+func TestParse(t *testing.T) {
+	races, err := Parse(strings.NewReader(sampleOutput))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(races) != 2 {
+		t.Fatalf("Expected 2 races after merging, got %d: %+v", len(races), races)
+	}
+
+	// The second report reaches the same accesses through another caller
+	first := races[0]
+	if first.Count != 2 {
+		t.Errorf("Expected the first race to be reported twice, got %d", first.Count)
+	}
+	if first.Access.Op != "read" || first.Access.Goroutine != 8 {
+		t.Errorf("Unexpected access: %+v", first.Access)
+	}
+	if got := first.Access.Site(); got != (Frame{Func: "rc.Inc.func1", File: "/src/rc/rc.go", Line: 8}) {
+		t.Errorf("Unexpected access site: %+v", got)
+	}
+	if first.Previous.Op != "write" || len(first.Previous.Stack) != 3 {
+		t.Errorf("Unexpected previous access: %+v", first.Previous)
+	}
+	wantCreated := []Frame{
+		{Func: "rc.Inc", File: "/src/rc/rc.go", Line: 7},
+		{Func: "rc.TestInc", File: "/src/rc/rc_test.go", Line: 6},
+	}
+	if !reflect.DeepEqual(first.Access.Created, wantCreated) {
+		t.Errorf("Created = %+v, want %+v", first.Access.Created, wantCreated)
+	}
+	if got := first.Previous.Created[1]; got != (Frame{Func: "main.main", File: "_testmain.go", Line: 48}) {
+		t.Errorf("Unexpected creation frame: %+v", got)
+	}
+
+	second := races[1]
+	if second.Access.Op != "write" || second.Access.Goroutine != 0 || second.Access.Created != nil {
+		t.Errorf("Expected a write by the main goroutine, got %+v", second.Access)
+	}
+	if second.Location != "global 'state' of size 8 at 0x00c000012345 (app+0x1234)" {
+		t.Errorf("Unexpected location: %q", second.Location)
+	}
+	if len(second.Previous.Created) != 1 {
+		t.Errorf("Expected the creation site of goroutine 6, got %+v", second.Previous.Created)
+	}
+}
+
+func TestParserMergesTests(t *testing.T) {
+	// access and previous are the function and position of the top frame
+	report := func(p *Parser, test string, access, previous [2]string) {
+		for _, line := range []string{
+			"WARNING: DATA RACE",
+			"Write at 0x01 by goroutine 2:",
+			"  " + access[0] + "()",
+			"      " + access[1] + " +0x1",
+			"",
+			"Previous write at 0x01 by goroutine 3:",
+			"  " + previous[0] + "()",
+			"      " + previous[1] + " +0x1",
+			"==================",
+		} {
+			p.Line("ex/a", test, line)
+		}
+	}
+
+	f := [2]string{"a.F", "/src/a.go:1"}
+	g := [2]string{"a.G", "/src/a.go:2"}
+	h := [2]string{"a.H", "/src/a.go:3"}
+
+	p := NewParser()
+	report(p, "TestOne", f, g)
+	// The same race detected from the other side
+	report(p, "TestTwo", g, f)
+	report(p, "TestOne", f, h)
+
+	races := p.Races()
+	if len(races) != 2 {
+		t.Fatalf("Expected 2 races, got %d", len(races))
+	}
+	if races[0].Count != 2 || !reflect.DeepEqual(races[0].Tests, []string{"TestOne", "TestTwo"}) {
+		t.Errorf("Unexpected merged race: %+v", races[0])
+	}
+	if races[0].Package != "ex/a" {
+		t.Errorf("Expected package ex/a, got %q", races[0].Package)
+	}
+}
+
+func TestReports(t *testing.T) {
+	races, err := Parse(strings.NewReader(sampleOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := &Report{Races: races}
+	if report.Reports() != 3 {
+		t.Errorf("Expected 3 reports, got %d", report.Reports())
+	}
+}
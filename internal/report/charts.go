@@ -0,0 +1,125 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/history"
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// Trend is a chart of one key number across analysis runs
+type Trend struct {
+	Title string
+	Last  string
+	Chart template.HTML
+}
+
+// Chart dimensions in SVG user units
+const (
+	chartWidth   = 560
+	chartHeight  = 140
+	chartPadding = 30
+)
+
+// buildTrends renders a chart per key number. At least two runs are needed
+// to show a trend.
+func buildTrends(entries []history.Entry) []Trend {
+	if len(entries) < 2 {
+		return nil
+	}
+
+	labels := make([]string, len(entries))
+	for i, entry := range entries {
+		labels[i] = i18n.Current().ShortDateTime(entry.Timestamp)
+		if entry.Commit != "" {
+			labels[i] += " " + entry.Commit
+		}
+	}
+
+	series := []struct {
+		// key is the message of the title
+		key   string
+		value func(history.Entry) float64
+	}{
+		{"trend.coverage", func(e history.Entry) float64 { return e.Coverage }},
+		{"trend.vet", func(e history.Entry) float64 { return float64(e.Issues["vet"]) }},
+		{"trend.lint", func(e history.Entry) float64 { return float64(e.Issues["golangci-lint"]) }},
+		{"trend.avgComplexity", func(e history.Entry) float64 { return e.AvgComplexity }},
+		{"trend.maxComplexity", func(e history.Entry) float64 { return float64(e.MaxComplexity) }},
+		{"trend.loc", func(e history.Entry) float64 { return float64(e.LOC) }},
+		{"trend.tests", func(e history.Entry) float64 { return float64(e.Tests) }},
+		{"trend.duration", func(e history.Entry) float64 { return e.Duration }},
+	}
+
+	trends := make([]Trend, 0, len(series))
+	for _, s := range series {
+		values := make([]float64, len(entries))
+		for i, entry := range entries {
+			values[i] = s.value(entry)
+		}
+		trends = append(trends, Trend{
+			Title: i18n.T(s.key),
+			Last:  formatValue(values[len(values)-1]),
+			Chart: lineChart(values, labels),
+		})
+	}
+	return trends
+}
+
+// lineChart renders values as an inline SVG line chart. Each point carries a
+// tooltip with its label and value.
+func lineChart(values []float64, labels []string) template.HTML {
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+
+	plotWidth := float64(chartWidth - 2*chartPadding)
+	plotHeight := float64(chartHeight - 2*chartPadding)
+	x := func(i int) float64 {
+		if len(values) == 1 {
+			return chartPadding
+		}
+		return chartPadding + plotWidth*float64(i)/float64(len(values)-1)
+	}
+	y := func(v float64) float64 {
+		return chartPadding + plotHeight*(hi-v)/(hi-lo)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="trend" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg" role="img">`, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#dee2e6"/>`, chartPadding, chartHeight-chartPadding, chartWidth-chartPadding, chartHeight-chartPadding)
+	fmt.Fprintf(&b, `<text x="2" y="%.1f" font-size="10" fill="#6c757d">%s</text>`, y(hi)+4, formatValue(hi))
+	fmt.Fprintf(&b, `<text x="2" y="%.1f" font-size="10" fill="#6c757d">%s</text>`, y(lo)+4, formatValue(lo))
+
+	points := make([]string, len(values))
+	for i, v := range values {
+		points[i] = fmt.Sprintf("%.1f,%.1f", x(i), y(v))
+	}
+	fmt.Fprintf(&b, `<polyline fill="none" stroke="#667eea" stroke-width="2" points="%s"/>`, strings.Join(points, " "))
+
+	for i, v := range values {
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="#764ba2"><title>%s: %s</title></circle>`,
+			x(i), y(v), html.EscapeString(labels[i]), formatValue(v))
+	}
+	b.WriteString(`</svg>`)
+
+	// Values are numbers and labels are escaped above
+	return template.HTML(b.String()) // #nosec G203
+}
+
+// formatValue formats a chart value with at most one decimal place
+func formatValue(v float64) string {
+	if v == math.Trunc(v) {
+		return i18n.Current().Number(v, 0)
+	}
+	return i18n.Current().Number(v, 1)
+}
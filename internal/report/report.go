@@ -4,16 +4,36 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/andro-kes/gokode/internal/bench"
+	"github.com/andro-kes/gokode/internal/complexity"
+	"github.com/andro-kes/gokode/internal/coverage"
+	"github.com/andro-kes/gokode/internal/depgraph"
+	"github.com/andro-kes/gokode/internal/fileutil"
+	"github.com/andro-kes/gokode/internal/flaky"
+	"github.com/andro-kes/gokode/internal/fuzz"
+	"github.com/andro-kes/gokode/internal/gotest"
+	"github.com/andro-kes/gokode/internal/history"
+	"github.com/andro-kes/gokode/internal/i18n"
+	"github.com/andro-kes/gokode/internal/mutate"
+	"github.com/andro-kes/gokode/internal/race"
+	"github.com/andro-kes/gokode/internal/snapshot"
+	"github.com/andro-kes/gokode/internal/source"
+	"github.com/andro-kes/gokode/internal/testquality"
 )
 
 // MetricsSummary contains aggregated metrics data
 type MetricsSummary struct {
 	Timestamp      string
 	VetOutput      string
+	VetIssues      []VetIssue
 	VetIssueCount  int
 	LintIssues     []LintIssue
 	LintIssueCount int
@@ -22,8 +42,55 @@ type MetricsSummary struct {
 	GocycloOutput  string
 	GocycloLines   []string
 	MetricsDir     string
+	Run            *RunInfo
+	// Gates are the gates of the run followed by the benchmark regression
+	// gate
+	Gates           []GateResult
+	Coverage        *coverage.Stats
+	CoverageProfile *coverage.Profile
+	PatchCoverage   *coverage.Patch
+	Exclusions      *coverage.Exclusions
+	Integration     *coverage.Integration
+	Complexity      []FunctionComplexity
+	AvgComplexity   float64
+	MaxComplexity   int
+	Risks           *complexity.Report
+	Tests           *gotest.Summary
+	TestSelection   *depgraph.Selection
+	Flaky           *flaky.Report
+	OrderDeps       []flaky.OrderDependency
+	Races           *race.Report
+	Bench           *bench.Report
+	Fuzz            *fuzz.Report
+	Mutation        *mutate.Report
+	TestQuality     *testquality.Report
+	TreeChanges     *snapshot.Report
+	SourcePages     []*SourcePage
+	SourceTree      []TreeEntry
+	// SelfContained is set when the report embeds the source pages and the
+	// artifacts it links to
+	SelfContained bool
+	TestsPassed   int
+	TestsFailed   int
+	TestsSkipped  int
+	History       []history.Entry
+	Trends        []Trend
+}
+
+// FunctionComplexity is a single line of gocyclo output
+type FunctionComplexity = complexity.Function
+
+// VetIssue is a single diagnostic from go vet output
+type VetIssue struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
 }
 
+// vetLine matches diagnostics of the form file.go:10:2: message
+var vetLine = regexp.MustCompile(`^(.+?\.go):(\d+)(?::(\d+))?: (.+)$`)
+
 // LintIssue represents a single linting issue
 type LintIssue struct {
 	FromLinter  string   `json:"fromLinter"`
@@ -41,35 +108,67 @@ type LintReport struct {
 	Issues []LintIssue `json:"Issues"`
 }
 
-// GenerateHTML generates an HTML report from metrics files
+// GenerateHTML generates an HTML report from metrics files, with the
+// source pages next to it
 func GenerateHTML(metricsDir string) error {
-	fmt.Println("Generating HTML report...")
+	return generateHTML(metricsDir, false)
+}
+
+// GenerateSelfContainedHTML generates the HTML report as a single file that
+// embeds the source pages and the artifacts it links to, so it keeps working
+// when moved, uploaded or sent on its own
+func GenerateSelfContainedHTML(metricsDir string) error {
+	return generateHTML(metricsDir, true)
+}
+
+func generateHTML(metricsDir string, selfContained bool) error {
+	i18n.Println("report.generating")
 
 	summary, err := collectMetrics(metricsDir)
 	if err != nil {
 		return fmt.Errorf("error collecting metrics: %w", err)
 	}
+	summary.SelfContained = selfContained
 
 	htmlContent, err := renderHTML(summary)
 	if err != nil {
 		return fmt.Errorf("error rendering HTML: %w", err)
 	}
 
+	if selfContained {
+		// Pages of an earlier report would be out of date
+		if err := os.RemoveAll(filepath.Join(metricsDir, SourcesDir)); err != nil {
+			return fmt.Errorf("error removing old source pages: %w", err)
+		}
+	} else if err := writeSourcePages(metricsDir, summary); err != nil {
+		return err
+	}
+
 	reportPath := filepath.Join(metricsDir, "report.html")
-	if err := os.WriteFile(reportPath, []byte(htmlContent), 0644); err != nil {
+	if err := fileutil.WriteFile(reportPath, []byte(htmlContent), 0644); err != nil {
 		return fmt.Errorf("error writing HTML report: %w", err)
 	}
 
-	fmt.Printf("✓ HTML report generated: %s\n", reportPath)
+	i18n.Println("report.generatedFile", reportPath)
 	return nil
 }
 
+// Load parses the metrics files in metricsDir into a summary
+func Load(metricsDir string) (*MetricsSummary, error) {
+	return collectMetrics(metricsDir)
+}
+
 func collectMetrics(metricsDir string) (*MetricsSummary, error) {
 	summary := &MetricsSummary{
-		Timestamp:  time.Now().Format("2006-01-02 15:04:05"),
+		Timestamp:  i18n.Current().DateTime(time.Now()),
 		MetricsDir: metricsDir,
 	}
 
+	// Read run status; it is absent when single commands were run
+	if info, err := ReadRunInfo(metricsDir); err == nil {
+		summary.Run = info
+	}
+
 	// Read vet output
 	vetFile := filepath.Join(metricsDir, "vet.txt")
 	if data, err := os.ReadFile(vetFile); err == nil {
@@ -82,6 +181,12 @@ func collectMetrics(metricsDir string) (*MetricsSummary, error) {
 				if strings.TrimSpace(line) != "" {
 					summary.VetIssueCount++
 				}
+				if m := vetLine.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+					issue := VetIssue{File: m[1], Message: m[4]}
+					issue.Line, _ = strconv.Atoi(m[2])
+					issue.Column, _ = strconv.Atoi(m[3])
+					summary.VetIssues = append(summary.VetIssues, issue)
+				}
 			}
 		}
 	}
@@ -100,6 +205,70 @@ func collectMetrics(metricsDir string) (*MetricsSummary, error) {
 	coverageFile := filepath.Join(metricsDir, "coverage.out")
 	if data, err := os.ReadFile(coverageFile); err == nil {
 		summary.CoverageData = string(data)
+		if profile, err := coverage.Parse(strings.NewReader(summary.CoverageData)); err == nil {
+			total := profile.Total()
+			summary.Coverage = &total
+			summary.CoverageProfile = profile
+		}
+	}
+
+	if patch, err := coverage.ReadPatch(filepath.Join(metricsDir, coverage.PatchFileName)); err == nil {
+		summary.PatchCoverage = patch
+	}
+	if exclusions, err := coverage.ReadExclusions(filepath.Join(metricsDir, coverage.ExclusionsFileName)); err == nil {
+		summary.Exclusions = exclusions
+	}
+	if integration, err := coverage.ReadIntegration(filepath.Join(metricsDir, coverage.IntegrationFileName)); err == nil {
+		summary.Integration = integration
+	}
+
+	// Read test results, preferring the JSON events over the verbose output
+	// written by older versions
+	tests, err := gotest.ParseJSONFile(filepath.Join(metricsDir, "test.json"))
+	if err != nil {
+		tests, err = gotest.ParseVerboseFile(filepath.Join(metricsDir, "test.txt"))
+	}
+	if err == nil {
+		summary.Tests = tests
+		summary.TestsPassed = tests.Count(gotest.StatusPass)
+		summary.TestsFailed = tests.Count(gotest.StatusFail)
+		summary.TestsSkipped = tests.Count(gotest.StatusSkip)
+	}
+	if report, err := flaky.Read(filepath.Join(metricsDir, flaky.File)); err == nil {
+		summary.Flaky = report
+	}
+	if deps, err := flaky.ReadOrder(filepath.Join(metricsDir, flaky.OrderFile)); err == nil {
+		summary.OrderDeps = deps
+	}
+	if report, err := race.Read(filepath.Join(metricsDir, race.File)); err == nil {
+		summary.Races = report
+	}
+	if results, err := bench.Read(filepath.Join(metricsDir, bench.File)); err == nil {
+		summary.Bench = results
+	}
+	if summary.Run != nil {
+		summary.Gates = append(summary.Gates, summary.Run.Gates...)
+	}
+	if summary.Bench != nil && summary.Bench.Gate != nil {
+		summary.Gates = append(summary.Gates, GateResult(*summary.Bench.Gate))
+	}
+	if report, err := fuzz.Read(filepath.Join(metricsDir, fuzz.File)); err == nil {
+		summary.Fuzz = report
+	}
+	if report, err := testquality.Read(filepath.Join(metricsDir, testquality.File)); err == nil {
+		summary.TestQuality = report
+	}
+	if report, err := snapshot.Read(filepath.Join(metricsDir, snapshot.File)); err == nil {
+		summary.TreeChanges = report
+	}
+	if report, err := mutate.Read(filepath.Join(metricsDir, mutate.File)); err == nil {
+		summary.Mutation = report
+	}
+	if report, err := complexity.Read(filepath.Join(metricsDir, complexity.File)); err == nil {
+		summary.Risks = report
+	}
+	if selection, err := depgraph.ReadSelection(filepath.Join(metricsDir, depgraph.SelectionFile)); err == nil {
+		summary.TestSelection = selection
 	}
 
 	// Check if coverage HTML exists
@@ -116,13 +285,71 @@ func collectMetrics(metricsDir string) (*MetricsSummary, error) {
 		if trimmed != "" {
 			summary.GocycloLines = strings.Split(trimmed, "\n")
 		}
+		summary.Complexity = complexity.Parse(summary.GocycloLines)
+		total := 0
+		for _, fn := range summary.Complexity {
+			total += fn.Complexity
+			summary.MaxComplexity = max(summary.MaxComplexity, fn.Complexity)
+		}
+		if len(summary.Complexity) > 0 {
+			summary.AvgComplexity = float64(total) / float64(len(summary.Complexity))
+		}
+	}
+
+	// Annotate the sources once all diagnostics are read
+	if snapshot, err := source.Read(filepath.Join(metricsDir, source.File)); err == nil {
+		summary.SourcePages = sourcePages(summary, snapshot)
+		summary.SourceTree = sourceTree(summary.SourcePages)
+	}
+
+	// Read run history for trend charts
+	if summary.Run != nil && summary.Run.HistoryFile != "" {
+		if entries, err := history.Load(filepath.Join(metricsDir, summary.Run.HistoryFile)); err == nil {
+			summary.History = entries
+			summary.Trends = buildTrends(entries)
+		}
 	}
 
 	return summary, nil
 }
 
+// HistoryEntry returns the key numbers of the summary for the run history.
+// Fields describing the run itself, such as the commit, LOC and duration,
+// are left for the caller to fill in.
+func (s *MetricsSummary) HistoryEntry() history.Entry {
+	entry := history.Entry{
+		Issues: map[string]int{
+			"vet":           s.VetIssueCount,
+			"golangci-lint": s.LintIssueCount,
+		},
+		AvgComplexity: s.AvgComplexity,
+		MaxComplexity: s.MaxComplexity,
+	}
+	if s.Coverage != nil {
+		entry.Coverage = s.Coverage.Percent()
+	}
+	if s.Tests != nil {
+		entry.Tests = len(s.Tests.Tests)
+	}
+	return entry
+}
+
 func renderHTML(summary *MetricsSummary) (string, error) {
-	tmpl := template.Must(template.New("report").Parse(htmlTemplate))
+	embed := &embedder{metricsDir: summary.MetricsDir, selfContained: summary.SelfContained, pages: summary.SourcePages}
+	locale := i18n.Current()
+	tmpl := template.Must(template.New("report").Funcs(locale.Funcs()).Funcs(template.FuncMap{
+		"lineRanges": coverage.LineRanges,
+		"join":       strings.Join,
+		"sub":        func(a, b int) int { return a - b },
+		"sourceURL":  sourceURL,
+		"sourceLink": sourceLinker(summary.SourcePages, summary.SelfContained),
+		"artifact":   embed.link,
+		"embedded":   embed.render,
+		"codeCSS":    codeCSS,
+		"base":       filepath.Base,
+		"benchValue": func(v float64) string { return locale.Localize(bench.FormatValue(v)) },
+		"summarize":  func(values []float64) bench.Sample { return bench.Summarize(values, bench.Confidence) },
+	}).Parse(htmlTemplate))
 
 	var buf strings.Builder
 	if err := tmpl.Execute(&buf, summary); err != nil {
@@ -132,12 +359,22 @@ func renderHTML(summary *MetricsSummary) (string, error) {
 	return buf.String(), nil
 }
 
+// sourceURL returns a link to a source file on the local machine, empty for
+// files without an absolute path such as the generated _testmain.go
+func sourceURL(file string) template.URL {
+	if !filepath.IsAbs(file) {
+		return ""
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(file)}
+	return template.URL(u.String())
+}
+
 const htmlTemplate = `<!DOCTYPE html>
-<html lang="ru">
+<html lang="{{lang}}">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Отчет анализа кода gokode</title>
+    <title>{{t "report.title"}}</title>
     <style>
         * {
             margin: 0;
@@ -173,6 +410,9 @@ const htmlTemplate = `<!DOCTYPE html>
             opacity: 0.9;
             font-size: 0.95em;
         }
+        .previous-run {
+            color: white;
+        }
         .content {
             padding: 30px;
         }
@@ -210,6 +450,21 @@ const htmlTemplate = `<!DOCTYPE html>
             color: #dc3545;
             font-weight: bold;
         }
+        .race-sides {
+            display: grid;
+            grid-template-columns: 1fr 1fr;
+            gap: 20px;
+        }
+
+        .race-stack {
+            margin-left: 20px;
+            font-size: 0.9em;
+        }
+
+        .race-stack li {
+            margin-bottom: 4px;
+        }
+
         .issue-count {
             display: inline-block;
             background: #dc3545;
@@ -267,6 +522,46 @@ const htmlTemplate = `<!DOCTYPE html>
             font-size: 0.85em;
             overflow-x: auto;
         }
+        .banner {
+            padding: 15px 30px;
+            font-weight: bold;
+        }
+        .banner.interrupted {
+            background: #fff3cd;
+            color: #856404;
+        }
+        .banner.failed {
+            background: #f8d7da;
+            color: #721c24;
+        }
+        .banner.partial {
+            background: #d1ecf1;
+            color: #0c5460;
+        }
+        .banner.partial details {
+            font-weight: normal;
+        }
+        table {
+            width: 100%;
+            border-collapse: collapse;
+        }
+        th, td {
+            text-align: left;
+            padding: 8px 12px;
+            border-bottom: 1px solid #e9ecef;
+        }
+        th {
+            color: #495057;
+        }
+        .trends {
+            display: grid;
+            grid-template-columns: repeat(auto-fit, minmax(320px, 1fr));
+            gap: 20px;
+        }
+        .trend {
+            width: 100%;
+            height: auto;
+        }
         .links {
             margin-top: 20px;
         }
@@ -284,6 +579,37 @@ const htmlTemplate = `<!DOCTYPE html>
         .links a:hover {
             background: #764ba2;
         }
+        .source-tree {
+            font-family: 'Courier New', monospace;
+            font-size: 0.9em;
+        }
+        .source-tree .dir {
+            color: #6c757d;
+            font-weight: bold;
+        }
+        .tree-stats {
+            color: #6c757d;
+            font-size: 0.9em;
+        }
+        .source-browser {
+            display: grid;
+            grid-template-columns: 280px 1fr;
+            gap: 20px;
+            align-items: start;
+        }
+        .source-browser .source-tree {
+            max-height: 80vh;
+            overflow: auto;
+        }
+        #source-viewer {
+            background: white;
+            max-height: 80vh;
+            overflow: auto;
+        }
+        #source-viewer h3, #source-viewer p {
+            padding: 0 10px;
+        }
+        {{if .SelfContained}}{{codeCSS}}{{end}}
         footer {
             background: #f8f9fa;
             padding: 20px;
@@ -296,20 +622,91 @@ const htmlTemplate = `<!DOCTYPE html>
 <body>
     <div class="container">
         <header>
-            <h1>📊 Отчет анализа кода gokode</h1>
-            <div class="timestamp">Сгенерирован: {{.Timestamp}}</div>
+            <h1>📊 {{t "report.title"}}</h1>
+            <div class="timestamp">{{t "report.generated" .Timestamp}}{{if .Run}}{{if .Run.Commit}} | {{t "report.commit" .Run.Commit}}{{end}}{{end}}</div>
+            {{if and .Run (not .SelfContained)}}{{if .Run.PreviousRun}}
+            <div class="timestamp"><a class="previous-run" href="../{{.Run.PreviousRun}}/report.html">← {{t "report.previousRun" .Run.PreviousRun}}</a></div>
+            {{end}}{{end}}
         </header>
 
+        {{if .Run}}{{if .Run.Interrupted}}
+        <div class="banner interrupted">⚠ {{t "report.banner.interrupted"}}</div>
+        {{else if .Run.Failed}}
+        <div class="banner failed">✗ {{t "report.banner.failed"}}</div>
+        {{end}}{{end}}
+        {{if .Run}}{{with .Run.Partial}}
+        <div class="banner partial">
+            ◐ {{t "report.banner.partial" .Since (len .ChangedFiles) (len .Packages)}}
+            {{if or .ChangedFiles .Packages}}
+            <details>
+                <summary>{{t "report.filesAndPackages"}}</summary>
+                {{if .ChangedFiles}}<p>{{t "report.label.files"}}: {{range $i, $f := .ChangedFiles}}{{if $i}}, {{end}}<code>{{$f}}</code>{{end}}</p>{{end}}
+                {{if .Packages}}<p>{{t "report.label.packages"}}: {{range $i, $p := .Packages}}{{if $i}}, {{end}}<code>{{$p}}</code>{{end}}</p>{{end}}
+            </details>
+            {{end}}
+        </div>
+        {{end}}{{end}}
+
         <div class="content">
+            {{if .Run}}
+            <!-- Steps Section -->
+            <div class="section">
+                <h2>🧭 {{t "report.steps"}}</h2>
+                <div class="metric-card">
+                    <table>
+                        <tr><th>{{t "report.col.step"}}</th><th>{{t "report.col.status"}}</th><th>{{t "report.col.duration"}}</th></tr>
+                        {{range .Run.Steps}}
+                        <tr>
+                            <td>{{name "step" .Name}}</td>
+                            <td>{{if eq .Status "ok"}}<span class="status-ok">✓ {{name "status" .Status}}</span>{{else if eq .Status "cached"}}<span class="status-ok">♻ {{name "status" .Status}}</span>{{else if eq .Status "resumed"}}<span class="status-ok">⏭ {{name "status" .Status}}</span>{{else if eq .Status "skipped"}}<span class="status-ok">– {{name "status" .Status}}</span>{{else if eq .Status "interrupted"}}<span class="status-warning">⚠ {{name "status" .Status}}</span>{{else}}<span class="status-error">✗ {{name "status" .Status}}</span>{{end}}</td>
+                            <td>{{duration .Duration}}</td>
+                        </tr>
+                        {{end}}
+                    </table>
+                </div>
+            </div>
+            {{end}}
+
+            {{if .Gates}}
+            <!-- Gates Section -->
+            <div class="section">
+                <h2>🚦 {{t "report.gates"}}</h2>
+                <div class="metric-card">
+                    <table>
+                        <tr><th>{{t "report.col.gate"}}</th><th>{{t "report.col.value"}}</th><th>{{t "report.col.threshold"}}</th><th>{{t "report.col.status"}}</th></tr>
+                        {{range .Gates}}
+                        <tr>
+                            <td>{{.Name}}</td>
+                            <td>{{.Actual}}</td>
+                            <td>{{.Threshold}}</td>
+                            <td>{{if .Passed}}<span class="status-ok">✓ {{t "report.gate.passed"}}</span>{{else}}<span class="status-error">✗ {{t "report.gate.failed"}}</span>{{end}}</td>
+                        </tr>
+                        {{end}}
+                    </table>
+                </div>
+            </div>
+            {{end}}
+
             <!-- Vet Section -->
             <div class="section">
                 <h2>🔍 Go Vet</h2>
                 <div class="metric-card">
-                    <h3>Статус: {{if eq .VetIssueCount 0}}<span class="status-ok">✓ Проблем не обнаружено</span>{{else}}<span class="status-error">✗ Обнаружено проблем</span><span class="issue-count">{{.VetIssueCount}}</span>{{end}}</h3>
-                    {{if .VetOutput}}
+                    <h3>{{t "report.label.status"}}: {{if eq .VetIssueCount 0}}<span class="status-ok">✓ {{t "report.noIssues"}}</span>{{else}}<span class="status-error">✗ {{t "report.issuesFound"}}</span><span class="issue-count">{{.VetIssueCount}}</span>{{end}}</h3>
+                    {{if .VetIssues}}
+                    {{range .VetIssues}}
+                    <div class="issue-item">
+                        <div class="issue-header">
+                            <span class="issue-linter">go vet</span>
+                            <span class="issue-location">{{sourceLink .File .Line (printf "%s:%d" .File .Line)}}</span>
+                        </div>
+                        <div class="issue-text">{{.Message}}</div>
+                    </div>
+                    {{end}}
+                    <details><summary>{{t "report.fullOutput"}}</summary><pre>{{.VetOutput}}</pre></details>
+                    {{else if .VetOutput}}
                     <pre>{{.VetOutput}}</pre>
                     {{else}}
-                    <p class="status-ok">Анализ go vet завершился успешно, проблем не найдено.</p>
+                    <p class="status-ok">{{t "report.vet.clean"}}</p>
                     {{end}}
                 </div>
             </div>
@@ -318,13 +715,13 @@ const htmlTemplate = `<!DOCTYPE html>
             <div class="section">
                 <h2>🔎 Golangci-lint</h2>
                 <div class="metric-card">
-                    <h3>Статус: {{if eq .LintIssueCount 0}}<span class="status-ok">✓ Проблем не обнаружено</span>{{else}}<span class="status-error">✗ Обнаружено проблем</span><span class="issue-count">{{.LintIssueCount}}</span>{{end}}</h3>
+                    <h3>{{t "report.label.status"}}: {{if eq .LintIssueCount 0}}<span class="status-ok">✓ {{t "report.noIssues"}}</span>{{else}}<span class="status-error">✗ {{t "report.issuesFound"}}</span><span class="issue-count">{{.LintIssueCount}}</span>{{end}}</h3>
                     {{if .LintIssues}}
                     {{range .LintIssues}}
                     <div class="issue-item">
                         <div class="issue-header">
                             <span class="issue-linter">{{.FromLinter}}</span>
-                            <span class="issue-location">{{.Pos.Filename}}:{{.Pos.Line}}:{{.Pos.Column}}</span>
+                            <span class="issue-location">{{sourceLink .Pos.Filename .Pos.Line (printf "%s:%d:%d" .Pos.Filename .Pos.Line .Pos.Column)}}</span>
                         </div>
                         <div class="issue-text">{{.Text}}</div>
                         {{if .SourceLines}}
@@ -333,50 +730,658 @@ const htmlTemplate = `<!DOCTYPE html>
                     </div>
                     {{end}}
                     {{else}}
-                    <p class="status-ok">Анализ golangci-lint завершился успешно, проблем не найдено.</p>
+                    <p class="status-ok">{{t "report.lint.clean"}}</p>
                     {{end}}
                 </div>
                 <div class="links">
-                    <a href="report.json" target="_blank">📄 Смотреть JSON отчет</a>
+                    <a {{artifact "report.json"}}>📄 {{t "report.lint.json"}}</a>
                 </div>
             </div>
 
+            <!-- Tests Section -->
+            {{if .Tests}}
+            <div class="section">
+                <h2>🧪 {{t "report.tests"}}</h2>
+                <div class="metric-card">
+                    <h3>{{t "report.label.status"}}: {{if eq .TestsFailed 0}}<span class="status-ok">✓ {{t "report.tests.passed"}}</span>{{else}}<span class="status-error">✗ {{t "report.tests.failed"}}</span><span class="issue-count">{{.TestsFailed}}</span>{{end}}</h3>
+                    <p>{{t "report.tests.counts" (len .Tests.Tests) .TestsPassed .TestsFailed .TestsSkipped}}</p>
+                    {{with .TestSelection}}
+                    <h3>🎯 {{t "report.tests.selection" .Since (len .Packages)}}</h3>
+                    {{if .Packages}}
+                    <table>
+                        <tr><th>{{t "report.col.package"}}</th><th>{{t "report.col.reason"}}</th></tr>
+                        {{range .Packages}}
+                        <tr><td><code>{{.Package}}</code></td><td>{{.Reason}}</td></tr>
+                        {{end}}
+                    </table>
+                    {{end}}
+                    {{end}}
+                </div>
+            </div>
+            {{end}}
+
+            {{with .TreeChanges}}
+            <!-- Tree Changes Section -->
+            <div class="section">
+                <h2>🧹 {{t "report.treeChanges"}}</h2>
+                <div class="metric-card">
+                    <h3>{{t "report.label.status"}}: {{if .Changes}}<span class="status-error">✗ {{t "report.treeChanges.changed"}}</span><span class="issue-count">{{len .Changes}}</span>{{else}}<span class="status-ok">✓ {{t "report.treeChanges.clean"}}</span>{{end}}</h3>
+                    {{if .Changes}}
+                    <p>{{t "report.treeChanges.counts" (.Count "created") (.Count "modified") (.Count "deleted")}}</p>
+                    <table>
+                        <tr><th>{{t "report.col.file"}}</th><th>{{t "report.col.change"}}</th><th>{{t "report.col.size"}}</th><th>{{t "report.col.package"}}</th></tr>
+                        {{range .Changes}}
+                        <tr><td><code>{{.Path}}</code></td><td>{{name "report.change" .Kind}}</td><td>{{t "size.B" .Size}}</td><td>{{if .Package}}<code>{{.Package}}</code>{{else}}{{t "report.treeChanges.noPackage"}}{{end}}</td></tr>
+                        {{end}}
+                    </table>
+                    {{end}}
+                </div>
+            </div>
+            {{end}}
+
+            {{with .TestQuality}}
+            <!-- Test Quality Section -->
+            <div class="section">
+                <h2>🩺 {{t "report.testQuality"}}</h2>
+                <div class="metric-card">
+                    <h3>{{t "report.label.status"}}: {{if .Issues}}<span class="status-error">✗ {{t "report.issuesFound"}}</span><span class="issue-count">{{len .Issues}}</span>{{else}}<span class="status-ok">✓ {{t "report.noIssues"}}</span>{{end}}</h3>
+                    <p>{{t "report.testQuality.counts" .Files .Tests}}</p>
+                    {{if .Issues}}
+                    <table>
+                        <tr><th>{{t "report.col.issue"}}</th><th>{{t "report.col.count"}}</th></tr>
+                        {{range .Counts}}<tr><td>{{name "testquality.kind" .Kind}}</td><td>{{.Count}}</td></tr>{{end}}
+                    </table>
+                    {{end}}
+                </div>
+                {{if .Issues}}
+                <div class="metric-card">
+                    {{range .Issues}}
+                    <div class="issue-item">
+                        <div class="issue-header">
+                            <span class="issue-linter">{{name "testquality.kind" .Kind}}</span>
+                            <span class="issue-location">{{sourceLink .File .Line (printf "%s:%d:%d" .File .Line .Column)}} <code>{{.Func}}</code></span>
+                        </div>
+                        <div class="issue-text">{{.Message}}</div>
+                    </div>
+                    {{end}}
+                </div>
+                {{end}}
+            </div>
+            {{end}}
+
+            {{with .Flaky}}
+            <!-- Flaky Tests Section -->
+            <div class="section">
+                <h2>🎲 {{t "report.flaky"}}</h2>
+                <div class="metric-card">
+                    {{$flaky := .Flaky}}
+                    <h3>{{t "report.label.status"}}: {{if $flaky}}<span class="status-error">✗ {{t "report.flaky.found"}}</span><span class="issue-count">{{len $flaky}}</span>{{else}}<span class="status-ok">✓ {{t "report.flaky.none"}}</span>{{end}}</h3>
+                    <p>{{t "report.flaky.counts" .Runs (len .Tests)}} | {{t "report.flaky.shuffle"}}: {{if .Shuffle}}{{t "report.yes"}}{{else}}{{t "report.no"}}{{end}}</p>
+                    {{if $flaky}}
+                    <table>
+                        <tr><th>{{t "report.col.package"}}</th><th>{{t "report.col.test"}}</th><th>{{t "report.col.failed"}}</th><th>{{t "report.col.seeds"}}</th></tr>
+                        {{range $flaky}}
+                        <tr>
+                            <td><code>{{.Package}}</code></td>
+                            <td><code>{{.Name}}</code></td>
+                            <td>{{t "report.outOf" .Failed .Runs}}</td>
+                            <td>{{range $i, $seed := .FailingSeeds}}{{if $i}}, {{end}}<code>-shuffle={{$seed}}</code>{{else}}—{{end}}</td>
+                        </tr>
+                        {{end}}
+                    </table>
+                    {{end}}
+                </div>
+            </div>
+            {{end}}
+
+            {{with .Bench}}
+            <!-- Benchmark Section -->
+            <div class="section">
+                <h2>⏱ {{t "report.bench"}}</h2>
+                <div class="metric-card">
+                    {{$regressions := .Regressions 0.0}}
+                    {{if .Baseline}}
+                    <h3>{{t "report.label.status"}}: {{if $regressions}}<span class="status-error">✗ {{t "report.bench.regressionsFound"}}</span><span class="issue-count">{{len $regressions}}</span>{{else}}<span class="status-ok">✓ {{t "report.bench.noRegressions"}}</span>{{end}}</h3>
+                    <p>{{t "report.bench.comparedWith"}} <code>{{.Baseline}}</code> | {{t "report.bench.count" .Count}} | {{t "report.bench.method"}}</p>
+                    {{else}}
+                    <h3>{{t "report.label.status"}}: <span class="status-warning">{{t "report.bench.noBaseline"}}</span></h3>
+                    <p>{{t "report.bench.benchmarks" (len .Benchmarks)}} | {{t "report.bench.count" .Count}}</p>
+                    {{end}}
+                </div>
+                {{if $regressions}}
+                <div class="metric-card">
+                    <h3>{{t "report.bench.regressions"}}</h3>
+                    <table>
+                        <tr><th>{{t "report.col.package"}}</th><th>{{t "report.col.benchmark"}}</th><th>{{t "report.col.unitOfMeasure"}}</th><th>{{t "report.col.change"}}</th><th>p</th></tr>
+                        {{range $regressions}}
+                        <tr>
+                            <td><code>{{.Package}}</code></td>
+                            <td><code>{{.Name}}</code></td>
+                            <td>{{.Unit}}</td>
+                            <td class="status-error">{{t "report.change" .Change}}</td>
+                            <td>{{num .P 3}}</td>
+                        </tr>
+                        {{end}}
+                    </table>
+                </div>
+                {{end}}
+                <div class="metric-card">
+                    {{if .Deltas}}
+                    <table>
+                        <tr><th>{{t "report.col.benchmark"}}</th><th>{{t "report.col.unitOfMeasure"}}</th><th>{{t "report.col.before"}}</th><th>{{t "report.col.after"}}</th><th>{{t "report.col.change"}}</th><th>p</th></tr>
+                        {{range .Deltas}}
+                        <tr>
+                            <td><code>{{.Name}}</code><br><small>{{.Package}}</small></td>
+                            <td>{{.Unit}}</td>
+                            <td>{{benchValue .Base.Median}} {{t "report.spread" .Base.Spread}}</td>
+                            <td>{{benchValue .Head.Median}} {{t "report.spread" .Head.Spread}}</td>
+                            <td>{{if not .Significant}}~{{else if gt .Regression 0.0}}<span class="status-error">{{t "report.change" .Change}}</span>{{else}}<span class="status-ok">{{t "report.change" .Change}}</span>{{end}}</td>
+                            <td>{{num .P 3}} (n={{.Base.N}}+{{.Head.N}})</td>
+                        </tr>
+                        {{end}}
+                    </table>
+                    {{else}}
+                    <table>
+                        <tr><th>{{t "report.col.benchmark"}}</th><th>{{t "report.col.unitOfMeasure"}}</th><th>{{t "report.col.median"}}</th></tr>
+                        {{range .Benchmarks}}{{$b := .}}{{range .Units}}{{$s := summarize (index $b.Values .)}}
+                        <tr>
+                            <td><code>{{$b.Name}}</code><br><small>{{$b.Package}}</small></td>
+                            <td>{{.}}</td>
+                            <td>{{benchValue $s.Median}} {{t "report.spread" $s.Spread}}</td>
+                        </tr>
+                        {{end}}{{end}}
+                    </table>
+                    {{end}}
+                </div>
+            </div>
+            {{end}}
+
+            {{with .Races}}
+            <!-- Data Race Section -->
+            <div class="section">
+                <h2>🏁 {{t "report.races"}}</h2>
+                <div class="metric-card">
+                    <h3>{{t "report.label.status"}}: {{if .Races}}<span class="status-error">✗ {{t "report.races.found"}}</span><span class="issue-count">{{len .Races}}</span>{{else}}<span class="status-ok">✓ {{t "report.races.none"}}</span>{{end}}</h3>
+                    {{if .Races}}<p>{{t "report.races.reports" .Reports}}</p>{{end}}
+                </div>
+                {{range .Races}}
+                <div class="metric-card race">
+                    <h3><code>{{.Package}}</code>{{if gt .Count 1}} <span class="issue-count">×{{.Count}}</span>{{end}}</h3>
+                    {{if .Tests}}<p>{{t "report.label.tests"}}: {{range $i, $t := .Tests}}{{if $i}}, {{end}}<code>{{$t}}</code>{{end}}</p>{{end}}
+                    {{if .Location}}<p>{{t "report.label.memory"}}: <code>{{.Location}}</code></p>{{end}}
+                    <div class="race-sides">
+                        {{template "raceAccess" .Access}}
+                        {{template "raceAccess" .Previous}}
+                    </div>
+                </div>
+                {{end}}
+            </div>
+            {{end}}
+
+            {{with .Fuzz}}
+            <!-- Fuzzing Section -->
+            <div class="section">
+                <h2>🐛 {{t "report.fuzz"}}</h2>
+                <div class="metric-card">
+                    {{$failed := .Failed}}
+                    <h3>{{t "report.label.status"}}: {{if not .Targets}}<span class="status-warning">{{t "report.fuzz.noTargets"}}</span>{{else if $failed}}<span class="status-error">✗ {{t "report.fuzz.failed"}}</span><span class="issue-count">{{len $failed}}</span>{{else}}<span class="status-ok">✓ {{t "report.fuzz.passed"}}</span>{{end}}</h3>
+                    <p>{{t "report.fuzz.counts" (len .Targets) .FuzzTime}}</p>
+                    {{if .Targets}}
+                    <table>
+                        <tr><th>{{t "report.col.package"}}</th><th>{{t "report.col.fuzzTarget"}}</th><th>{{t "report.col.execs"}}</th><th>{{t "report.col.execsPerSec"}}</th><th>{{t "report.col.newCorpus"}}</th><th>{{t "report.col.result"}}</th></tr>
+                        {{range .Targets}}
+                        <tr>
+                            <td><code>{{.Package}}</code></td>
+                            <td><code>{{.Name}}</code></td>
+                            <td>{{int .Execs}}</td>
+                            <td>{{num .ExecsPerSec 0}}</td>
+                            <td>{{t "report.fuzz.corpus" .NewInteresting .Corpus}}</td>
+                            <td>{{if .Failed}}<span class="status-error">✗ {{t "report.fuzz.failure"}}</span>{{else}}<span class="status-ok">✓</span>{{end}}</td>
+                        </tr>
+                        {{end}}
+                    </table>
+                    {{end}}
+                </div>
+                {{range $failed}}
+                <div class="metric-card">
+                    <h3><code>{{.Package}}</code> <code>{{.Name}}</code></h3>
+                    <p class="status-error">{{.Message}}</p>
+                    {{with .PanicSite}}<p>{{t "report.label.panicSite"}}: <code>{{.}}</code></p>{{end}}
+                    {{range .FailingInputs}}
+                    <p>{{t "report.label.failingInput"}}: <code>{{.Path}}</code></p>
+                    <pre>{{.Content}}</pre>
+                    {{end}}
+                    {{if .Crash}}<details><summary>{{t "report.testOutput"}}</summary><pre>{{.Crash}}</pre></details>{{end}}
+                </div>
+                {{end}}
+            </div>
+            {{end}}
+
+            {{with .Mutation}}
+            <!-- Mutation Testing Section -->
+            <div class="section">
+                <h2>🧬 {{t "report.mutation"}}</h2>
+                <div class="metric-card">
+                    {{$functions := .Functions}}
+                    <h3>{{t "report.label.mutationScore"}}: {{percent .Score}}</h3>
+                    <p>{{t "report.mutation.description"}}</p>
+                    <table>
+                        <tr><th>{{t "report.col.function"}}</th><th>{{t "report.col.file"}}</th><th>{{t "report.col.score"}}</th><th>{{t "report.col.detected"}}</th><th>{{t "report.col.survived"}}</th></tr>
+                        {{range $functions}}
+                        <tr>
+                            <td><code>{{.Func}}</code></td>
+                            <td><code>{{.File}}</code></td>
+                            <td>{{percent .Score}}</td>
+                            <td>{{.Detected}}</td>
+                            <td>{{if .Survivors}}<span class="status-error">{{len .Survivors}}</span>{{else}}0{{end}}</td>
+                        </tr>
+                        {{end}}
+                    </table>
+                </div>
+                {{range $functions}}{{if .Survivors}}
+                <div class="metric-card">
+                    <h3>{{t "report.label.survivors"}}: <code>{{.Func}}</code></h3>
+                    <table>
+                        <tr><th>{{t "report.col.line"}}</th><th>{{t "report.col.mutation"}}</th><th>{{t "report.col.before"}}</th><th>{{t "report.col.after"}}</th></tr>
+                        {{range .Survivors}}
+                        <tr>
+                            <td><code>{{sourceLink .File .Line (printf "%s:%d" .File .Line)}}</code></td>
+                            <td>{{name "mutate.operator" .Operator}}</td>
+                            <td><code>{{.Original}}</code></td>
+                            <td>{{if .Mutated}}<code>{{.Mutated}}</code>{{else}}—{{end}}</td>
+                        </tr>
+                        {{end}}
+                    </table>
+                </div>
+                {{end}}{{end}}
+                {{with .NotCovered}}
+                <div class="metric-card">
+                    <h3>{{t "report.mutation.notCovered"}}</h3>
+                    <ul>
+                        {{range .}}<li><code>{{.File}}</code>: {{t "report.mutation.mutants" .Mutants}}</li>{{end}}
+                    </ul>
+                </div>
+                {{end}}
+                {{if .Skipped}}
+                <div class="metric-card">
+                    <h3>{{t "report.mutation.skipped"}}</h3>
+                    <ul>
+                        {{range .Skipped}}<li><code>{{.File}}</code>: {{.Reason}}</li>{{end}}
+                    </ul>
+                </div>
+                {{end}}
+            </div>
+            {{end}}
+
+            {{if .OrderDeps}}
+            <!-- Test Order Section -->
+            <div class="section">
+                <h2>🔗 {{t "report.order"}}</h2>
+                <div class="metric-card">
+                    <table>
+                        <tr><th>{{t "report.col.package"}}</th><th>{{t "report.col.test"}}</th><th>{{t "report.col.seed"}}</th><th>{{t "report.col.result"}}</th></tr>
+                        {{range .OrderDeps}}
+                        <tr>
+                            <td><code>{{.Package}}</code></td>
+                            <td><code>{{.Test}}</code></td>
+                            <td><code>-shuffle={{.Seed}}</code></td>
+                            <td>{{if not .Reproduced}}<span class="status-warning">{{t "report.order.notReproduced"}}</span>{{else if .FailsAlone}}<span class="status-error">{{t "report.order.failsAlone"}}</span> — {{t "report.order.failsAloneHint"}}{{else if .After}}<span class="status-error">{{t "report.order.failsAfter"}} {{range $i, $t := .After}}{{if $i}}, {{end}}<code>{{$t}}</code>{{end}}</span>{{else}}<span class="status-warning">{{t "report.order.unknown"}}</span>{{end}}</td>
+                        </tr>
+                        {{end}}
+                    </table>
+                </div>
+            </div>
+            {{end}}
+
             <!-- Coverage Section -->
             <div class="section">
-                <h2>📈 Покрытие тестами</h2>
+                <h2>📈 {{t "report.coverage"}}</h2>
                 <div class="metric-card">
-                    <h3>Статус: {{if .CoverageData}}<span class="status-ok">✓ Отчет о покрытии сгенерирован</span>{{else}}<span class="status-warning">⚠ Данные о покрытии отсутствуют</span>{{end}}</h3>
+                    <h3>{{t "report.label.status"}}: {{if .CoverageData}}<span class="status-ok">✓ {{t "report.coverage.generated"}}</span>{{else}}<span class="status-warning">⚠ {{t "report.coverage.missing"}}</span>{{end}}</h3>
                     {{if .CoverageData}}
-                    <p>Отчет о покрытии кода тестами успешно сгенерирован.</p>
-                    {{if .CoverageHTML}}
+                    <p>{{t "report.coverage.generatedText"}}</p>
+                    {{if .Coverage}}
+                    <p>{{t "report.label.coverage"}}: <strong>{{percent .Coverage.Percent}}</strong> ({{t "report.statementsOf" (int .Coverage.Covered) (int .Coverage.Statements)}})</p>
+                    {{end}}
+                    {{with .Exclusions}}{{if .Excluded}}
+                    <p>{{t "report.label.withoutExclusions"}}: {{percent .Raw.Percent}} ({{t "report.statementsOf" (int .Raw.Covered) (int .Raw.Statements)}}) | {{t "report.coverage.excluded" (sub .Raw.Statements .Adjusted.Statements)}}</p>
+                    <table>
+                        <tr><th>{{t "report.col.file"}}</th><th>{{t "report.col.function"}}</th><th>{{t "report.col.reason"}}</th><th>{{t "report.col.statements"}}</th></tr>
+                        {{range .Excluded}}
+                        <tr>
+                            <td><code>{{sourceLink .File .StartLine .File}}</code></td>
+                            <td>{{if .Func}}<code>{{.Func}}</code> ({{t "report.lines" .StartLine .EndLine}}){{else}}{{t "report.wholeFile"}}{{end}}</td>
+                            <td>{{name "coverage.reason" .Reason}}{{if .Rule}} <code>{{.Rule}}</code>{{end}}</td>
+                            <td>{{.Covered}}/{{.Statements}}</td>
+                        </tr>
+                        {{end}}
+                    </table>
+                    {{end}}{{end}}
+                    {{with .Integration}}
+                    <h3>🔗 {{t "report.label.integrationCoverage"}}: {{percent .CombinedPercent}}</h3>
+                    <p>{{t "report.label.unitTests"}}: {{percent .UnitPercent}} | {{t "report.label.integrationCommands"}}: {{percent .IntegrationPercent}} | {{t "report.label.integrationOnly"}}: {{t "report.statementsOf" (int .IntegrationOnly) (int .Statements)}}</p>
+                    <table>
+                        <tr><th>{{t "report.col.package"}}</th><th>{{t "report.col.total"}}</th><th>{{t "report.col.unitTests"}}</th><th>{{t "report.col.integrationCommands"}}</th><th>{{t "report.col.integrationOnly"}}</th></tr>
+                        {{range .Packages}}
+                        <tr>
+                            <td><code>{{.Package}}</code></td>
+                            <td>{{percent .CombinedPercent}} ({{.Combined}}/{{.Statements}})</td>
+                            <td>{{percent .UnitPercent}}</td>
+                            <td>{{percent .IntegrationPercent}}</td>
+                            <td>{{.IntegrationOnly}}</td>
+                        </tr>
+                        {{end}}
+                    </table>
+                    <table>
+                        <tr><th>{{t "report.col.command"}}</th><th>{{t "report.col.exitCode"}}</th><th>{{t "report.col.time"}}</th></tr>
+                        {{range .Commands}}
+                        <tr><td><code>{{.Command}}</code></td><td>{{if eq .ExitCode 0}}<span class="status-ok">0</span>{{else}}<span class="status-error">{{.ExitCode}}</span>{{end}}</td><td>{{t "report.seconds" .Elapsed}}</td></tr>
+                        {{end}}
+                    </table>
                     <div class="links">
-                        <a href="{{.CoverageHTML}}" target="_blank">📊 Открыть HTML отчет о покрытии</a>
+                        <a {{artifact "coverage_combined.html"}}>📊 {{t "report.coverage.combinedHTML"}}</a>
+                    </div>
+                    {{end}}
+                    {{with .PatchCoverage}}
+                    <p>{{t "report.label.patchCoverage" .Base}}: <strong>{{percent .Percent}}</strong> ({{t "report.changedLinesOf" (int .Covered) (int .Lines)}})</p>
+                    {{if .Files}}
+                    <table>
+                        <tr><th>{{t "report.col.file"}}</th><th>{{t "report.col.coverage"}}</th><th>{{t "report.col.uncoveredLines"}}</th></tr>
+                        {{range .Files}}
+                        <tr>
+                            <td><code>{{sourceLink .File 0 .File}}</code></td>
+                            <td>{{percent .Percent}} ({{.Covered}}/{{.Statements}})</td>
+                            <td>{{if .Uncovered}}<span class="status-error">{{lineRanges .Uncovered}}</span>{{else}}<span class="status-ok">—</span>{{end}}</td>
+                        </tr>
+                        {{end}}
+                    </table>
+                    {{end}}
+                    {{end}}
+                    {{if .SourcePages}}
+                    <div class="links">
+                        <a href="#sources">📂 {{t "report.coverage.sources"}}</a>
+                    </div>
+                    {{else if .CoverageHTML}}
+                    <div class="links">
+                        <a {{artifact .CoverageHTML}}>📊 {{t "report.coverage.html"}}</a>
                     </div>
                     {{end}}
                     {{else}}
-                    <p>Данные о покрытии кода тестами отсутствуют.</p>
+                    <p>{{t "report.coverage.missingText"}}</p>
                     {{end}}
                 </div>
             </div>
 
             <!-- Gocyclo Section -->
             <div class="section">
-                <h2>🔄 Цикломатическая сложность</h2>
+                <h2>🔄 {{t "report.gocyclo"}}</h2>
                 <div class="metric-card">
-                    <h3>Статус: {{if .GocycloOutput}}<span class="status-ok">✓ Анализ завершен</span>{{else}}<span class="status-warning">⚠ Данные отсутствуют</span>{{end}}</h3>
+                    <h3>{{t "report.label.status"}}: {{if .GocycloOutput}}<span class="status-ok">✓ {{t "report.gocyclo.done"}}</span>{{else}}<span class="status-warning">⚠ {{t "report.noData"}}</span>{{end}}</h3>
                     {{if .GocycloOutput}}
+                    {{if .Complexity}}
+                    <p>{{t "report.gocyclo.counts" (len .Complexity) .AvgComplexity .MaxComplexity}}</p>
+                    {{end}}
                     <pre>{{.GocycloOutput}}</pre>
                     {{else}}
-                    <p>Данные о цикломатической сложности отсутствуют.</p>
+                    <p>{{t "report.gocyclo.missing"}}</p>
                     {{end}}
                 </div>
             </div>
+
+            {{with .Risks}}{{if .Functions}}
+            <!-- Risk Section -->
+            <div class="section">
+                <h2>🎯 {{t "report.risks"}}</h2>
+                <div class="metric-card">
+                    <p>{{t "report.risks.description"}}</p>
+                    <p>{{t "report.risks.counts" (len .Functions) .Max.Score .CrappyCount}}</p>
+                    <table>
+                        <tr><th>{{t "report.col.function"}}</th><th>{{t "report.col.location"}}</th><th>{{t "report.col.complexity"}}</th><th>{{t "report.col.coverage"}}</th><th>CRAP</th></tr>
+                        {{range .Top 20}}
+                        <tr>
+                            <td><code>{{.Package}}.{{.Func}}</code></td>
+                            <td><code>{{sourceLink .File .Line (printf "%s:%d" .File .Line)}}</code></td>
+                            <td>{{.Complexity}}</td>
+                            <td>{{percent .Coverage}} ({{.Covered}}/{{.Statements}})</td>
+                            <td>{{if .Crappy}}<span class="status-error">{{num .Score 1}}</span>{{else}}{{num .Score 1}}{{end}}</td>
+                        </tr>
+                        {{end}}
+                    </table>
+                </div>
+            </div>
+            {{end}}{{end}}
+
+            {{if .SourceTree}}
+            <!-- Sources Section -->
+            <div class="section" id="sources">
+                <h2>📂 {{t "report.sources"}}</h2>
+                <div class="metric-card">
+                    <p>{{t "report.sources.description" (len .SourcePages)}}</p>
+                    <div{{if .SelfContained}} class="source-browser"{{end}}>
+                    <div class="source-tree">
+                        {{range .SourceTree}}
+                        {{if .Page}}
+                        <div style="padding-left: {{.Indent}}px">{{sourceLink .Page.Path 0 .Name}}{{with .Page.Coverage}} <span class="tree-stats">{{percent .Percent}}</span>{{end}}{{if .Page.Markers}} <span class="tree-stats">⚠ {{.Page.Markers}}</span>{{end}}</div>
+                        {{else}}
+                        <div class="dir" style="padding-left: {{.Indent}}px">{{.Name}}</div>
+                        {{end}}
+                        {{end}}
+                    </div>
+                    {{if .SelfContained}}
+                    <div id="source-viewer" data-package="{{t "report.label.package"}}" data-coverage="{{t "report.label.coverage"}}" data-statements="{{t "report.viewer.statements"}}" data-of="{{t "report.viewer.of"}}" data-not-found="{{t "report.viewer.notFound"}}" data-unsupported="{{t "report.viewer.unsupported"}}" data-legend="{{t "source.legend.covered"}},{{t "source.legend.partial"}},{{t "source.legend.uncovered"}}">
+                        <p>{{t "report.viewer.select"}}</p>
+                    </div>
+                    {{end}}
+                    </div>
+                </div>
+            </div>
+            {{end}}
+
+            <!-- Trends Section -->
+            {{if .Trends}}
+            <div class="section">
+                <h2>📉 {{t "report.trends"}}</h2>
+                <p>{{t "report.trends.runs" (len .History)}}</p>
+                <div class="trends">
+                    {{range .Trends}}
+                    <div class="metric-card">
+                        <h3>{{.Title}}: {{.Last}}</h3>
+                        {{.Chart}}
+                    </div>
+                    {{end}}
+                </div>
+            </div>
+            {{end}}
         </div>
 
         <footer>
-            <p>Сгенерировано утилитой <strong>gokode</strong> | Все отчеты сохранены в директории <code>{{.MetricsDir}}</code></p>
+            <p>{{t "report.footer.generatedBy"}} <strong>gokode</strong> | {{t "report.footer.savedIn"}} <code>{{.MetricsDir}}</code></p>
         </footer>
     </div>
+    {{if .SelfContained}}
+    {{embedded}}
+    <script>
+    (function () {
+        var blobs = {};
+        // load decompresses an embedded blob into a Blob
+        function load(id) {
+            if (!blobs[id]) {
+                var element = document.getElementById(id);
+                if (!element || typeof DecompressionStream === 'undefined') {
+                    return Promise.reject(new Error(id));
+                }
+                var binary = atob(element.textContent);
+                var bytes = new Uint8Array(binary.length);
+                for (var i = 0; i < binary.length; i++) {
+                    bytes[i] = binary.charCodeAt(i);
+                }
+                var stream = new Blob([bytes]).stream().pipeThrough(new DecompressionStream('gzip'));
+                blobs[id] = new Response(stream).blob();
+            }
+            return blobs[id];
+        }
+
+        var types = {html: 'text/html', json: 'application/json'};
+        document.addEventListener('click', function (event) {
+            var link = event.target.closest('a[data-artifact]');
+            if (!link) {
+                return;
+            }
+            event.preventDefault();
+            var name = link.getAttribute('data-artifact');
+            var type = (types[name.split('.').pop()] || 'text/plain') + ';charset=utf-8';
+            // The window is opened right away so that it isn't blocked
+            var win = window.open('', '_blank');
+            load('artifact-' + name).then(function (blob) {
+                var url = URL.createObjectURL(new Blob([blob], {type: type}));
+                if (win) {
+                    win.location.href = url;
+                } else {
+                    location.href = url;
+                }
+            }, function () {
+                if (win) {
+                    win.close();
+                }
+            });
+        });
+
+        var viewer = document.getElementById('source-viewer');
+        if (!viewer) {
+            return;
+        }
+        var text = viewer.dataset;
+        var classes = {c: 'covered', u: 'uncovered', p: 'partial'};
+        var sources = null;
+        var percent = new Intl.NumberFormat(document.documentElement.lang, {style: 'percent', minimumFractionDigits: 1, maximumFractionDigits: 1});
+
+        function element(tag, className, content) {
+            var node = document.createElement(tag);
+            if (className) {
+                node.className = className;
+            }
+            if (content !== undefined) {
+                node.textContent = content;
+            }
+            return node;
+        }
+
+        function render(files, path, line) {
+            var file = null;
+            for (var i = 0; i < files.length; i++) {
+                if (files[i].path === path) {
+                    file = files[i];
+                }
+            }
+            viewer.textContent = '';
+            if (!file) {
+                viewer.appendChild(element('p', 'status-warning', text.notFound + ': ' + path));
+                return;
+            }
+            viewer.appendChild(element('h3', '', file.path));
+            var info = text.package + ': ' + file.package;
+            if (file.coverage) {
+                var statements = file.coverage.statements || 0;
+                var covered = file.coverage.covered || 0;
+                info += ' | ' + text.coverage + ': ' + percent.format(statements ? covered / statements : 0) + ' (' + covered + ' ' + text.of + ' ' + statements + ' ' + text.statements + ')';
+            }
+            viewer.appendChild(element('p', '', info));
+            if (file.coverage) {
+                var legend = element('div', 'legend');
+                var labels = text.legend.split(',');
+                ['covered', 'partial', 'uncovered'].forEach(function (name, i) {
+                    legend.appendChild(element('span', name, labels[i]));
+                });
+                viewer.appendChild(legend);
+            }
+
+            var table = element('table', 'code');
+            var selected = null;
+            file.lines.forEach(function (content, i) {
+                var number = i + 1;
+                var row = element('tr', classes[file.cov.charAt(i)] || '');
+                var cell = element('td', 'num');
+                var anchor = element('a', '', String(number));
+                anchor.href = '#source/' + file.path + ':' + number;
+                cell.appendChild(anchor);
+                row.appendChild(cell);
+                row.appendChild(element('td', 'text', content));
+                table.appendChild(row);
+                if (number === line) {
+                    row.classList.add('target');
+                    selected = row;
+                }
+                var markers = file.markers && file.markers[number];
+                if (markers) {
+                    var markerRow = element('tr', 'markers');
+                    var markerCell = element('td');
+                    markers.forEach(function (marker) {
+                        var span = element('span', 'marker ' + marker.kind);
+                        span.appendChild(element('b', '', marker.label));
+                        span.appendChild(document.createTextNode(marker.text));
+                        markerCell.appendChild(span);
+                    });
+                    markerRow.appendChild(element('td'));
+                    markerRow.appendChild(markerCell);
+                    table.appendChild(markerRow);
+                }
+            });
+            viewer.appendChild(table);
+            if (selected) {
+                selected.scrollIntoView({block: 'center'});
+            } else {
+                viewer.scrollIntoView();
+            }
+        }
+
+        // Links to the viewer look like #source/calc/calc.go:12
+        function show() {
+            var hash = decodeURIComponent(location.hash);
+            var prefix = '#source/';
+            if (hash.indexOf(prefix) !== 0) {
+                return;
+            }
+            var path = hash.slice(prefix.length);
+            var line = 0;
+            var colon = path.lastIndexOf(':');
+            if (colon >= 0) {
+                line = parseInt(path.slice(colon + 1), 10) || 0;
+                path = path.slice(0, colon);
+            }
+            if (!sources) {
+                sources = load('gokode-sources').then(function (blob) {
+                    return blob.text();
+                }).then(JSON.parse);
+            }
+            sources.then(function (files) {
+                render(files, path, line);
+            }, function () {
+                viewer.textContent = '';
+                viewer.appendChild(element('p', 'status-error', text.unsupported));
+            });
+        }
+        window.addEventListener('hashchange', show);
+        show();
+    })();
+    </script>
+    {{end}}
 </body>
 </html>
+{{define "raceAccess"}}
+<div class="race-access">
+    <h4>{{if .Goroutine}}{{t "report.race.access" (name "report.race.op" .Op) .Goroutine}}{{else}}{{t "report.race.accessMain" (name "report.race.op" .Op)}}{{end}}</h4>
+    {{template "raceStack" .Stack}}
+    {{if .Created}}
+    <p>{{t "report.race.created"}}</p>
+    {{template "raceStack" .Created}}
+    {{end}}
+</div>
+{{end}}
+{{define "raceStack"}}
+<ol class="race-stack">
+    {{range .}}
+    <li><code>{{.Func}}</code><br>{{$url := sourceURL .File}}{{if $url}}<a href="{{$url}}" title="{{.File}}">{{base .File}}:{{.Line}}</a>{{else}}{{.File}}:{{.Line}}{{end}}</li>
+    {{end}}
+</ol>
+{{end}}
 `
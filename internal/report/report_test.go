@@ -1,10 +1,28 @@
 package report
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/andro-kes/gokode/internal/bench"
+	"github.com/andro-kes/gokode/internal/complexity"
+	"github.com/andro-kes/gokode/internal/coverage"
+	"github.com/andro-kes/gokode/internal/depgraph"
+	"github.com/andro-kes/gokode/internal/fuzz"
+	"github.com/andro-kes/gokode/internal/history"
+	"github.com/andro-kes/gokode/internal/i18n"
+	"github.com/andro-kes/gokode/internal/mutate"
+	"github.com/andro-kes/gokode/internal/race"
+	"github.com/andro-kes/gokode/internal/snapshot"
+	"github.com/andro-kes/gokode/internal/source"
+	"github.com/andro-kes/gokode/internal/testquality"
 )
 
 func TestGenerateHTML(t *testing.T) {
@@ -70,11 +88,11 @@ func TestGenerateHTML(t *testing.T) {
 	// Check for key content
 	expectedStrings := []string{
 		"<!DOCTYPE html>",
-		"Отчет анализа кода gokode",
+		"gokode code analysis report",
 		"Go Vet",
 		"Golangci-lint",
-		"Покрытие тестами",
-		"Цикломатическая сложность",
+		"Test coverage",
+		"Cyclomatic complexity",
 		"errcheck",
 		"Error return value not checked",
 	}
@@ -113,3 +131,644 @@ func TestGenerateHTMLEmptyMetrics(t *testing.T) {
 		t.Fatal("report.html was not created for empty metrics")
 	}
 }
+
+func TestGenerateHTMLInterruptedRun(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	run := &RunInfo{
+		Status: StatusInterrupted,
+		Steps: []StepResult{
+			{Name: "Format", Status: StatusOK},
+			{Name: "Vet", Status: StatusInterrupted},
+		},
+	}
+	if err := WriteRunInfo(metricsDir, run); err != nil {
+		t.Fatalf("WriteRunInfo failed: %v", err)
+	}
+
+	if err := GenerateHTML(metricsDir); err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(metricsDir, "report.html"))
+	if err != nil {
+		t.Fatalf("Failed to read report.html: %v", err)
+	}
+
+	htmlString := string(content)
+	for _, expected := range []string{"The analysis was interrupted", "Analysis steps", "Vet"} {
+		if !strings.Contains(htmlString, expected) {
+			t.Errorf("HTML report missing expected content: %s", expected)
+		}
+	}
+}
+
+func TestGenerateHTMLPartialRun(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	run := &RunInfo{
+		Status: StatusComplete,
+		Steps: []StepResult{
+			{Name: "Format", Status: StatusOK},
+			{Name: "Vet", Status: StatusSkipped},
+		},
+		Partial: &PartialRun{
+			Since:        "origin/main",
+			ChangedFiles: []string{"internal/a/a.go"},
+			Packages:     []string{"./internal/a"},
+		},
+	}
+	if err := WriteRunInfo(metricsDir, run); err != nil {
+		t.Fatalf("WriteRunInfo failed: %v", err)
+	}
+
+	if err := GenerateHTML(metricsDir); err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(metricsDir, "report.html"))
+	if err != nil {
+		t.Fatalf("Failed to read report.html: %v", err)
+	}
+
+	htmlString := string(content)
+	for _, expected := range []string{"Partial analysis", "origin/main", "internal/a/a.go", "skipped"} {
+		if !strings.Contains(htmlString, expected) {
+			t.Errorf("HTML report missing expected content: %s", expected)
+		}
+	}
+}
+
+func TestGenerateHTMLTestSelection(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	testOutput := "=== RUN   TestB\n--- PASS: TestB (0.00s)\nPASS\nok  \texample.com/m/b\t0.003s\n"
+	if err := os.WriteFile(filepath.Join(metricsDir, "test.txt"), []byte(testOutput), 0644); err != nil {
+		t.Fatalf("Failed to write test.txt: %v", err)
+	}
+	selection := []depgraph.TestSelection{{
+		Package: "example.com/m/b",
+		Dir:     "./b",
+		Chain:   []string{"example.com/m/b", "example.com/m/a"},
+		Files:   []string{"a/a.go"},
+	}}
+	if err := depgraph.WriteSelection(filepath.Join(metricsDir, depgraph.SelectionFile), "origin/main", selection); err != nil {
+		t.Fatalf("WriteSelection failed: %v", err)
+	}
+
+	if err := GenerateHTML(metricsDir); err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(metricsDir, "report.html"))
+	if err != nil {
+		t.Fatalf("Failed to read report.html: %v", err)
+	}
+
+	htmlString := string(content)
+	for _, expected := range []string{"affected by changes since origin/main", "example.com/m/b → example.com/m/a", "a/a.go"} {
+		if !strings.Contains(htmlString, expected) {
+			t.Errorf("HTML report missing expected content: %s", expected)
+		}
+	}
+}
+
+func TestGenerateHTMLRaces(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	report := &race.Report{Races: []race.Race{{
+		Package: "example.com/m/a",
+		Tests:   []string{"TestInc"},
+		Access: race.Access{
+			Op:        "read",
+			Goroutine: 8,
+			Stack:     []race.Frame{{Func: "a.Inc.func1", File: "/src/m/a/a.go", Line: 8}},
+			Created:   []race.Frame{{Func: "a.Inc", File: "/src/m/a/a.go", Line: 7}},
+		},
+		Previous: race.Access{
+			Op:    "write",
+			Stack: []race.Frame{{Func: "main.main", File: "_testmain.go", Line: 48}},
+		},
+		Count: 2,
+	}}}
+	if err := race.Write(filepath.Join(metricsDir, race.File), report); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if err := GenerateHTML(metricsDir); err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(metricsDir, "report.html"))
+	if err != nil {
+		t.Fatalf("Failed to read report.html: %v", err)
+	}
+
+	htmlString := string(content)
+	for _, expected := range []string{
+		"Data races",
+		"Read in goroutine 8",
+		"Write in the main goroutine",
+		`href="file:///src/m/a/a.go"`,
+		"_testmain.go:48",
+		"Goroutine created at",
+	} {
+		if !strings.Contains(htmlString, expected) {
+			t.Errorf("HTML report missing expected content: %s", expected)
+		}
+	}
+}
+
+func TestGenerateHTMLBench(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	base := []bench.Benchmark{{Package: "example.com/m/a", Name: "BenchmarkParse", Procs: 8, Values: map[string][]float64{"ns/op": {100, 101, 99, 100, 102, 98}}}}
+	head := []bench.Benchmark{{Package: "example.com/m/a", Name: "BenchmarkParse", Procs: 8, Values: map[string][]float64{"ns/op": {13000, 13100, 12900, 13000, 13200, 12800}}}}
+	results := &bench.Report{Count: 6, Benchmarks: head, Baseline: "metrics/refs/main", Deltas: bench.Compare(base, head)}
+	results.Gate = &bench.Gate{Name: "Benchmark regression", Actual: "12900.0%", Threshold: "≤ 10.0%", Passed: false}
+	if err := bench.Write(filepath.Join(metricsDir, bench.File), results); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if err := GenerateHTML(metricsDir); err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(metricsDir, "report.html"))
+	if err != nil {
+		t.Fatalf("Failed to read report.html: %v", err)
+	}
+
+	htmlString := string(content)
+	for _, expected := range []string{"Benchmarks", "Regressions found", "metrics/refs/main", "BenchmarkParse", "12,900.00%", "13,000 ±", "Benchmark regression", "≤ 10.0%", "✗ failed"} {
+		if !strings.Contains(htmlString, expected) {
+			t.Errorf("HTML report missing expected content: %s", expected)
+		}
+	}
+}
+
+func TestGenerateHTMLFuzz(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	results := &fuzz.Report{FuzzTime: "10s", Targets: []fuzz.Target{
+		{Package: "example.com/m/a", Name: "FuzzOK", Elapsed: 10 * time.Second, Execs: 250000, NewInteresting: 3, Corpus: 7},
+		{Package: "example.com/m/a", Name: "FuzzParse", Failed: true, Message: "panic: bad input", PanicSite: "/src/a/a.go:5",
+			FailingInputs: []fuzz.Input{{Path: "a/testdata/fuzz/FuzzParse/ec2765b7", Content: "go test fuzz v1\nstring(\"bad\")\n"}}},
+	}}
+	if err := fuzz.Write(filepath.Join(metricsDir, fuzz.File), results); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if err := GenerateHTML(metricsDir); err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(metricsDir, "report.html"))
+	if err != nil {
+		t.Fatalf("Failed to read report.html: %v", err)
+	}
+
+	htmlString := string(content)
+	for _, expected := range []string{"Fuzzing", "Failing inputs found", "FuzzOK", "25,000", "panic: bad input", "/src/a/a.go:5", "a/testdata/fuzz/FuzzParse/ec2765b7"} {
+		if !strings.Contains(htmlString, expected) {
+			t.Errorf("HTML report missing expected content: %s", expected)
+		}
+	}
+}
+
+func TestGenerateHTMLMutation(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	results := &mutate.Report{
+		Mutants: []mutate.Mutant{
+			{File: "calc/calc.go", Line: 7, Func: "Max", Operator: mutate.OperatorConditional, Original: "a > b", Mutated: "a <= b", Status: mutate.StatusKilled},
+			{File: "calc/calc.go", Line: 17, Func: "Sum", Operator: mutate.OperatorArithmetic, Original: "total += x", Mutated: "total -= x", Status: mutate.StatusSurvived},
+			{File: "calc/calc.go", Line: 26, Func: "Sum", Operator: mutate.OperatorCall, Original: "log(x)", Status: mutate.StatusNotViable},
+			{File: "gen/gen.go", Line: 3, Func: "Gen", Operator: mutate.OperatorConditional, Original: "a > b", Mutated: "a <= b", Status: mutate.StatusNotCovered},
+		},
+		Skipped: []mutate.Skip{{File: "broken/b.go", Reason: "tests fail without mutations: ./broken"}},
+	}
+	if err := mutate.Write(filepath.Join(metricsDir, mutate.File), results); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if err := GenerateHTML(metricsDir); err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(metricsDir, "report.html"))
+	if err != nil {
+		t.Fatalf("Failed to read report.html: %v", err)
+	}
+
+	htmlString := string(content)
+	for _, expected := range []string{"Mutation testing", "Mutation score: 50.0%", "Surviving mutants: <code>Sum</code>", "total -= x", "calc/calc.go:17", "broken/b.go", "Files without tests", "<code>gen/gen.go</code>: mutants: 1"} {
+		if !strings.Contains(htmlString, expected) {
+			t.Errorf("HTML report missing expected content: %s", expected)
+		}
+	}
+	if strings.Contains(htmlString, "Surviving mutants: <code>Max</code>") {
+		t.Error("Expected no survivors section for a function without surviving mutants")
+	}
+}
+
+func TestGenerateHTMLTestQuality(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	results := &testquality.Report{Files: 1, Tests: 2, Issues: []testquality.Issue{
+		{File: "worker/jsoner/json_test.go", Line: 26, Column: 6, Func: "TestWrite", Kind: testquality.KindNoFailure, Message: "test can never fail"},
+		{File: "worker/jsoner/json_test.go", Line: 57, Column: 3, Func: "TestWrite", Kind: testquality.KindSkipOnMismatch, Message: "test is skipped instead of failing when !reflect.DeepEqual(actual, expected)"},
+	}}
+	if err := testquality.Write(filepath.Join(metricsDir, testquality.File), results); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if err := GenerateHTML(metricsDir); err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(metricsDir, "report.html"))
+	if err != nil {
+		t.Fatalf("Failed to read report.html: %v", err)
+	}
+
+	htmlString := string(content)
+	for _, expected := range []string{"Test quality", "Tests: 2", "Test cannot fail", "Skip on mismatch", "worker/jsoner/json_test.go:57:3", "!reflect.DeepEqual(actual, expected)"} {
+		if !strings.Contains(htmlString, expected) {
+			t.Errorf("HTML report missing expected content: %s", expected)
+		}
+	}
+	if strings.Contains(htmlString, "Ignored error") {
+		t.Error("Expected only the kinds of issues found to be counted")
+	}
+}
+
+func TestGenerateHTMLRisks(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	risks := &complexity.Report{Functions: []complexity.Risk{
+		{Package: "runner", Func: "(*Runner).Run", File: "internal/runner/runner.go", Line: 40, EndLine: 90, Complexity: 12, Statements: 40, Covered: 10, Score: 72.8},
+		{Package: "calc", Func: "Add", File: "calc/calc.go", Line: 3, EndLine: 5, Complexity: 1, Statements: 1, Covered: 1, Score: 1},
+	}}
+	if err := complexity.Write(filepath.Join(metricsDir, complexity.File), risks); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if err := GenerateHTML(metricsDir); err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(metricsDir, "report.html"))
+	if err != nil {
+		t.Fatalf("Failed to read report.html: %v", err)
+	}
+
+	htmlString := string(content)
+	for _, expected := range []string{"Riskiest functions", "Maximum score: 72.8", "Risky: 1", "runner.(*Runner).Run", "internal/runner/runner.go:40", "25.0% (10/40)", `<span class="status-error">72.8</span>`} {
+		if !strings.Contains(htmlString, expected) {
+			t.Errorf("HTML report missing expected content: %s", expected)
+		}
+	}
+}
+
+func TestGenerateHTMLSources(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	snapshot := &source.Snapshot{Files: []source.Source{
+		{Path: "calc/calc.go", Package: "example.com/m/calc", Profile: "example.com/m/calc/calc.go",
+			Text: "package calc\n\nfunc Sign(n int) int {\n\tif n < 0 {\n\t\treturn -1\n\t}\n\treturn 1\n}\n"},
+		{Path: "calc/calc_test.go", Package: "example.com/m/calc", Text: "package calc\n"},
+		{Path: "main.go", Package: "example.com/m", Profile: "example.com/m/main.go", Text: "package main\n\nfunc main() {}\n"},
+	}}
+	if err := source.Write(filepath.Join(metricsDir, source.File), snapshot); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	files := map[string]string{
+		"coverage.out": "mode: set\nexample.com/m/calc/calc.go:3.22,4.11 1 1\nexample.com/m/calc/calc.go:4.11,6.3 1 0\nexample.com/m/calc/calc.go:7.2,7.10 1 1\n",
+		"vet.txt":      "# example.com/m/calc\n./calc/calc.go:5:3: unreachable code\n",
+		"report.json":  `{"Issues": [{"FromLinter": "revive", "Text": "exported function Sign should have comment", "Pos": {"Filename": "calc/calc.go", "Line": 3, "Column": 1}}]}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(metricsDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	// A page of a file that is gone must not survive the next report
+	stale := filepath.Join(metricsDir, SourcesDir, "old.go.html")
+	if err := os.MkdirAll(filepath.Dir(stale), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stale, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := GenerateHTML(metricsDir); err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(metricsDir, "report.html"))
+	if err != nil {
+		t.Fatalf("Failed to read report.html: %v", err)
+	}
+	htmlString := string(content)
+	for _, expected := range []string{"Source code", `href="source/calc/calc.go.html#L3"`, `href="source/calc/calc.go.html#L5"`, `href="#sources"`, `href="source/main.go.html"`} {
+		if !strings.Contains(htmlString, expected) {
+			t.Errorf("HTML report missing expected content: %s", expected)
+		}
+	}
+
+	page, err := os.ReadFile(filepath.Join(metricsDir, SourcesDir, "calc", "calc.go.html"))
+	if err != nil {
+		t.Fatalf("Failed to read source page: %v", err)
+	}
+	pageString := string(page)
+	for _, expected := range []string{
+		`<tr id="L3" class="covered">`,
+		`<tr id="L4" class="partial">`,
+		`<tr id="L5" class="uncovered">`,
+		`<tr id="L1">`,
+		"<b>revive</b>exported function Sign should have comment",
+		"<b>go vet</b>unreachable code",
+		"Coverage: 66.7%",
+		`href="../../report.html"`,
+		`href="../../source/calc/calc_test.go.html"`,
+	} {
+		if !strings.Contains(pageString, expected) {
+			t.Errorf("source page missing expected content: %s", expected)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(metricsDir, SourcesDir, "calc", "calc_test.go.html")); err != nil {
+		t.Errorf("Expected a page for the test file: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("Expected the stale source page to be removed")
+	}
+}
+
+func TestGenerateSelfContainedHTML(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	snapshot := &source.Snapshot{Files: []source.Source{
+		{Path: "calc/calc.go", Package: "example.com/m/calc", Profile: "example.com/m/calc/calc.go",
+			Text: "package calc\n\nfunc Sign(n int) int {\n\tif n < 0 {\n\t\treturn -1\n\t}\n\treturn 1\n}\n"},
+	}}
+	if err := source.Write(filepath.Join(metricsDir, source.File), snapshot); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	files := map[string]string{
+		"coverage.out": "mode: set\nexample.com/m/calc/calc.go:3.22,4.11 1 1\nexample.com/m/calc/calc.go:4.11,6.3 1 0\n",
+		"report.json":  `{"Issues": [{"FromLinter": "revive", "Text": "exported function Sign should have comment", "Pos": {"Filename": "calc/calc.go", "Line": 3, "Column": 1}}]}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(metricsDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	// Pages of an earlier report are left over and must go
+	if err := os.MkdirAll(filepath.Join(metricsDir, SourcesDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := GenerateSelfContainedHTML(metricsDir); err != nil {
+		t.Fatalf("GenerateSelfContainedHTML failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(metricsDir, "report.html"))
+	if err != nil {
+		t.Fatalf("Failed to read report.html: %v", err)
+	}
+	htmlString := string(content)
+	for _, expected := range []string{
+		`id="gokode-sources"`,
+		`id="artifact-report.json"`,
+		`data-artifact="report.json"`,
+		`href="#source/calc/calc.go:3"`,
+		`id="source-viewer"`,
+		"DecompressionStream",
+	} {
+		if !strings.Contains(htmlString, expected) {
+			t.Errorf("self-contained report missing expected content: %s", expected)
+		}
+	}
+	for _, unexpected := range []string{`href="source/`, `href="report.json"`, `src="http`} {
+		if strings.Contains(htmlString, unexpected) {
+			t.Errorf("self-contained report has an external reference: %s", unexpected)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(metricsDir, SourcesDir)); !os.IsNotExist(err) {
+		t.Error("Expected no source pages next to a self-contained report")
+	}
+
+	// The embedded sources decompress to the lines of the snapshot
+	start := strings.Index(htmlString, `id="gokode-sources">`)
+	if start < 0 {
+		t.Fatal("No embedded sources")
+	}
+	blob := htmlString[start+len(`id="gokode-sources">`):]
+	blob = blob[:strings.Index(blob, "<")]
+	data, err := base64.StdEncoding.DecodeString(blob)
+	if err != nil {
+		t.Fatalf("Failed to decode sources: %v", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to decompress sources: %v", err)
+	}
+	var sources []embeddedSource
+	if err := json.NewDecoder(zr).Decode(&sources); err != nil {
+		t.Fatalf("Failed to parse sources: %v", err)
+	}
+	if len(sources) != 1 || sources[0].Path != "calc/calc.go" || sources[0].Lines[2] != "func Sign(n int) int {" {
+		t.Fatalf("Unexpected embedded sources: %+v", sources)
+	}
+	if !strings.HasPrefix(sources[0].Cov, "  cpu") || len(sources[0].Markers[3]) == 0 {
+		t.Errorf("Unexpected coverage %q or markers %+v", sources[0].Cov, sources[0].Markers)
+	}
+}
+
+func TestGenerateHTMLIntegrationCoverage(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	integration := &coverage.Integration{
+		Binaries: []string{"./cmd/app"},
+		Commands: []coverage.IntegrationCommand{{Command: "./e2e.sh", ExitCode: 1, Elapsed: 1.5}},
+		Split:    coverage.Split{Statements: 10, Unit: 2, Integration: 9, Combined: 9, IntegrationOnly: 7},
+		Packages: []coverage.PackageSplit{
+			{Package: "example.com/m/cmd/app", Split: coverage.Split{Statements: 4, Integration: 4, Combined: 4, IntegrationOnly: 4}},
+		},
+	}
+	if err := coverage.WriteIntegration(filepath.Join(metricsDir, coverage.IntegrationFileName), integration); err != nil {
+		t.Fatalf("WriteIntegration failed: %v", err)
+	}
+	profile := "mode: set\nexample.com/m/pkg/a.go:3.14,5.2 2 1\n"
+	if err := os.WriteFile(filepath.Join(metricsDir, "coverage.out"), []byte(profile), 0644); err != nil {
+		t.Fatalf("Failed to write coverage profile: %v", err)
+	}
+
+	if err := GenerateHTML(metricsDir); err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(metricsDir, "report.html"))
+	if err != nil {
+		t.Fatalf("Failed to read report.html: %v", err)
+	}
+
+	htmlString := string(content)
+	for _, expected := range []string{"Coverage with integration tests: 90.0%", "Unit tests: 20.0%", "Integration only: 7 of 10", "example.com/m/cmd/app", "./e2e.sh", "coverage_combined.html"} {
+		if !strings.Contains(htmlString, expected) {
+			t.Errorf("HTML report missing expected content: %s", expected)
+		}
+	}
+}
+
+func TestGenerateHTMLCoverageExclusions(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	exclusions := &coverage.Exclusions{
+		Raw:      coverage.Stats{Statements: 10, Covered: 4},
+		Adjusted: coverage.Stats{Statements: 4, Covered: 4},
+		Excluded: []coverage.Exclusion{
+			{File: "api/api.pb.go", Reason: coverage.ReasonGlob, Rule: "**/*.pb.go", Statements: 5},
+			{File: "main.go", Func: "(*server).serve", StartLine: 11, EndLine: 14, Reason: coverage.ReasonAnnotation, Statements: 1},
+		},
+	}
+	if err := coverage.WriteExclusions(filepath.Join(metricsDir, coverage.ExclusionsFileName), exclusions); err != nil {
+		t.Fatalf("WriteExclusions failed: %v", err)
+	}
+	profile := "mode: set\nexample.com/m/util.go:3.10,4.2 4 1\n"
+	if err := os.WriteFile(filepath.Join(metricsDir, "coverage.out"), []byte(profile), 0644); err != nil {
+		t.Fatalf("Failed to write coverage profile: %v", err)
+	}
+
+	if err := GenerateHTML(metricsDir); err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(metricsDir, "report.html"))
+	if err != nil {
+		t.Fatalf("Failed to read report.html: %v", err)
+	}
+
+	htmlString := string(content)
+	for _, expected := range []string{"Coverage: <strong>100.0%</strong>", "Without exclusions: 40.0%", "Excluded statements: 6", "api/api.pb.go", "whole file", "(*server).serve", "lines 11-14", "//gokode:nocover annotation"} {
+		if !strings.Contains(htmlString, expected) {
+			t.Errorf("HTML report missing expected content: %s", expected)
+		}
+	}
+}
+
+func TestGenerateHTMLTreeChanges(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	changes := &snapshot.Report{Changes: []snapshot.Change{
+		{Path: "worker/scanner/test/report.json", Kind: snapshot.Modified, Package: "github.com/andro-kes/gokode/worker/scanner", Size: 120},
+		{Path: "out.txt", Kind: snapshot.Created, Size: 3},
+	}}
+	if err := snapshot.Write(filepath.Join(metricsDir, snapshot.File), changes); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if err := GenerateHTML(metricsDir); err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(metricsDir, "report.html"))
+	if err != nil {
+		t.Fatalf("Failed to read report.html: %v", err)
+	}
+
+	htmlString := string(content)
+	for _, expected := range []string{"Files changed by tests", "Created: 1 | Modified: 1 | Deleted: 0", "worker/scanner/test/report.json", "github.com/andro-kes/gokode/worker/scanner", "unknown"} {
+		if !strings.Contains(htmlString, expected) {
+			t.Errorf("HTML report missing expected content: %s", expected)
+		}
+	}
+}
+
+func TestGenerateHTMLTrends(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	for i, coverage := range []float64{40, 55.5} {
+		entry := history.Entry{
+			Timestamp: time.Date(2026, 10, 1+i, 12, 0, 0, 0, time.UTC),
+			Commit:    "abc",
+			Coverage:  coverage,
+			Issues:    map[string]int{"vet": 2 - i},
+		}
+		if err := history.Append(filepath.Join(metricsDir, history.File), entry); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	if err := WriteRunInfo(metricsDir, &RunInfo{Status: StatusComplete, HistoryFile: history.File}); err != nil {
+		t.Fatalf("WriteRunInfo failed: %v", err)
+	}
+
+	if err := GenerateHTML(metricsDir); err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(metricsDir, "report.html"))
+	if err != nil {
+		t.Fatalf("Failed to read report.html: %v", err)
+	}
+
+	htmlString := string(content)
+	for _, expected := range []string{"Trends", "<svg", "Coverage, %: 55.5", "<polyline"} {
+		if !strings.Contains(htmlString, expected) {
+			t.Errorf("HTML report missing expected content: %s", expected)
+		}
+	}
+	if strings.Contains(htmlString, "<script") {
+		t.Error("Expected trend charts without JavaScript")
+	}
+}
+
+func TestGenerateHTMLLocalized(t *testing.T) {
+	previous := i18n.Current()
+	ru, _ := i18n.Lookup("ru")
+	i18n.Set(ru)
+	t.Cleanup(func() { i18n.Set(previous) })
+	metricsDir := t.TempDir()
+
+	snapshot := &source.Snapshot{Files: []source.Source{
+		{Path: "calc/calc.go", Package: "example.com/m/calc", Profile: "example.com/m/calc/calc.go",
+			Text: "package calc\n\nfunc Sign(n int) int {\n\tif n < 0 {\n\t\treturn -1\n\t}\n\treturn 1\n}\n"},
+	}}
+	if err := source.Write(filepath.Join(metricsDir, source.File), snapshot); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	coverageData := "mode: set\nexample.com/m/calc/calc.go:3.22,4.11 1 1\nexample.com/m/calc/calc.go:4.11,6.3 1 0\nexample.com/m/calc/calc.go:7.2,7.10 1 1\n"
+	if err := os.WriteFile(filepath.Join(metricsDir, "coverage.out"), []byte(coverageData), 0644); err != nil {
+		t.Fatalf("Failed to write coverage.out: %v", err)
+	}
+	run := &RunInfo{Status: StatusComplete, Steps: []StepResult{{Name: "Vet", Status: StatusSkipped}}}
+	if err := WriteRunInfo(metricsDir, run); err != nil {
+		t.Fatalf("WriteRunInfo failed: %v", err)
+	}
+
+	if err := GenerateHTML(metricsDir); err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(metricsDir, "report.html"))
+	if err != nil {
+		t.Fatalf("Failed to read report.html: %v", err)
+	}
+	htmlString := string(content)
+	for _, expected := range []string{`<html lang="ru">`, "Отчет анализа кода gokode", "Покрытие: <strong>66,7 %</strong>", "пропущен"} {
+		if !strings.Contains(htmlString, expected) {
+			t.Errorf("HTML report missing expected content: %s", expected)
+		}
+	}
+
+	page, err := os.ReadFile(filepath.Join(metricsDir, SourcesDir, "calc", "calc.go.html"))
+	if err != nil {
+		t.Fatalf("Failed to read source page: %v", err)
+	}
+	for _, expected := range []string{`<html lang="ru">`, "Покрытие: 66,7 %", "покрыто"} {
+		if !strings.Contains(string(page), expected) {
+			t.Errorf("source page missing expected content: %s", expected)
+		}
+	}
+}
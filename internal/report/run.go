@@ -0,0 +1,100 @@
+package report
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/andro-kes/gokode/internal/fileutil"
+)
+
+// Run and step statuses recorded in run.json
+const (
+	StatusRunning     = "running"
+	StatusComplete    = "complete"
+	StatusFailed      = "failed"
+	StatusInterrupted = "interrupted"
+	StatusOK          = "ok"
+	StatusCached      = "cached"
+	StatusResumed     = "resumed"
+	StatusSkipped     = "skipped"
+)
+
+// RunInfoFile is the name of the file describing the last analysis run
+const RunInfoFile = "run.json"
+
+// StepResult describes the outcome of a single analysis step
+type StepResult struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// RunInfo describes an analysis run and the outcome of its steps
+type RunInfo struct {
+	Status     string       `json:"status"`
+	StartedAt  time.Time    `json:"startedAt"`
+	FinishedAt time.Time    `json:"finishedAt"`
+	Commit     string       `json:"commit,omitempty"`
+	Steps      []StepResult `json:"steps"`
+	// PreviousRun is the name of the previous run directory, a sibling of
+	// this run's metrics directory
+	PreviousRun string `json:"previousRun,omitempty"`
+	// HistoryFile is the path of the run history relative to this run's
+	// metrics directory
+	HistoryFile string `json:"historyFile,omitempty"`
+	// Partial is set when the run was limited to changes since a revision
+	Partial *PartialRun `json:"partial,omitempty"`
+	// Gates are the quality gates configured in .gokode.json
+	Gates []GateResult `json:"gates,omitempty"`
+}
+
+// GateResult is the outcome of a quality gate
+type GateResult struct {
+	Name string `json:"name"`
+	// Actual and Threshold are formatted for display, e.g. "72.5%"
+	Actual    string `json:"actual"`
+	Threshold string `json:"threshold"`
+	Passed    bool   `json:"passed"`
+}
+
+// PartialRun describes a run limited to the files changed since a revision
+type PartialRun struct {
+	Since        string   `json:"since"`
+	ChangedFiles []string `json:"changedFiles"`
+	Packages     []string `json:"packages"`
+}
+
+// Interrupted reports whether the run was stopped before it completed
+func (r *RunInfo) Interrupted() bool {
+	return r.Status == StatusInterrupted
+}
+
+// Failed reports whether one of the run's steps failed
+func (r *RunInfo) Failed() bool {
+	return r.Status == StatusFailed
+}
+
+// GatesFailed reports whether one of the quality gates failed
+func (r *RunInfo) GatesFailed() bool {
+	for _, gate := range r.Gates {
+		if !gate.Passed {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteRunInfo atomically writes run.json to the metrics directory
+func WriteRunInfo(metricsDir string, info *RunInfo) error {
+	return fileutil.WriteJSON(filepath.Join(metricsDir, RunInfoFile), info, "run info")
+}
+
+// ReadRunInfo reads run.json from the metrics directory
+func ReadRunInfo(metricsDir string) (*RunInfo, error) {
+	var info RunInfo
+	if err := fileutil.ReadJSON(filepath.Join(metricsDir, RunInfoFile), &info, "run info"); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
@@ -0,0 +1,142 @@
+package report
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/coverage"
+)
+
+// sourcesBlob is the id of the embedded source pages in a self-contained
+// report
+const sourcesBlob = "gokode-sources"
+
+// embeddedSource is a source page in the data of a self-contained report,
+// rendered by the report's script
+type embeddedSource struct {
+	Path     string          `json:"path"`
+	Package  string          `json:"package"`
+	Coverage *coverage.Stats `json:"coverage,omitempty"`
+	Lines    []string        `json:"lines"`
+	// Cov has a character per line: c covered, u uncovered, p partial or a
+	// space for lines without statements
+	Cov     string           `json:"cov"`
+	Markers map[int][]Marker `json:"markers,omitempty"`
+}
+
+// embedder links the artifacts of the metrics directory from the report.
+// In a self-contained report it records them instead and embeds them, with
+// the source pages, as gzip compressed base64 data that the report's script
+// decompresses in the browser.
+type embedder struct {
+	metricsDir    string
+	selfContained bool
+	pages         []*SourcePage
+	artifacts     []string
+}
+
+// link returns the attributes of a link to the artifact
+func (e *embedder) link(name string) template.HTMLAttr {
+	if !e.selfContained {
+		return template.HTMLAttr(`href="` + template.HTMLEscapeString(name) + `" target="_blank"`)
+	}
+	found := false
+	for _, artifact := range e.artifacts {
+		found = found || artifact == name
+	}
+	if !found {
+		e.artifacts = append(e.artifacts, name)
+	}
+	return template.HTMLAttr(`href="#" data-artifact="` + template.HTMLEscapeString(name) + `"`)
+}
+
+// render returns the embedded data of the artifacts linked so far and of
+// the source pages. Artifacts missing from the metrics directory are left
+// out.
+func (e *embedder) render() (template.HTML, error) {
+	var b strings.Builder
+	for _, name := range e.artifacts {
+		data, err := os.ReadFile(filepath.Join(e.metricsDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("error reading %s: %w", name, err)
+		}
+		if err := writeBlob(&b, "artifact-"+name, data); err != nil {
+			return "", err
+		}
+	}
+
+	if len(e.pages) > 0 {
+		sources := make([]embeddedSource, 0, len(e.pages))
+		for _, page := range e.pages {
+			sources = append(sources, embedSource(page))
+		}
+		data, err := json.Marshal(sources)
+		if err != nil {
+			return "", fmt.Errorf("error encoding sources: %w", err)
+		}
+		if err := writeBlob(&b, sourcesBlob, data); err != nil {
+			return "", err
+		}
+	}
+	return template.HTML(b.String()), nil
+}
+
+// embedSource converts a source page to its embedded form
+func embedSource(page *SourcePage) embeddedSource {
+	source := embeddedSource{
+		Path:     page.Path,
+		Package:  page.Package,
+		Coverage: page.Coverage,
+		Lines:    make([]string, len(page.Lines)),
+	}
+	cov := make([]byte, len(page.Lines))
+	for i, line := range page.Lines {
+		source.Lines[i] = line.Text
+		switch line.Coverage {
+		case lineCovered:
+			cov[i] = 'c'
+		case lineUncovered:
+			cov[i] = 'u'
+		case linePartial:
+			cov[i] = 'p'
+		default:
+			cov[i] = ' '
+		}
+		if len(line.Markers) > 0 {
+			if source.Markers == nil {
+				source.Markers = make(map[int][]Marker)
+			}
+			source.Markers[line.Number] = line.Markers
+		}
+	}
+	source.Cov = string(cov)
+	return source
+}
+
+// writeBlob writes data as a gzip compressed, base64 encoded script element
+func writeBlob(b *strings.Builder, id string, data []byte) error {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return fmt.Errorf("error compressing %s: %w", id, err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("error compressing %s: %w", id, err)
+	}
+	fmt.Fprintf(b, "<script type=\"application/gzip\" id=\"%s\">%s</script>\n",
+		template.HTMLEscapeString(id), base64.StdEncoding.EncodeToString(buf.Bytes()))
+	return nil
+}
@@ -0,0 +1,480 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/complexity"
+	"github.com/andro-kes/gokode/internal/coverage"
+	"github.com/andro-kes/gokode/internal/fileutil"
+	"github.com/andro-kes/gokode/internal/i18n"
+	"github.com/andro-kes/gokode/internal/mutate"
+	"github.com/andro-kes/gokode/internal/source"
+)
+
+// SourcesDir is the directory of the source pages in the metrics directory
+const SourcesDir = "source"
+
+// complexityMarkerMin is the cyclomatic complexity above which a function
+// gets a marker in the source viewer, gocyclo's usual -over threshold
+const complexityMarkerMin = 10
+
+// Coverage of a line in the source viewer
+const (
+	lineCovered   = "covered"
+	lineUncovered = "uncovered"
+	// linePartial lines are in both covered and uncovered blocks
+	linePartial = "partial"
+)
+
+// Marker is a diagnostic shown at a line of a source page
+type Marker struct {
+	// Kind selects the style: lint, vet, complexity, quality or mutant
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+	Text  string `json:"text"`
+}
+
+// SourceLine is a line of a source page
+type SourceLine struct {
+	Number int
+	Text   string
+	// Coverage is empty for lines without statements
+	Coverage string
+	Markers  []Marker
+}
+
+// SourcePage is a project file annotated with its coverage and diagnostics
+type SourcePage struct {
+	Path    string
+	Package string
+	// Coverage is nil for files without statements in the cover profile,
+	// such as test files
+	Coverage *coverage.Stats
+	Lines    []SourceLine
+	Markers  int
+}
+
+// URL returns the page's location relative to the metrics directory
+func (p *SourcePage) URL() string {
+	return (&url.URL{Path: p.file()}).EscapedPath()
+}
+
+// file returns the slash separated name of the page in the metrics directory
+func (p *SourcePage) file() string {
+	return SourcesDir + "/" + p.Path + ".html"
+}
+
+// TreeEntry is a directory or file of the source navigation tree
+type TreeEntry struct {
+	Name  string
+	Depth int
+	// Page is nil for directories
+	Page *SourcePage
+}
+
+// Indent returns the indentation of the entry in pixels
+func (e TreeEntry) Indent() int {
+	return e.Depth * 16
+}
+
+// sourceKey normalizes the file names of the various tools, such as
+// ./worker/a.go, to the slash separated project relative source paths
+func sourceKey(file string) string {
+	return path.Clean(filepath.ToSlash(file))
+}
+
+// sourcePages annotates the files of the snapshot with the coverage and the
+// diagnostics collected in the summary
+func sourcePages(s *MetricsSummary, snapshot *source.Snapshot) []*SourcePage {
+	blocks := make(map[string][]coverage.Block)
+	if s.CoverageProfile != nil {
+		for _, block := range s.CoverageProfile.Blocks {
+			blocks[block.File] = append(blocks[block.File], block)
+		}
+	}
+	markers := sourceMarkers(s)
+
+	pages := make([]*SourcePage, 0, len(snapshot.Files))
+	for _, file := range snapshot.Files {
+		page := &SourcePage{Path: file.Path, Package: file.Package}
+		texts := strings.Split(strings.TrimSuffix(file.Text, "\n"), "\n")
+		page.Lines = make([]SourceLine, len(texts))
+		for i, text := range texts {
+			page.Lines[i] = SourceLine{Number: i + 1, Text: text, Markers: markers[file.Path][i+1]}
+			page.Markers += len(page.Lines[i].Markers)
+		}
+
+		if fileBlocks := blocks[file.Profile]; file.Profile != "" && len(fileBlocks) > 0 {
+			// Each line records whether covered (1) and uncovered (2)
+			// blocks span it
+			state := make(map[int]int)
+			stats := coverage.Stats{}
+			for _, block := range fileBlocks {
+				if block.NumStmt == 0 {
+					continue
+				}
+				stats.Statements += block.NumStmt
+				bit := 2
+				if block.Count > 0 {
+					stats.Covered += block.NumStmt
+					bit = 1
+				}
+				for line := block.StartLine; line <= block.EndLine; line++ {
+					state[line] |= bit
+				}
+			}
+			page.Coverage = &stats
+			for i := range page.Lines {
+				switch state[i+1] {
+				case 1:
+					page.Lines[i].Coverage = lineCovered
+				case 2:
+					page.Lines[i].Coverage = lineUncovered
+				case 3:
+					page.Lines[i].Coverage = linePartial
+				}
+			}
+		}
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// sourceMarkers returns the diagnostics of the summary by file and line
+func sourceMarkers(s *MetricsSummary) map[string]map[int][]Marker {
+	markers := make(map[string]map[int][]Marker)
+	add := func(file string, line int, marker Marker) {
+		key := sourceKey(file)
+		if markers[key] == nil {
+			markers[key] = make(map[int][]Marker)
+		}
+		markers[key][line] = append(markers[key][line], marker)
+	}
+
+	for _, issue := range s.LintIssues {
+		add(issue.Pos.Filename, issue.Pos.Line, Marker{Kind: "lint", Label: issue.FromLinter, Text: issue.Text})
+	}
+	for _, issue := range s.VetIssues {
+		add(issue.File, issue.Line, Marker{Kind: "vet", Label: "go vet", Text: issue.Message})
+	}
+
+	risks := make(map[string]complexity.Risk)
+	if s.Risks != nil {
+		for _, risk := range s.Risks.Functions {
+			risks[risk.File+":"+strconv.Itoa(risk.Line)] = risk
+		}
+	}
+	for _, fn := range s.Complexity {
+		risk, scored := risks[sourceKey(fn.File)+":"+strconv.Itoa(fn.Line)]
+		if fn.Complexity <= complexityMarkerMin && !(scored && risk.Crappy()) {
+			continue
+		}
+		text := i18n.T("source.marker.complexity", fn.Function, fn.Complexity)
+		if scored {
+			text += i18n.T("source.marker.crap", risk.Coverage(), risk.Score)
+		}
+		add(fn.File, fn.Line, Marker{Kind: "complexity", Label: i18n.T("source.label.complexity"), Text: text})
+	}
+
+	if s.TestQuality != nil {
+		for _, issue := range s.TestQuality.Issues {
+			add(issue.File, issue.Line, Marker{Kind: "quality", Label: i18n.Current().Name("testquality.kind", issue.Kind), Text: issue.Message})
+		}
+	}
+	if s.Mutation != nil {
+		for _, mutant := range s.Mutation.Mutants {
+			if mutant.Status != mutate.StatusSurvived {
+				continue
+			}
+			text := i18n.Current().Name("mutate.operator", mutant.Operator) + ": " + mutant.Original
+			if mutant.Mutated != "" {
+				text += " → " + mutant.Mutated
+			}
+			add(mutant.File, mutant.Line, Marker{Kind: "mutant", Label: i18n.T("source.label.survivor"), Text: text})
+		}
+	}
+	return markers
+}
+
+// sourceTree lays out the pages, sorted by path, as a tree of directories
+func sourceTree(pages []*SourcePage) []TreeEntry {
+	var tree []TreeEntry
+	var open []string
+	for _, page := range pages {
+		elems := strings.Split(page.Path, "/")
+		dir := elems[:len(elems)-1]
+		common := 0
+		for common < len(open) && common < len(dir) && open[common] == dir[common] {
+			common++
+		}
+		for i := common; i < len(dir); i++ {
+			tree = append(tree, TreeEntry{Name: dir[i] + "/", Depth: i})
+		}
+		open = dir
+		tree = append(tree, TreeEntry{Name: elems[len(elems)-1], Depth: len(dir), Page: page})
+	}
+	return tree
+}
+
+// sourceLinker returns a function rendering a file location as a link to
+// its line on the source page, or as plain text for files without a page.
+// A line of 0 links to the top of the page. A self-contained report links
+// to its own source viewer instead, e.g. #source/calc/calc.go:12.
+func sourceLinker(pages []*SourcePage, selfContained bool) func(file string, line int, text string) template.HTML {
+	urls := make(map[string]string, len(pages))
+	for _, page := range pages {
+		if selfContained {
+			urls[page.Path] = "#source/" + page.Path
+		} else {
+			urls[page.Path] = page.URL()
+		}
+	}
+	return func(file string, line int, text string) template.HTML {
+		href, ok := urls[sourceKey(file)]
+		if !ok {
+			return template.HTML(template.HTMLEscapeString(text))
+		}
+		if line > 0 && selfContained {
+			href += ":" + strconv.Itoa(line)
+		} else if line > 0 {
+			href += "#L" + strconv.Itoa(line)
+		}
+		return template.HTML(`<a href="` + template.HTMLEscapeString(href) + `">` + template.HTMLEscapeString(text) + `</a>`)
+	}
+}
+
+// sourcePageData is the data of a source page template
+type sourcePageData struct {
+	Page      *SourcePage
+	Tree      []TreeEntry
+	Timestamp string
+	// Root is the metrics directory relative to the page
+	Root string
+}
+
+// sourcePageTemplate parses the source page template for the current
+// language
+func sourcePageTemplate() *template.Template {
+	return template.Must(template.New("source").Funcs(i18n.Current().Funcs()).Funcs(template.FuncMap{
+		"codeCSS": codeCSS,
+	}).Parse(sourceTemplate))
+}
+
+// codeCSS returns the styles of annotated source code, shared by the source
+// pages and the source viewer of a self-contained report
+func codeCSS() template.CSS {
+	return template.CSS(codeStyle)
+}
+
+// writeSourcePages writes the source pages of the summary, replacing those
+// of an earlier report
+func writeSourcePages(metricsDir string, summary *MetricsSummary) error {
+	dir := filepath.Join(metricsDir, SourcesDir)
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("error removing old source pages: %w", err)
+	}
+	tmpl := sourcePageTemplate()
+	for _, page := range summary.SourcePages {
+		name := filepath.Join(metricsDir, filepath.FromSlash(page.file()))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return fmt.Errorf("error creating source page directory: %w", err)
+		}
+		var buf strings.Builder
+		err := tmpl.Execute(&buf, sourcePageData{
+			Page:      page,
+			Tree:      summary.SourceTree,
+			Timestamp: summary.Timestamp,
+			Root:      strings.Repeat("../", strings.Count(page.file(), "/")),
+		})
+		if err != nil {
+			return fmt.Errorf("error rendering source page %s: %w", page.Path, err)
+		}
+		if err := fileutil.WriteFile(name, []byte(buf.String()), 0644); err != nil {
+			return fmt.Errorf("error writing source page %s: %w", page.Path, err)
+		}
+	}
+	return nil
+}
+
+const sourceTemplate = `<!DOCTYPE html>
+<html lang="{{lang}}">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Page.Path}} — gokode</title>
+    <style>
+        * {
+            margin: 0;
+            padding: 0;
+            box-sizing: border-box;
+        }
+        body {
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', 'Roboto', 'Oxygen', 'Ubuntu', 'Cantarell', sans-serif;
+            line-height: 1.6;
+            color: #333;
+            background: #f5f5f5;
+        }
+        header {
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            color: white;
+            padding: 15px 30px;
+        }
+        header a {
+            color: white;
+        }
+        header h1 {
+            font-size: 1.4em;
+            font-family: 'Courier New', monospace;
+        }
+        .layout {
+            display: grid;
+            grid-template-columns: 300px 1fr;
+            align-items: start;
+        }
+        nav {
+            position: sticky;
+            top: 0;
+            max-height: 100vh;
+            overflow: auto;
+            background: white;
+            border-right: 1px solid #e9ecef;
+            padding: 10px 0;
+            font-size: 0.85em;
+        }
+        nav div {
+            padding: 1px 10px;
+            white-space: nowrap;
+        }
+        nav .dir {
+            color: #6c757d;
+            font-weight: bold;
+        }
+        nav .current {
+            background: #e9ecef;
+        }
+        nav a {
+            color: #495057;
+            text-decoration: none;
+        }
+        .tree-stats {
+            color: #6c757d;
+            margin-left: 6px;
+        }
+        main {
+            background: white;
+            overflow-x: auto;
+        }
+        {{codeCSS}}
+    </style>
+</head>
+<body>
+    <header>
+        <a href="{{.Root}}report.html">{{t "source.back"}}</a>
+        <h1>{{.Page.Path}}</h1>
+        <div>{{t "report.label.package"}}: <code>{{.Page.Package}}</code>{{with .Page.Coverage}} | {{t "report.label.coverage"}}: {{percent .Percent}} ({{t "report.statementsOf" (int .Covered) (int .Statements)}}){{end}} | {{t "source.markers" .Page.Markers}} | {{t "report.generated" .Timestamp}}</div>
+    </header>
+    <div class="layout">
+        <nav>
+            {{range .Tree}}
+            {{if .Page}}
+            <div{{if eq .Page.Path $.Page.Path}} class="current"{{end}} style="padding-left: {{.Indent}}px"><a href="{{$.Root}}{{.Page.URL}}">{{.Name}}</a>{{with .Page.Coverage}}<span class="tree-stats">{{t "source.treePercent" .Percent}}</span>{{end}}{{if .Page.Markers}}<span class="tree-stats">⚠ {{.Page.Markers}}</span>{{end}}</div>
+            {{else}}
+            <div class="dir" style="padding-left: {{.Indent}}px">{{.Name}}</div>
+            {{end}}
+            {{end}}
+        </nav>
+        <main>
+            {{if .Page.Coverage}}
+            <div class="legend"><span class="covered">{{t "source.legend.covered"}}</span><span class="partial">{{t "source.legend.partial"}}</span><span class="uncovered">{{t "source.legend.uncovered"}}</span></div>
+            {{end}}
+            <table class="code">
+                {{range .Page.Lines}}
+                <tr id="L{{.Number}}"{{if .Coverage}} class="{{.Coverage}}"{{end}}><td class="num"><a href="#L{{.Number}}">{{.Number}}</a></td><td class="text">{{.Text}}</td></tr>
+                {{if .Markers}}
+                <tr class="markers"><td></td><td>{{range .Markers}}<span class="marker {{.Kind}}"><b>{{.Label}}</b>{{.Text}}</span>{{end}}</td></tr>
+                {{end}}
+                {{end}}
+            </table>
+        </main>
+    </div>
+</body>
+</html>
+`
+
+const codeStyle = `.legend {
+    padding: 10px 20px;
+    font-size: 0.9em;
+    border-bottom: 1px solid #e9ecef;
+}
+.legend span {
+    padding: 0 6px;
+    margin-right: 8px;
+}
+table.code {
+    border-collapse: collapse;
+    width: 100%;
+    font-family: 'Courier New', monospace;
+    font-size: 0.85em;
+    line-height: 1.4;
+}
+table.code td {
+    padding: 0 10px;
+    border: none;
+    white-space: pre;
+    tab-size: 4;
+    vertical-align: top;
+}
+table.code td.num {
+    text-align: right;
+    color: #adb5bd;
+    user-select: none;
+    width: 1%;
+}
+table.code td.num a {
+    color: inherit;
+    text-decoration: none;
+}
+tr:target td, tr.target td {
+    outline: 2px solid #667eea;
+}
+.covered, .covered td.text {
+    background: #e6ffed;
+}
+.uncovered, .uncovered td.text {
+    background: #ffeef0;
+}
+.partial, .partial td.text {
+    background: #fff8c5;
+}
+table.code tr.markers td {
+    white-space: normal;
+    font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', sans-serif;
+    padding-bottom: 4px;
+}
+.marker {
+    display: block;
+    background: #f8f9fa;
+    border-left: 3px solid #dc3545;
+    padding: 2px 8px;
+    margin-top: 2px;
+}
+.marker b {
+    margin-right: 6px;
+}
+.marker.complexity {
+    border-color: #ffc107;
+}
+.marker.quality {
+    border-color: #fd7e14;
+}
+.marker.mutant {
+    border-color: #6f42c1;
+}
+`
@@ -0,0 +1,138 @@
+package rundir
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// LatestLink is the name of the symlink pointing at the most recent run
+const LatestLink = "latest"
+
+// timeLayout is the timestamp prefix of run directory names
+const timeLayout = "20060102-150405"
+
+// runName matches run directory names: timestamp, optional commit and an
+// optional counter added when two runs start within the same second
+var runName = regexp.MustCompile(`^\d{8}-\d{6}(-[0-9A-Za-z]+)*$`)
+
+// Create creates a run directory under root named by the start time and
+// commit, e.g. 20261018-150405-1a2b3c4
+func Create(root, commit string, now time.Time) (string, error) {
+	base := now.Format(timeLayout)
+	if commit != "" {
+		base += "-" + commit
+	}
+
+	name := base
+	for i := 2; ; i++ {
+		err := os.Mkdir(filepath.Join(root, name), 0755)
+		if err == nil {
+			return name, nil
+		}
+		if !os.IsExist(err) {
+			return "", fmt.Errorf("error creating run directory: %w", err)
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// List returns the names of the run directories under root, oldest first
+func List(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []string
+	for _, entry := range entries {
+		if entry.IsDir() && runName.MatchString(entry.Name()) {
+			runs = append(runs, entry.Name())
+		}
+	}
+	sort.Strings(runs)
+	return runs, nil
+}
+
+// Latest returns the name of the run the latest symlink points at
+func Latest(root string) (string, error) {
+	target, err := os.Readlink(filepath.Join(root, LatestLink))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(target), nil
+}
+
+// SetLatest atomically points the latest symlink at the named run
+func SetLatest(root, name string) error {
+	tmp := filepath.Join(root, "."+LatestLink+".tmp")
+	os.Remove(tmp)
+	if err := os.Symlink(name, tmp); err != nil {
+		return fmt.Errorf("error creating %s symlink: %w", LatestLink, err)
+	}
+	if err := os.Rename(tmp, filepath.Join(root, LatestLink)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error updating %s symlink: %w", LatestLink, err)
+	}
+	return nil
+}
+
+// Previous returns the newest run under root older than current, or an
+// empty string if there is none
+func Previous(root, current string) (string, error) {
+	runs, err := List(root)
+	if err != nil {
+		return "", err
+	}
+	previous := ""
+	for _, name := range runs {
+		if name >= current {
+			break
+		}
+		previous = name
+	}
+	return previous, nil
+}
+
+// Prune removes old runs under root. A run is kept while it is among the
+// newest keepRuns runs or younger than keepDays days, so a pause longer than
+// keepDays does not wipe the history; a zero limit disables that rule. The
+// current run is never removed. It returns the names of removed runs.
+func Prune(root, current string, keepRuns, keepDays int, now time.Time) ([]string, error) {
+	if keepRuns <= 0 && keepDays <= 0 {
+		return nil, nil
+	}
+
+	runs, err := List(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for i, name := range runs {
+		if name == current {
+			continue
+		}
+
+		if keepRuns > 0 && len(runs)-i <= keepRuns {
+			continue
+		}
+		if keepDays > 0 {
+			started, err := time.ParseInLocation(timeLayout, name[:len(timeLayout)], now.Location())
+			// A run whose age is unknown is kept rather than guessed at
+			if err != nil || now.Sub(started) <= time.Duration(keepDays)*24*time.Hour {
+				continue
+			}
+		}
+
+		if err := os.RemoveAll(filepath.Join(root, name)); err != nil {
+			return removed, fmt.Errorf("error removing run %s: %w", name, err)
+		}
+		removed = append(removed, name)
+	}
+
+	return removed, nil
+}
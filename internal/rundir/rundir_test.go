@@ -0,0 +1,141 @@
+package rundir
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCreateAndList(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2026, 10, 18, 15, 4, 5, 0, time.Local)
+
+	first, err := Create(root, "1a2b3c4", now)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if first != "20261018-150405-1a2b3c4" {
+		t.Errorf("Unexpected run name: %s", first)
+	}
+
+	// A second run in the same second gets a distinct directory
+	second, err := Create(root, "1a2b3c4", now)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if second == first {
+		t.Error("Expected a distinct name for a run started in the same second")
+	}
+
+	// Unrelated directories are not runs
+	if err := os.Mkdir(filepath.Join(root, "other"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+
+	runs, err := List(root)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if !reflect.DeepEqual(runs, []string{first, second}) {
+		t.Errorf("Unexpected runs: %v", runs)
+	}
+
+	if err := SetLatest(root, second); err != nil {
+		t.Fatalf("SetLatest failed: %v", err)
+	}
+	latest, err := Latest(root)
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if latest != second {
+		t.Errorf("Expected latest run %s, got %s", second, latest)
+	}
+
+	previous, err := Previous(root, second)
+	if err != nil {
+		t.Fatalf("Previous failed: %v", err)
+	}
+	if previous != first {
+		t.Errorf("Expected previous run %s, got %s", first, previous)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local)
+
+	var runs []string
+	for _, age := range []int{10, 5, 2, 0} {
+		name, err := Create(root, "abc", now.AddDate(0, 0, -age))
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		runs = append(runs, name)
+	}
+	current := runs[3]
+
+	removed, err := Prune(root, current, 0, 7, now)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if !reflect.DeepEqual(removed, []string{runs[0]}) {
+		t.Errorf("Expected runs older than 7 days to be removed, got %v", removed)
+	}
+
+	removed, err = Prune(root, current, 2, 0, now)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if !reflect.DeepEqual(removed, []string{runs[1]}) {
+		t.Errorf("Expected all but the 2 newest runs to be removed, got %v", removed)
+	}
+
+	left, err := List(root)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if !reflect.DeepEqual(left, []string{runs[2], current}) {
+		t.Errorf("Unexpected remaining runs: %v", left)
+	}
+}
+
+func TestPruneBothLimits(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local)
+
+	var runs []string
+	for _, age := range []int{30, 20, 15, 10, 0} {
+		name, err := Create(root, "abc", now.AddDate(0, 0, -age))
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		runs = append(runs, name)
+	}
+	current := runs[4]
+
+	// After a pause every earlier run is older than keepDays, but the newest
+	// of them are still kept by keepRuns
+	removed, err := Prune(root, current, 3, 7, now)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if !reflect.DeepEqual(removed, []string{runs[0], runs[1]}) {
+		t.Errorf("Expected only runs outside both limits to be removed, got %v", removed)
+	}
+
+	// Young runs are kept beyond keepRuns
+	for _, age := range []int{3, 2, 1} {
+		if _, err := Create(root, "def", now.Add(-time.Duration(age)*time.Hour)); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	removed, err = Prune(root, current, 1, 7, now)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if !reflect.DeepEqual(removed, []string{runs[2], runs[3]}) {
+		t.Errorf("Expected runs younger than keepDays to be kept, got %v", removed)
+	}
+}
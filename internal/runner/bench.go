@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/andro-kes/gokode/internal/bench"
+	"github.com/andro-kes/gokode/internal/fileutil"
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// BenchOutputFile is the go test -bench output in the metrics directory
+const BenchOutputFile = "bench.txt"
+
+// RunBench runs the benchmarks of the given packages, or all packages if none
+// are given, count times with go test -run=^$ -bench=. -benchmem and writes
+// the results to bench.json. When baseline is given, every benchmark measured
+// in both runs is compared with it; baselineName describes where it came from.
+func RunBench(ctx context.Context, path, metricsDir string, count int, baseline *bench.Report, baselineName string, pkgs ...string) (*bench.Report, error) {
+	i18n.Println("bench.running", count)
+
+	args := append([]string{"test", "-run=^$", "-bench=.", "-benchmem", "-count=" + strconv.Itoa(count)}, orAll(pkgs, "./...")...)
+	var output bytes.Buffer
+	cmd := command(ctx, path, "go", args...)
+	cmd.Stdout = io.MultiWriter(os.Stdout, &output)
+	cmd.Stderr = io.MultiWriter(os.Stderr, &output)
+	runErr := cmd.Run()
+	if ctxErr := interrupted(ctx, "go test -bench"); ctxErr != nil {
+		return nil, ctxErr
+	}
+
+	if err := fileutil.WriteFile(filepath.Join(metricsDir, BenchOutputFile), output.Bytes(), 0644); err != nil {
+		return nil, fmt.Errorf("error writing benchmark output: %w", err)
+	}
+	if runErr != nil {
+		return nil, fmt.Errorf("benchmarks failed: %w", runErr)
+	}
+	benchmarks, err := bench.Parse(bytes.NewReader(output.Bytes()))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing benchmark output: %w", err)
+	}
+
+	report := &bench.Report{Count: count, Benchmarks: benchmarks}
+	if baseline != nil {
+		report.Baseline = baselineName
+		report.Deltas = bench.Compare(baseline.Benchmarks, benchmarks)
+	}
+	benchFile := filepath.Join(metricsDir, bench.File)
+	if err := bench.Write(benchFile, report); err != nil {
+		return nil, err
+	}
+
+	if baseline != nil {
+		i18n.Println("bench.comparison", baselineName)
+		for _, delta := range report.Deltas {
+			change := "~"
+			if delta.Significant {
+				change = i18n.T("bench.change", delta.Change)
+			}
+			i18n.Println("bench.delta",
+				delta.Package, delta.Name, delta.Unit,
+				bench.FormatValue(delta.Base.Median), delta.Base.Spread(),
+				bench.FormatValue(delta.Head.Median), delta.Head.Spread(),
+				change, delta.P, delta.Base.N, delta.Head.N)
+		}
+	}
+	i18n.Println("bench.done", len(benchmarks), benchFile)
+	return report, nil
+}
@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/andro-kes/gokode/internal/coverage"
+	"github.com/andro-kes/gokode/internal/depgraph"
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// RawCoverageFile is the coverage profile before exclusions in the metrics
+// directory
+const RawCoverageFile = "coverage_raw.out"
+
+// profileResolver returns a function mapping the import path based file
+// names of a cover profile to file names relative to projectDir, or to an
+// empty string for files outside the project
+func profileResolver(projectDir string, graph *depgraph.Graph) func(string) string {
+	return func(profileFile string) string {
+		pkg, ok := graph.Packages[path.Dir(profileFile)]
+		if !ok {
+			return ""
+		}
+		rel, _ := relPath(projectDir, filepath.Join(pkg.Dir, path.Base(profileFile)))
+		return rel
+	}
+}
+
+// coverageExclusions finds the files and functions of the profile to leave
+// out of coverage: files matching one of the globs, generated files and
+// functions annotated with //gokode:nocover. It also returns the resolver
+// mapping the profile to project files.
+func coverageExclusions(ctx context.Context, projectDir string, profile *coverage.Profile, globs []string) ([]coverage.Exclusion, func(string) string, error) {
+	graph, err := depgraph.Load(ctx, projectDir)
+	if err != nil {
+		if ctxErr := interrupted(ctx, "go list"); ctxErr != nil {
+			return nil, nil, ctxErr
+		}
+		return nil, nil, err
+	}
+	resolve := profileResolver(projectDir, graph)
+
+	var exclusions []coverage.Exclusion
+	for _, file := range profile.Files() {
+		rel := resolve(file)
+		if rel == "" {
+			continue
+		}
+		src, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading %s: %w", rel, err)
+		}
+		found, err := coverage.FileExclusions(rel, src, globs)
+		if err != nil {
+			return nil, nil, err
+		}
+		exclusions = append(exclusions, found...)
+	}
+	return exclusions, resolve, nil
+}
+
+// excludeCoverage removes the excluded code from the profile at name,
+// keeping the original as rawFile, and writes the raw and adjusted coverage
+// with the exclusions to exclusionsFile
+func excludeCoverage(ctx context.Context, projectDir, name, rawFile, exclusionsFile string, globs []string) error {
+	profile, err := coverage.ParseFile(name)
+	if err != nil {
+		return fmt.Errorf("error reading coverage profile: %w", err)
+	}
+	exclusions, resolve, err := coverageExclusions(ctx, projectDir, profile, globs)
+	if err != nil {
+		return err
+	}
+	adjusted, result := profile.Exclude(exclusions, resolve)
+
+	if err := profile.Write(rawFile); err != nil {
+		return err
+	}
+	if err := adjusted.Write(name); err != nil {
+		return err
+	}
+	if err := coverage.WriteExclusions(exclusionsFile, result); err != nil {
+		return err
+	}
+	if len(result.Excluded) > 0 {
+		i18n.Println("coverage.excluded",
+			len(result.Excluded), result.Raw.Statements-result.Adjusted.Statements, result.Raw.Percent(), result.Adjusted.Percent())
+	}
+	return nil
+}
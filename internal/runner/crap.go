@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/complexity"
+	"github.com/andro-kes/gokode/internal/coverage"
+	"github.com/andro-kes/gokode/internal/depgraph"
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// RunCrap combines the gocyclo output and the coverage profile in metricsDir
+// into the CRAP score of each function and writes them to crap.json. Both
+// must have been written by this run.
+func RunCrap(ctx context.Context, path, metricsDir string) error {
+	i18n.Println("crap.running")
+	data, err := os.ReadFile(filepath.Join(metricsDir, "gocyclo.txt"))
+	if err != nil {
+		return fmt.Errorf("error reading gocyclo output: %w", err)
+	}
+	profile, err := coverage.ParseFile(filepath.Join(metricsDir, CoverageFile))
+	if err != nil {
+		return fmt.Errorf("error reading coverage profile: %w", err)
+	}
+	graph, err := depgraph.Load(ctx, path)
+	if err != nil {
+		if ctxErr := interrupted(ctx, "go list"); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+
+	functions := complexity.Parse(strings.Split(strings.TrimSpace(string(data)), "\n"))
+	read := func(name string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(path, filepath.FromSlash(name)))
+	}
+	report, err := complexity.Score(functions, profile, profileResolver(path, graph), read)
+	if err != nil {
+		return err
+	}
+
+	crapFile := filepath.Join(metricsDir, complexity.File)
+	if err := complexity.Write(crapFile, report); err != nil {
+		return err
+	}
+
+	for _, risk := range report.Top(10) {
+		i18n.Println("crap.function",
+			risk.Score, risk.Package, risk.Func, risk.File, risk.Line, risk.Complexity, risk.Coverage())
+	}
+	i18n.Println("crap.done",
+		len(report.Functions), report.CrappyCount(), complexity.Crappy, crapFile)
+	return nil
+}
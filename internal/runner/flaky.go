@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/andro-kes/gokode/internal/flaky"
+	"github.com/andro-kes/gokode/internal/gotest"
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// RunFlaky runs the tests of the given packages, or all packages if none are
+// given, runs times with go test -json -count=1, optionally in a random order
+// with a different -shuffle seed each time, and writes per-test results to
+// flaky.json. It returns the report so callers can act on the flaky tests.
+func RunFlaky(ctx context.Context, path, metricsDir string, runs int, shuffle bool, pkgs ...string) (*flaky.Report, error) {
+	i18n.Println("flaky.running", runs)
+	report := &flaky.Report{Shuffle: shuffle}
+
+	for i := 1; i <= runs; i++ {
+		args := []string{"test", "-json", "-count=1"}
+		var seed int64
+		if shuffle {
+			seed = rand.Int64()
+			args = append(args, "-shuffle="+strconv.FormatInt(seed, 10))
+		}
+		args = append(args, orAll(pkgs, "./...")...)
+
+		var events bytes.Buffer
+		cmd := command(ctx, path, "go", args...)
+		cmd.Stdout = &events
+		cmd.Stderr = os.Stderr
+
+		// Failing tests are what we are looking for, so the exit status is
+		// not an error; only a run without any results is
+		runErr := cmd.Run()
+		if ctxErr := interrupted(ctx, "go test"); ctxErr != nil {
+			return nil, ctxErr
+		}
+		summary, err := gotest.ParseJSON(&events)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing go test output: %w", err)
+		}
+		if runErr != nil && len(summary.Tests) == 0 {
+			return nil, fmt.Errorf("go test failed: %w", runErr)
+		}
+
+		report.Add(seed, summary)
+		failed := summary.Count(gotest.StatusFail)
+		if shuffle {
+			i18n.Println("flaky.runSeed", i, runs, seed, len(summary.Tests), failed)
+		} else {
+			i18n.Println("flaky.run", i, runs, len(summary.Tests), failed)
+		}
+	}
+
+	flakyFile := filepath.Join(metricsDir, flaky.File)
+	if err := flaky.Write(flakyFile, report); err != nil {
+		return nil, err
+	}
+
+	flakyTests := report.Flaky()
+	if len(flakyTests) == 0 {
+		i18n.Println("flaky.none", runs, flakyFile)
+		return report, nil
+	}
+	i18n.Println("flaky.found", len(flakyTests))
+	for _, test := range flakyTests {
+		line := i18n.T("flaky.test", test.Package, test.Name, test.Failed, test.Runs())
+		if len(test.FailingSeeds) > 0 {
+			line += i18n.T("flaky.reproduce", test.FailingSeeds[0])
+		}
+		fmt.Println(line)
+	}
+	i18n.Println("flaky.done", flakyFile)
+	return report, nil
+}
@@ -0,0 +1,172 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/andro-kes/gokode/internal/depgraph"
+	"github.com/andro-kes/gokode/internal/fileutil"
+	"github.com/andro-kes/gokode/internal/fuzz"
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// FuzzOutputFile is the output of all fuzzing runs in the metrics directory
+const FuzzOutputFile = "fuzz.txt"
+
+// maxInputSize limits how much of a failing input is kept in the report
+const maxInputSize = 64 * 1024
+
+// RunFuzz discovers the fuzz targets of the given packages, or all packages
+// if none are given, with go test -list and fuzzes each of them for fuzzTime
+// with go test -fuzz. Failing inputs the fuzzer writes under testdata/fuzz
+// are collected into fuzz.json. Finding a failing input is not an error.
+func RunFuzz(ctx context.Context, path, metricsDir, fuzzTime string, pkgs ...string) (*fuzz.Report, error) {
+	i18n.Println("fuzz.discovering")
+	args := append([]string{"test", "-json", "-list", "^Fuzz"}, orAll(pkgs, "./...")...)
+	var list bytes.Buffer
+	cmd := command(ctx, path, "go", args...)
+	cmd.Stdout = &list
+	cmd.Stderr = os.Stderr
+	listErr := cmd.Run()
+	if ctxErr := interrupted(ctx, "go test -list"); ctxErr != nil {
+		return nil, ctxErr
+	}
+	targets, err := fuzz.ParseList(&list)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing go test output: %w", err)
+	}
+	if listErr != nil && len(targets) == 0 {
+		return nil, fmt.Errorf("error listing fuzz targets: %w", listErr)
+	}
+
+	graph, err := depgraph.Load(ctx, path)
+	if err != nil {
+		if ctxErr := interrupted(ctx, "go list"); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+
+	packages := make([]string, 0, len(targets))
+	for pkg := range targets {
+		packages = append(packages, pkg)
+	}
+	sort.Strings(packages)
+
+	report := &fuzz.Report{FuzzTime: fuzzTime}
+	var output bytes.Buffer
+	for _, pkg := range packages {
+		dir := path
+		if p, ok := graph.Packages[pkg]; ok {
+			dir = p.Dir
+		}
+		for _, name := range targets[pkg] {
+			target, err := fuzzTarget(ctx, path, dir, pkg, name, fuzzTime, &output)
+			if err != nil {
+				return nil, err
+			}
+			report.Targets = append(report.Targets, target)
+		}
+	}
+
+	if err := fileutil.WriteFile(filepath.Join(metricsDir, FuzzOutputFile), output.Bytes(), 0644); err != nil {
+		return nil, fmt.Errorf("error writing fuzzing output: %w", err)
+	}
+	fuzzFile := filepath.Join(metricsDir, fuzz.File)
+	if err := fuzz.Write(fuzzFile, report); err != nil {
+		return nil, err
+	}
+
+	if len(report.Targets) == 0 {
+		i18n.Println("fuzz.none", fuzzFile)
+		return report, nil
+	}
+	fmt.Println()
+	for _, target := range report.Targets {
+		status := i18n.T("fuzz.ok")
+		if target.Failed {
+			status = i18n.T("fuzz.failed", target.Message)
+			if target.PanicSite != "" {
+				status = i18n.T("fuzz.failedAt", target.Message, target.PanicSite)
+			}
+		}
+		i18n.Println("fuzz.target",
+			target.Package, target.Name, target.Execs, target.ExecsPerSec(), target.NewInteresting, status)
+		for _, input := range target.FailingInputs {
+			i18n.Println("fuzz.failingInput", input.Path)
+		}
+	}
+	i18n.Println("fuzz.done", len(report.Targets), len(report.Failed()), fuzzFile)
+	return report, nil
+}
+
+// fuzzTarget fuzzes a single target of the package in dir and appends the
+// output to output
+func fuzzTarget(ctx context.Context, path, dir, pkg, name, fuzzTime string, output *bytes.Buffer) (fuzz.Target, error) {
+	target := fuzz.Target{Package: pkg, Name: name}
+	i18n.Println("fuzz.running", pkg, name, fuzzTime)
+	fmt.Fprintf(output, "=== %s %s\n", pkg, name)
+
+	corpusDir := filepath.Join(dir, "testdata", "fuzz", name)
+	before := make(map[string]bool)
+	for _, file := range listFiles(corpusDir) {
+		before[file] = true
+	}
+
+	var run bytes.Buffer
+	cmd := command(ctx, path, "go", "test", "-run=^$", "-fuzz=^"+regexp.QuoteMeta(name)+"$", "-fuzztime="+fuzzTime, pkg)
+	cmd.Stdout = io.MultiWriter(os.Stdout, &run)
+	cmd.Stderr = io.MultiWriter(os.Stderr, &run)
+	runErr := cmd.Run()
+	if ctxErr := interrupted(ctx, "go test -fuzz"); ctxErr != nil {
+		return target, ctxErr
+	}
+	output.Write(run.Bytes())
+
+	if err := fuzz.ParseOutput(bytes.NewReader(run.Bytes()), &target); err != nil {
+		return target, fmt.Errorf("error parsing go test output: %w", err)
+	}
+	if runErr != nil && !target.Failed {
+		return target, fmt.Errorf("fuzzing %s %s failed: %w", pkg, name, runErr)
+	}
+
+	for _, file := range listFiles(corpusDir) {
+		if before[file] {
+			continue
+		}
+		input := fuzz.Input{Path: file}
+		if rel, ok := relPath(path, file); ok {
+			input.Path = rel
+		}
+		if data, err := os.ReadFile(file); err == nil {
+			if len(data) > maxInputSize {
+				data = data[:maxInputSize]
+			}
+			input.Content = string(data)
+		}
+		target.FailingInputs = append(target.FailingInputs, input)
+	}
+	return target, nil
+}
+
+// listFiles returns the files directly in dir, sorted; a missing directory
+// has none
+func listFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files
+}
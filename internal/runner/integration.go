@@ -0,0 +1,213 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/andro-kes/gokode/internal/coverage"
+	"github.com/andro-kes/gokode/internal/fileutil"
+	"github.com/andro-kes/gokode/internal/flaky"
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// Integration coverage artifacts in the metrics directory
+const (
+	IntegrationOutputFile    = "integration.txt"
+	CombinedCoverageFile     = "coverage_combined.out"
+	CombinedCoverageHTMLFile = "coverage_combined.html"
+)
+
+// RunIntegrationCoverage runs the test suite like RunTestSuite, keeping the
+// raw coverage counters of the test binaries, builds the binaries main
+// packages with -cover and runs each integration command with sh in the
+// project directory. The commands find the built binaries first in PATH and
+// in $GOKODE_BIN, and write their counters to $GOCOVERDIR. The counters are
+// merged with go tool covdata into the combined profile, and the coverage
+// split between unit tests and integration commands is written to
+// integration_coverage.json. Both leave out the code excluded from the unit
+// test coverage.
+func RunIntegrationCoverage(ctx context.Context, path, metricsDir string, knownFlaky []flaky.Known, exclude, binaries, commands []string, pkgs ...string) error {
+	integrationFile := filepath.Join(metricsDir, coverage.IntegrationFileName)
+	outputFile := filepath.Join(metricsDir, IntegrationOutputFile)
+	combinedOut := filepath.Join(metricsDir, CombinedCoverageFile)
+	combinedHTML := filepath.Join(metricsDir, CombinedCoverageHTMLFile)
+	// A failure below must not leave the previous run's results behind
+	removeStale(integrationFile, outputFile, combinedOut, combinedHTML)
+
+	tmpDir, err := os.MkdirTemp("", "gokode-integration-")
+	if err != nil {
+		return fmt.Errorf("error creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	unitDir := filepath.Join(tmpDir, "unit")
+	integrationDir := filepath.Join(tmpDir, "integration")
+	mergedDir := filepath.Join(tmpDir, "merged")
+	binDir := filepath.Join(tmpDir, "bin")
+	for _, dir := range []string{unitDir, integrationDir, mergedDir, binDir} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			return fmt.Errorf("error creating temporary directory: %w", err)
+		}
+	}
+
+	if err := runTestSuite(ctx, path, metricsDir, knownFlaky, exclude, unitDir, pkgs); err != nil {
+		return err
+	}
+
+	i18n.Println("integration.building", strings.Join(binaries, " "))
+	build := command(ctx, path, "go", append([]string{"build", "-cover", "-o", binDir + string(filepath.Separator)}, binaries...)...)
+	build.Stdout = os.Stdout
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		if ctxErr := interrupted(ctx, "go build"); ctxErr != nil {
+			return ctxErr
+		}
+		return fmt.Errorf("error building integration binaries: %w", err)
+	}
+
+	result := &coverage.Integration{Binaries: binaries}
+	var output bytes.Buffer
+	for _, line := range commands {
+		i18n.Println("integration.running", line)
+		fmt.Fprintf(&output, "$ %s\n", line)
+		cmd := command(ctx, path, "sh", "-c", line)
+		cmd.Env = append(os.Environ(),
+			"GOCOVERDIR="+integrationDir,
+			"GOKODE_BIN="+binDir,
+			"PATH="+binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+		cmd.Stdout = io.MultiWriter(os.Stdout, &output)
+		cmd.Stderr = io.MultiWriter(os.Stderr, &output)
+		start := time.Now()
+		runErr := cmd.Run()
+		if ctxErr := interrupted(ctx, "integration command"); ctxErr != nil {
+			return ctxErr
+		}
+		exitCode := 0
+		if runErr != nil {
+			var exitErr *exec.ExitError
+			if !errors.As(runErr, &exitErr) {
+				return fmt.Errorf("error running integration command %q: %w", line, runErr)
+			}
+			exitCode = exitErr.ExitCode()
+			fmt.Fprintf(&output, "exit status %d\n", exitCode)
+		}
+		result.Commands = append(result.Commands, coverage.IntegrationCommand{
+			Command:  line,
+			ExitCode: exitCode,
+			Elapsed:  time.Since(start).Seconds(),
+		})
+	}
+	if err := fileutil.WriteFile(outputFile, output.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing integration output: %w", err)
+	}
+
+	i18n.Println("integration.merging")
+	var inputs []string
+	for _, dir := range []string{unitDir, integrationDir} {
+		if hasCounters(dir) {
+			inputs = append(inputs, dir)
+		}
+	}
+	if len(inputs) == 0 {
+		return errors.New("no coverage counters were written by the tests or the integration commands")
+	}
+	merge := command(ctx, path, "go", "tool", "covdata", "merge", "-i="+strings.Join(inputs, ","), "-o="+mergedDir)
+	if msg, err := merge.CombinedOutput(); err != nil {
+		if ctxErr := interrupted(ctx, "go tool covdata"); ctxErr != nil {
+			return ctxErr
+		}
+		return fmt.Errorf("error merging coverage counters: %s", bytes.TrimSpace(msg))
+	}
+
+	unit, err := covdataProfile(ctx, path, unitDir, filepath.Join(tmpDir, "unit.out"))
+	if err != nil {
+		return err
+	}
+	integration, err := covdataProfile(ctx, path, integrationDir, filepath.Join(tmpDir, "integration.out"))
+	if err != nil {
+		return err
+	}
+	combinedTmp := fileutil.TempName(combinedOut)
+	defer os.Remove(combinedTmp)
+	combined, err := covdataProfile(ctx, path, mergedDir, combinedTmp)
+	if err != nil {
+		return err
+	}
+	exclusions, resolve, err := coverageExclusions(ctx, path, combined, exclude)
+	if err != nil {
+		return err
+	}
+	unit, _ = unit.Exclude(exclusions, resolve)
+	integration, _ = integration.Exclude(exclusions, resolve)
+	combined, _ = combined.Exclude(exclusions, resolve)
+	if err := combined.Write(combinedTmp); err != nil {
+		return err
+	}
+	result.Split, result.Packages = coverage.SplitCoverage(unit, integration, combined)
+
+	combinedHTMLTmp := fileutil.TempName(combinedHTML)
+	defer os.Remove(combinedHTMLTmp)
+	cover := command(ctx, path, "go", "tool", "cover", "-html="+combinedTmp, "-o", combinedHTMLTmp)
+	if err := cover.Run(); err != nil {
+		if ctxErr := interrupted(ctx, "go tool cover"); ctxErr != nil {
+			return ctxErr
+		}
+		return fmt.Errorf("error generating combined HTML coverage report: %w", err)
+	}
+	if err := fileutil.Commit(combinedTmp, combinedOut); err != nil {
+		return fmt.Errorf("error writing combined coverage profile: %w", err)
+	}
+	if err := fileutil.Commit(combinedHTMLTmp, combinedHTML); err != nil {
+		return fmt.Errorf("error writing combined HTML coverage report: %w", err)
+	}
+	if err := coverage.WriteIntegration(integrationFile, result); err != nil {
+		return err
+	}
+
+	i18n.Println("integration.coverage",
+		result.CombinedPercent(), result.UnitPercent(), result.IntegrationPercent(), result.IntegrationOnly)
+	if result.Failed() {
+		var failed []string
+		for _, cmd := range result.Commands {
+			if cmd.ExitCode != 0 {
+				failed = append(failed, cmd.Command)
+			}
+		}
+		return fmt.Errorf("integration commands failed: %s", strings.Join(failed, "; "))
+	}
+	i18n.Println("integration.done", integrationFile, combinedOut, combinedHTML)
+	return nil
+}
+
+// hasCounters reports whether dir holds coverage data files
+func hasCounters(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	return err == nil && len(entries) > 0
+}
+
+// covdataProfile converts the coverage data in dir to a cover profile at
+// name and parses it. A directory without data yields an empty profile.
+func covdataProfile(ctx context.Context, path, dir, name string) (*coverage.Profile, error) {
+	if !hasCounters(dir) {
+		return &coverage.Profile{}, nil
+	}
+	cmd := command(ctx, path, "go", "tool", "covdata", "textfmt", "-i="+dir, "-o="+name)
+	if msg, err := cmd.CombinedOutput(); err != nil {
+		if ctxErr := interrupted(ctx, "go tool covdata"); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("error converting coverage counters: %s", bytes.TrimSpace(msg))
+	}
+	profile, err := coverage.ParseFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("error reading coverage profile: %w", err)
+	}
+	return profile, nil
+}
@@ -0,0 +1,254 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andro-kes/gokode/internal/depgraph"
+	"github.com/andro-kes/gokode/internal/gotest"
+	"github.com/andro-kes/gokode/internal/i18n"
+	"github.com/andro-kes/gokode/internal/mutate"
+)
+
+// mutantTimeoutSlack is added to twice the time the unmutated tests take to
+// get the time after which a mutant counts as timed out
+const mutantTimeoutSlack = 10 * time.Second
+
+// mutantSource is a source file with its mutants and the tests that run
+// against them
+type mutantSource struct {
+	path    string
+	rel     string
+	src     []byte
+	mutants []mutate.Mutant
+	// dirs are the packages whose tests depend on the file in the ./dir form
+	dirs []string
+}
+
+// RunMutate applies the mutants of the non-test Go files of the project, or
+// of the given files relative to path, one at a time with go test -overlay
+// and runs the tests of the packages whose tests depend on the mutated file.
+// A mutant survives when all of those tests pass. Files whose tests fail
+// without mutations are skipped. The result is written to mutation.json.
+func RunMutate(ctx context.Context, path, metricsDir string, files ...string) (*mutate.Report, error) {
+	i18n.Println("mutate.generating")
+	graph, err := depgraph.Load(ctx, path)
+	if err != nil {
+		if ctxErr := interrupted(ctx, "go list"); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+	tests, err := depgraph.LoadTestGraph(ctx, path)
+	if err != nil {
+		if ctxErr := interrupted(ctx, "go list"); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+
+	report := &mutate.Report{}
+	var targets []*mutantSource
+	total := 0
+	for _, file := range sourceFiles(graph, path, files) {
+		rel := file
+		if r, ok := relPath(path, file); ok {
+			rel = r
+		}
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", rel, err)
+		}
+		mutants, err := mutate.Generate(rel, src)
+		if err != nil {
+			report.Skipped = append(report.Skipped, mutate.Skip{File: rel, Reason: err.Error()})
+			continue
+		}
+		if len(mutants) == 0 {
+			continue
+		}
+		target := &mutantSource{path: file, rel: rel, src: src, mutants: mutants}
+		for _, selection := range tests.SelectTests([]string{file}) {
+			target.dirs = append(target.dirs, selection.Dir)
+		}
+		targets = append(targets, target)
+		total += len(mutants)
+	}
+	i18n.Println("mutate.generated", total, len(targets))
+
+	tmpDir, err := os.MkdirTemp("", "gokode-mutate-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// baselines are the durations of the unmutated test runs by package set,
+	// or -1 if the tests failed
+	baselines := make(map[string]time.Duration)
+	done := 0
+	for _, target := range targets {
+		if len(target.dirs) == 0 {
+			// No test can detect these mutants
+			i18n.Println("mutate.untested", target.rel, len(target.mutants))
+			for _, mutant := range target.mutants {
+				mutant.Status = mutate.StatusNotCovered
+				report.Mutants = append(report.Mutants, mutant)
+			}
+			done += len(target.mutants)
+			continue
+		}
+
+		key := strings.Join(target.dirs, " ")
+		baseline, ok := baselines[key]
+		if !ok {
+			baseline, err = testBaseline(ctx, path, target.dirs)
+			if err != nil {
+				return nil, err
+			}
+			baselines[key] = baseline
+		}
+		if baseline < 0 {
+			report.Skipped = append(report.Skipped, mutate.Skip{File: target.rel, Reason: i18n.T("mutate.baselineFailed", key)})
+			done += len(target.mutants)
+			continue
+		}
+
+		overlay, err := writeOverlay(tmpDir, target.path)
+		if err != nil {
+			return nil, err
+		}
+		for _, mutant := range target.mutants {
+			done++
+			if err := os.WriteFile(filepath.Join(tmpDir, "mutant.go"), mutant.Apply(target.src), 0644); err != nil {
+				return nil, fmt.Errorf("error writing mutant: %w", err)
+			}
+			mutant.Status, mutant.KilledBy, err = runMutant(ctx, path, overlay, 2*baseline+mutantTimeoutSlack, target.dirs)
+			if err != nil {
+				return nil, err
+			}
+			i18n.Println("mutate.progress", done, total, mutant.File, mutant.Line, mutant.Change(), i18n.Current().Name("mutant.status", string(mutant.Status)))
+			report.Mutants = append(report.Mutants, mutant)
+		}
+	}
+
+	mutationFile := filepath.Join(metricsDir, mutate.File)
+	if err := mutate.Write(mutationFile, report); err != nil {
+		return nil, err
+	}
+
+	for _, skip := range report.Skipped {
+		i18n.Println("mutate.skipped", skip.File, skip.Reason)
+	}
+	for _, function := range report.Functions() {
+		if len(function.Survivors) == 0 {
+			continue
+		}
+		i18n.Println("mutate.function", function.File, function.Func, function.Score(), len(function.Survivors))
+	}
+	detected, survived, notViable, notCovered := report.Counts()
+	i18n.Println("mutate.done",
+		report.Score(), detected, survived, notViable, notCovered, mutationFile)
+	return report, nil
+}
+
+// sourceFiles returns the non-test Go files of the packages in graph,
+// limited to files if any are given, sorted
+func sourceFiles(graph *depgraph.Graph, path string, files []string) []string {
+	only := make(map[string]bool, len(files))
+	for _, file := range files {
+		only[filepath.Join(path, file)] = true
+	}
+
+	var sources []string
+	for _, pkg := range graph.Packages {
+		for _, name := range pkg.GoFiles {
+			file := filepath.Join(pkg.Dir, name)
+			if len(files) == 0 || only[file] {
+				sources = append(sources, file)
+			}
+		}
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// testBaseline runs the tests of dirs without mutations and returns how long
+// they took, or -1 if they failed
+func testBaseline(ctx context.Context, path string, dirs []string) (time.Duration, error) {
+	i18n.Println("mutate.baseline", strings.Join(dirs, " "))
+	cmd := command(ctx, path, "go", append([]string{"test", "-count=1", "-vet=off"}, dirs...)...)
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	if ctxErr := interrupted(ctx, "go test"); ctxErr != nil {
+		return 0, ctxErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s", output)
+		return -1, nil
+	}
+	return time.Since(start), nil
+}
+
+// writeOverlay writes the go build -overlay file replacing file with the
+// mutant file in dir and returns its name
+func writeOverlay(dir, file string) (string, error) {
+	overlay := struct {
+		Replace map[string]string
+	}{Replace: map[string]string{file: filepath.Join(dir, "mutant.go")}}
+	data, err := json.Marshal(overlay)
+	if err != nil {
+		return "", fmt.Errorf("error encoding overlay: %w", err)
+	}
+	name := filepath.Join(dir, "overlay.json")
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		return "", fmt.Errorf("error writing overlay: %w", err)
+	}
+	return name, nil
+}
+
+// runMutant runs the tests of dirs with the overlay applied and returns the
+// status of the mutant and the first test that failed
+func runMutant(ctx context.Context, path, overlay string, timeout time.Duration, dirs []string) (mutate.Status, string, error) {
+	mutantCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	args := append([]string{"test", "-json", "-count=1", "-failfast", "-vet=off", "-overlay=" + overlay}, dirs...)
+	var events bytes.Buffer
+	cmd := command(mutantCtx, path, "go", args...)
+	cmd.Stdout = &events
+	cmd.Stderr = &events
+	runErr := cmd.Run()
+	if ctxErr := interrupted(ctx, "go test"); ctxErr != nil {
+		return "", "", ctxErr
+	}
+	if errors.Is(mutantCtx.Err(), context.DeadlineExceeded) {
+		return mutate.StatusTimeout, "", nil
+	}
+	if runErr == nil {
+		return mutate.StatusSurvived, "", nil
+	}
+
+	output := events.Bytes()
+	summary, err := gotest.ParseJSON(bytes.NewReader(output))
+	if err != nil {
+		return "", "", fmt.Errorf("error parsing go test output: %w", err)
+	}
+	for _, test := range summary.Tests {
+		if test.Status == gotest.StatusFail {
+			return mutate.StatusKilled, test.Name, nil
+		}
+	}
+	if bytes.Contains(output, []byte("[build failed]")) || bytes.Contains(output, []byte("[setup failed]")) {
+		return mutate.StatusNotViable, "", nil
+	}
+	// A failure outside of a test, e.g. in TestMain or init
+	return mutate.StatusKilled, "", nil
+}
@@ -0,0 +1,178 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/flaky"
+	"github.com/andro-kes/gokode/internal/gotest"
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// OrderTarget is a test that failed with a -shuffle seed
+type OrderTarget struct {
+	Package string
+	Test    string
+	Seed    int64
+}
+
+// FailingTests runs the tests of the given packages, or all packages if none
+// are given, with the -shuffle seed and returns the top-level tests that
+// failed
+func FailingTests(ctx context.Context, path string, seed int64, pkgs ...string) ([]OrderTarget, error) {
+	i18n.Println("order.running", seed)
+	summary, _, err := shuffledRun(ctx, path, seed, nil, orAll(pkgs, "./...")...)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []OrderTarget
+	for _, test := range summary.Tests {
+		if test.Status == gotest.StatusFail && !strings.Contains(test.Name, "/") {
+			targets = append(targets, OrderTarget{Package: test.Package, Test: test.Name, Seed: seed})
+		}
+	}
+	return targets, nil
+}
+
+// RunOrderDeps searches, for each target, the tests that make it fail when
+// they run before it. The shuffled order is determined by the seed before
+// tests are filtered with -run, so subsets run in the same relative order,
+// which lets the predecessors of a failing test be bisected. The results are
+// written to order.json.
+func RunOrderDeps(ctx context.Context, path, metricsDir string, targets []OrderTarget) ([]flaky.OrderDependency, error) {
+	var deps []flaky.OrderDependency
+	for _, target := range targets {
+		i18n.Println("order.searching", target.Package, target.Test, target.Seed)
+		dep, err := findOrderDependency(ctx, path, target)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("  %s\n", dep)
+		deps = append(deps, dep)
+	}
+
+	orderFile := filepath.Join(metricsDir, flaky.OrderFile)
+	if err := flaky.WriteOrder(orderFile, deps); err != nil {
+		return nil, err
+	}
+	i18n.Println("order.done", orderFile)
+	return deps, nil
+}
+
+func findOrderDependency(ctx context.Context, path string, target OrderTarget) (flaky.OrderDependency, error) {
+	dep := flaky.OrderDependency{Package: target.Package, Test: target.Test, Seed: target.Seed}
+
+	summary, order, err := shuffledRun(ctx, path, target.Seed, nil, target.Package)
+	if err != nil {
+		return dep, err
+	}
+	if !failed(summary, target.Test) {
+		return dep, nil
+	}
+	dep.Reproduced = true
+
+	fails := func(before []string) (bool, error) {
+		summary, _, err := shuffledRun(ctx, path, target.Seed, append(append([]string(nil), before...), target.Test), target.Package)
+		if err != nil {
+			return false, err
+		}
+		return failed(summary, target.Test), nil
+	}
+
+	alone, err := fails(nil)
+	if err != nil {
+		return dep, err
+	}
+	if alone {
+		dep.FailsAlone = true
+		return dep, nil
+	}
+
+	var candidates []string
+	for _, name := range order {
+		if name == target.Test {
+			break
+		}
+		candidates = append(candidates, name)
+	}
+	if len(candidates) == 0 {
+		return dep, nil
+	}
+
+	dep.After, err = flaky.Minimize(candidates, fails)
+	return dep, err
+}
+
+// shuffledRun runs the tests of pkgs with the -shuffle seed, restricted to the
+// named top-level tests if any are given, and returns the results and the
+// order in which top-level tests started. Test failures are not an error.
+func shuffledRun(ctx context.Context, path string, seed int64, tests []string, pkgs ...string) (*gotest.Summary, []string, error) {
+	args := []string{"test", "-json", "-count=1", "-shuffle=" + strconv.FormatInt(seed, 10)}
+	if len(tests) > 0 {
+		quoted := make([]string, len(tests))
+		for i, test := range tests {
+			quoted[i] = regexp.QuoteMeta(test)
+		}
+		args = append(args, "-run=^("+strings.Join(quoted, "|")+")$")
+	}
+	args = append(args, pkgs...)
+
+	var events bytes.Buffer
+	cmd := command(ctx, path, "go", args...)
+	cmd.Stdout = &events
+	cmd.Stderr = os.Stderr
+	runErr := cmd.Run()
+	if ctxErr := interrupted(ctx, "go test"); ctxErr != nil {
+		return nil, nil, ctxErr
+	}
+
+	summary, err := gotest.ParseJSON(bytes.NewReader(events.Bytes()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing go test output: %w", err)
+	}
+	if runErr != nil && len(summary.Tests) == 0 {
+		return nil, nil, fmt.Errorf("go test failed: %w", runErr)
+	}
+	order, err := startOrder(&events)
+	if err != nil {
+		return nil, nil, err
+	}
+	return summary, order, nil
+}
+
+// startOrder returns the top-level tests in the order of their run events
+func startOrder(r io.Reader) ([]string, error) {
+	var order []string
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if event, ok := gotest.DecodeEvent(line); ok && event.Action == "run" && !strings.Contains(event.Test, "/") {
+			order = append(order, event.Test)
+		}
+		if err == io.EOF {
+			return order, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// failed reports whether the named test failed
+func failed(summary *gotest.Summary, name string) bool {
+	for _, test := range summary.Tests {
+		if test.Name == name && test.Status == gotest.StatusFail {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/coverage"
+	"github.com/andro-kes/gokode/internal/depgraph"
+	"github.com/andro-kes/gokode/internal/fileutil"
+	"github.com/andro-kes/gokode/internal/git"
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// PatchMarkdownFile is the Markdown summary of patch coverage in the metrics
+// directory
+const PatchMarkdownFile = "patch_coverage.md"
+
+// RunPatchCoverage combines the coverage profile written by RunTestSuite with
+// the lines changed since base and writes the coverage of the changed
+// statements as JSON and Markdown
+func RunPatchCoverage(ctx context.Context, projectDir, metricsDir, base string) error {
+	i18n.Println("patch.running", base)
+
+	profile, err := coverage.ParseFile(filepath.Join(metricsDir, CoverageFile))
+	if err != nil {
+		return fmt.Errorf("error reading coverage profile: %w", err)
+	}
+	changed, err := git.ChangedLines(ctx, projectDir, base)
+	if err != nil {
+		if ctxErr := interrupted(ctx, "git diff"); ctxErr != nil {
+			return ctxErr
+		}
+		return fmt.Errorf("error finding lines changed since %s: %w", base, err)
+	}
+	graph, err := depgraph.Load(ctx, projectDir)
+	if err != nil {
+		if ctxErr := interrupted(ctx, "go list"); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+
+	// Both the profile and the diff are keyed by file names relative to
+	// the project from here on
+	relChanged := make(map[string][]int)
+	for file, lines := range changed {
+		if rel, ok := relPath(projectDir, file); ok {
+			relChanged[rel] = lines
+		}
+	}
+	patch := profile.PatchCoverage(relChanged, profileResolver(projectDir, graph))
+	patch.Base = base
+
+	if err := coverage.WritePatch(filepath.Join(metricsDir, coverage.PatchFileName), patch); err != nil {
+		return err
+	}
+	var md bytes.Buffer
+	if err := coverage.WritePatchMarkdown(&md, patch); err != nil {
+		return err
+	}
+	if err := fileutil.WriteFile(filepath.Join(metricsDir, PatchMarkdownFile), md.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing patch coverage: %w", err)
+	}
+
+	i18n.Println("patch.coverage", patch.Percent(), patch.Covered, patch.Lines)
+	for _, file := range patch.Files {
+		if len(file.Uncovered) > 0 {
+			i18n.Println("patch.uncovered", file.File, coverage.LineRanges(file.Uncovered))
+		}
+	}
+	i18n.Println("patch.done", filepath.Join(metricsDir, coverage.PatchFileName))
+	return nil
+}
+
+// relPath returns file relative to dir in slash form if it lies inside dir
+func relPath(dir, file string) (string, bool) {
+	rel, err := filepath.Rel(dir, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/fileutil"
+	"github.com/andro-kes/gokode/internal/gotest"
+	"github.com/andro-kes/gokode/internal/i18n"
+	"github.com/andro-kes/gokode/internal/race"
+)
+
+// RaceOutputFile is the verbose output of the race detector run in the
+// metrics directory
+const RaceOutputFile = "race.txt"
+
+// RunRace runs the tests of the given packages, or all packages if none are
+// given, with go test -race -json -count=1 and writes the data races found
+// to race.json. Tests failing because of races are what the run looks for,
+// so only a run without any results is an error.
+func RunRace(ctx context.Context, path, metricsDir string, pkgs ...string) (*race.Report, error) {
+	i18n.Println("race.running")
+
+	args := append([]string{"test", "-race", "-json", "-count=1"}, orAll(pkgs, "./...")...)
+	var events, output bytes.Buffer
+	cmd := command(ctx, path, "go", args...)
+	cmd.Stdout = &events
+	cmd.Stderr = io.MultiWriter(os.Stderr, &output)
+	runErr := cmd.Run()
+	if ctxErr := interrupted(ctx, "go test -race"); ctxErr != nil {
+		return nil, ctxErr
+	}
+
+	summary, err := gotest.ParseJSON(bytes.NewReader(events.Bytes()))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing go test output: %w", err)
+	}
+	if runErr != nil && len(summary.Tests) == 0 {
+		return nil, fmt.Errorf("go test -race failed: %w", runErr)
+	}
+
+	races, err := parseRaces(&events, &output)
+	if err != nil {
+		return nil, fmt.Errorf("error reading go test output: %w", err)
+	}
+	report := &race.Report{Races: races}
+
+	if err := fileutil.WriteFile(filepath.Join(metricsDir, RaceOutputFile), output.Bytes(), 0644); err != nil {
+		return nil, fmt.Errorf("error writing race detector output: %w", err)
+	}
+	raceFile := filepath.Join(metricsDir, race.File)
+	if err := race.Write(raceFile, report); err != nil {
+		return nil, err
+	}
+
+	if len(report.Races) == 0 {
+		i18n.Println("race.none", raceFile)
+		return report, nil
+	}
+	i18n.Println("race.found", len(report.Races), report.Reports())
+	for _, r := range report.Races {
+		l := i18n.Current()
+		line := i18n.T("race.race", r.Package,
+			l.Name("race.op", r.Access.Op), r.Access.Site(), l.Name("race.op", r.Previous.Op), r.Previous.Site())
+		if len(r.Tests) > 0 {
+			line += " (" + strings.Join(r.Tests, ", ") + ")"
+		}
+		fmt.Println(line)
+	}
+	i18n.Println("race.done", raceFile)
+	return report, nil
+}
+
+// parseRaces feeds the output of the go test -json events to the race
+// parser, which attributes each race to the package and test it was printed
+// by, and records the text output
+func parseRaces(events io.Reader, text io.Writer) ([]race.Race, error) {
+	parser := race.NewParser()
+	// A line may be split over several events
+	partial := make(map[string]string)
+
+	reader := bufio.NewReader(events)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if event, ok := gotest.DecodeEvent(line); ok && event.Output != "" {
+			if _, err := io.WriteString(text, event.Output); err != nil {
+				return nil, err
+			}
+			key := event.Package + "\x00" + event.Test
+			out := partial[key] + event.Output
+			for {
+				before, after, found := strings.Cut(out, "\n")
+				if !found {
+					break
+				}
+				parser.Line(event.Package, event.Test, before)
+				out = after
+			}
+			partial[key] = out
+		}
+		if err == io.EOF {
+			return parser.Races(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
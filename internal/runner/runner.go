@@ -7,54 +7,100 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"time"
 
+	"github.com/andro-kes/gokode/internal/fileutil"
+	"github.com/andro-kes/gokode/internal/i18n"
 	"github.com/andro-kes/gokode/internal/tools"
 )
 
-// RunFormat formats code with gofmt
-func RunFormat(ctx context.Context, path string) error {
-	fmt.Println("Formatting code with gofmt...")
-	cmd := exec.CommandContext(ctx, "gofmt", "-w", "-s", ".")
-	cmd.Dir = path
+// interruptGrace is how long a child process gets to exit after being
+// interrupted before it is killed
+const interruptGrace = 10 * time.Second
+
+// command creates a command that is interrupted rather than killed when ctx is
+// cancelled, so child processes can clean up before exiting
+func command(ctx context.Context, dir, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = interruptGrace
+	return cmd
+}
+
+// orAll returns args, or the pattern matching everything if args is empty
+func orAll(args []string, all string) []string {
+	if len(args) == 0 {
+		return []string{all}
+	}
+	return args
+}
+
+// interrupted reports whether ctx was cancelled or timed out, in which case the
+// output of the last command is incomplete and must not be written
+func interrupted(ctx context.Context, tool string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s interrupted: %w", tool, err)
+	}
+	return nil
+}
+
+// RunFormat formats code with gofmt. It formats the given files, or the
+// whole tree if none are given.
+func RunFormat(ctx context.Context, path string, files ...string) error {
+	i18n.Println("format.running")
+	cmd := command(ctx, path, "gofmt", append([]string{"-w", "-s"}, orAll(files, ".")...)...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	if err := cmd.Run(); err != nil {
+		if ctxErr := interrupted(ctx, "gofmt"); ctxErr != nil {
+			return ctxErr
+		}
 		return fmt.Errorf("error running gofmt: %w", err)
 	}
-	fmt.Println("✓ Format complete")
+	i18n.Println("format.done")
 	return nil
 }
 
-// RunVet runs go vet and writes output to a file
-func RunVet(ctx context.Context, path, metricsDir string) error {
-	fmt.Println("Running go vet...")
+// RunVet runs go vet on the given packages, or all packages if none are
+// given, and writes output to a file
+func RunVet(ctx context.Context, path, metricsDir string, pkgs ...string) error {
+	i18n.Println("vet.running")
 	vetFile := filepath.Join(metricsDir, "vet.txt")
 
-	cmd := exec.CommandContext(ctx, "go", "vet", "./...")
-	cmd.Dir = path
+	cmd := command(ctx, path, "go", append([]string{"vet"}, orAll(pkgs, "./...")...)...)
 
 	output, err := cmd.CombinedOutput()
+	if ctxErr := interrupted(ctx, "go vet"); ctxErr != nil {
+		return ctxErr
+	}
 
 	// Write output to file regardless of error
-	if writeErr := os.WriteFile(vetFile, output, 0644); writeErr != nil {
+	if writeErr := fileutil.WriteFile(vetFile, output, 0644); writeErr != nil {
 		return fmt.Errorf("error writing vet output: %w", writeErr)
 	}
 
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "go vet found issues (see %s):\n%s\n", vetFile, string(output))
+		i18n.Fprintln(os.Stderr, "vet.issues", vetFile, string(output))
 		// Don't fail on vet issues, just report them
 	}
 
-	fmt.Printf("✓ Vet complete (output: %s)\n", vetFile)
+	i18n.Println("vet.done", vetFile)
 	return nil
 }
 
-// RunLint runs golangci-lint and writes pretty-printed JSON to a file
-func RunLint(ctx context.Context, path, metricsDir string, fix bool) error {
+// RunLint runs golangci-lint on the given packages, or all packages if none
+// are given, and writes pretty-printed JSON to a file
+func RunLint(ctx context.Context, path, metricsDir string, fix bool, pkgs ...string) error {
 	// Ensure golangci-lint is installed
 	if !tools.IsInstalled("golangci-lint") {
-		fmt.Println("golangci-lint not found, installing...")
+		i18n.Println("tools.missing", "golangci-lint")
 		if err := tools.InstallGolangciLint(); err != nil {
 			return fmt.Errorf("error installing golangci-lint: %w", err)
 		}
@@ -62,18 +108,21 @@ func RunLint(ctx context.Context, path, metricsDir string, fix bool) error {
 
 	reportFile := filepath.Join(metricsDir, "report.json")
 
-	args := []string{"run", "--out-format", "json", "./..."}
+	pkgs = orAll(pkgs, "./...")
+	args := append([]string{"run", "--out-format", "json"}, pkgs...)
 	if fix {
 		args = append(args, "--fix")
-		fmt.Println("Running golangci-lint with --fix...")
+		i18n.Println("lint.runningFix")
 	} else {
-		fmt.Println("Running golangci-lint...")
+		i18n.Println("lint.running")
 	}
 
-	cmd := exec.CommandContext(ctx, "golangci-lint", args...)
-	cmd.Dir = path
+	cmd := command(ctx, path, "golangci-lint", args...)
 
 	output, err := cmd.CombinedOutput()
+	if ctxErr := interrupted(ctx, "golangci-lint"); ctxErr != nil {
+		return ctxErr
+	}
 
 	// Parse and pretty-print the JSON output
 	var jsonData interface{}
@@ -88,7 +137,7 @@ func RunLint(ctx context.Context, path, metricsDir string, fix bool) error {
 	}
 
 	// Write JSON output to file
-	if writeErr := os.WriteFile(reportFile, output, 0644); writeErr != nil {
+	if writeErr := fileutil.WriteFile(reportFile, output, 0644); writeErr != nil {
 		return fmt.Errorf("error writing lint report: %w", writeErr)
 	}
 
@@ -104,10 +153,9 @@ func RunLint(ctx context.Context, path, metricsDir string, fix bool) error {
 		}
 
 		if hasIssues {
-			fmt.Println("Lint issues found:")
+			i18n.Println("lint.issues")
 			// Run again without JSON for console output
-			consoleCmd := exec.CommandContext(ctx, "golangci-lint", "run", "./...")
-			consoleCmd.Dir = path
+			consoleCmd := command(ctx, path, "golangci-lint", append([]string{"run"}, pkgs...)...)
 			consoleCmd.Stdout = os.Stdout
 			consoleCmd.Stderr = os.Stderr
 			_ = consoleCmd.Run() // Ignore error, we already have the JSON
@@ -115,85 +163,45 @@ func RunLint(ctx context.Context, path, metricsDir string, fix bool) error {
 	}
 
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "golangci-lint found issues (see %s)\n", reportFile)
+		i18n.Fprintln(os.Stderr, "lint.failed", reportFile)
 		// Don't fail on lint issues, just report them
 	}
 
-	fmt.Printf("✓ Lint complete (report: %s)\n", reportFile)
+	i18n.Println("lint.done", reportFile)
 	return nil
 }
 
-// RunTests runs go tests
-func RunTests(ctx context.Context, path string) error {
-	fmt.Println("Running tests...")
-	cmd := exec.CommandContext(ctx, "go", "test", "./...", "-v")
-	cmd.Dir = path
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("tests failed: %w", err)
-	}
-	fmt.Println("✓ Tests passed")
-	return nil
-}
-
-// RunCoverage runs tests with coverage and generates HTML report
-func RunCoverage(ctx context.Context, path, metricsDir string) error {
-	fmt.Println("Running tests with coverage...")
-	coverageOut := filepath.Join(metricsDir, "coverage.out")
-	coverageHTML := filepath.Join(metricsDir, "coverage.html")
-
-	// Run tests with coverage
-	cmd := exec.CommandContext(ctx, "go", "test", "./...", "-coverprofile="+coverageOut)
-	cmd.Dir = path
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("coverage tests failed: %w", err)
-	}
-
-	// Generate HTML report
-	cmd = exec.CommandContext(ctx, "go", "tool", "cover", "-html="+coverageOut, "-o", coverageHTML)
-	cmd.Dir = path
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("error generating HTML coverage report: %w", err)
-	}
-
-	fmt.Printf("✓ Coverage complete (profile: %s, HTML: %s)\n", coverageOut, coverageHTML)
-	return nil
-}
-
-// RunGocyclo runs cyclomatic complexity analysis
-func RunGocyclo(ctx context.Context, path, metricsDir string) error {
+// RunGocyclo runs cyclomatic complexity analysis on the given files, or the
+// whole tree if none are given
+func RunGocyclo(ctx context.Context, path, metricsDir string, files ...string) error {
 	// Ensure gocyclo is installed
 	if !tools.IsInstalled("gocyclo") {
-		fmt.Println("gocyclo not found, installing...")
+		i18n.Println("tools.missing", "gocyclo")
 		if err := tools.InstallGocyclo(); err != nil {
 			return fmt.Errorf("error installing gocyclo: %w", err)
 		}
 	}
 
-	fmt.Println("Running cyclomatic complexity analysis...")
+	i18n.Println("gocyclo.running")
 	gocycloFile := filepath.Join(metricsDir, "gocyclo.txt")
 
-	cmd := exec.CommandContext(ctx, "gocyclo", ".")
-	cmd.Dir = path
+	cmd := command(ctx, path, "gocyclo", orAll(files, ".")...)
 
 	output, err := cmd.CombinedOutput()
+	if ctxErr := interrupted(ctx, "gocyclo"); ctxErr != nil {
+		return ctxErr
+	}
 
 	// Write output to file
-	if writeErr := os.WriteFile(gocycloFile, output, 0644); writeErr != nil {
+	if writeErr := fileutil.WriteFile(gocycloFile, output, 0644); writeErr != nil {
 		return fmt.Errorf("error writing gocyclo output: %w", writeErr)
 	}
 
 	if err != nil {
 		// gocyclo returns non-zero if it finds complex functions
-		fmt.Printf("Cyclomatic complexity analysis complete (see %s)\n", gocycloFile)
+		i18n.Println("gocyclo.complex", gocycloFile)
 	} else {
-		fmt.Printf("✓ Cyclomatic complexity analysis complete (output: %s)\n", gocycloFile)
+		i18n.Println("gocyclo.done", gocycloFile)
 	}
 
 	return nil
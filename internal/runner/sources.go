@@ -0,0 +1,60 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/andro-kes/gokode/internal/depgraph"
+	"github.com/andro-kes/gokode/internal/i18n"
+	"github.com/andro-kes/gokode/internal/source"
+)
+
+// RunSources records the Go files of the packages under path, with their
+// names in cover profiles, in sources.json for the report's source viewer
+func RunSources(ctx context.Context, path, metricsDir string) error {
+	i18n.Println("sources.running")
+	graph, err := depgraph.Load(ctx, path)
+	if err != nil {
+		if ctxErr := interrupted(ctx, "go list"); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+
+	snapshot := &source.Snapshot{}
+	for _, pkg := range graph.Packages {
+		// Only the non-test files are instrumented for coverage
+		lists := []struct {
+			names    []string
+			profiled bool
+		}{{pkg.GoFiles, true}, {pkg.TestGoFiles, false}, {pkg.XTestGoFiles, false}}
+		for _, list := range lists {
+			for _, name := range list.names {
+				rel, ok := relPath(path, filepath.Join(pkg.Dir, name))
+				if !ok {
+					continue
+				}
+				text, err := os.ReadFile(filepath.Join(pkg.Dir, name))
+				if err != nil {
+					return fmt.Errorf("error reading %s: %w", rel, err)
+				}
+				file := source.Source{Path: rel, Package: pkg.ImportPath, Text: string(text)}
+				if list.profiled {
+					file.Profile = pkg.ImportPath + "/" + name
+				}
+				snapshot.Files = append(snapshot.Files, file)
+			}
+		}
+	}
+	sort.Slice(snapshot.Files, func(i, j int) bool { return snapshot.Files[i].Path < snapshot.Files[j].Path })
+
+	sourcesFile := filepath.Join(metricsDir, source.File)
+	if err := source.Write(sourcesFile, snapshot); err != nil {
+		return err
+	}
+	i18n.Println("sources.done", len(snapshot.Files), sourcesFile)
+	return nil
+}
@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/i18n"
+	"github.com/andro-kes/gokode/internal/testquality"
+)
+
+// RunTestQuality analyses the test files among the given files, relative to
+// path, or all test files if none are given, for tests that cannot fail,
+// skips that hide failures, ignored errors and process state that is not
+// restored, and writes the result to test_quality.json
+func RunTestQuality(path, metricsDir string, files ...string) error {
+	i18n.Println("testquality.running")
+	names, err := testFiles(path, files)
+	if err != nil {
+		return err
+	}
+
+	sources := make([]testquality.Source, 0, len(names))
+	for _, name := range names {
+		src, err := os.ReadFile(filepath.Join(path, name))
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", name, err)
+		}
+		sources = append(sources, testquality.Source{Name: name, Src: src})
+	}
+	report, err := testquality.Analyze(sources)
+	if err != nil {
+		return err
+	}
+
+	qualityFile := filepath.Join(metricsDir, testquality.File)
+	if err := testquality.Write(qualityFile, report); err != nil {
+		return err
+	}
+
+	for _, issue := range report.Issues {
+		i18n.Println("testquality.issue", issue.File, issue.Line, issue.Func, issue.Message, i18n.Current().Name("testquality.kind", issue.Kind))
+	}
+	i18n.Println("testquality.done",
+		report.Tests, report.Files, len(report.Issues), qualityFile)
+	return nil
+}
+
+// testFiles returns the _test.go files among files, or all test files under
+// path if files is empty, relative to path. Like the go tool it skips
+// testdata, vendor, nested modules and directories starting with . or _.
+func testFiles(path string, files []string) ([]string, error) {
+	if len(files) > 0 {
+		var tests []string
+		for _, file := range files {
+			if strings.HasSuffix(file, "_test.go") {
+				tests = append(tests, file)
+			}
+		}
+		return tests, nil
+	}
+
+	var tests []string
+	err := filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			if file == path {
+				return nil
+			}
+			if name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			// Nested modules are separate projects
+			if _, err := os.Stat(filepath.Join(file, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(name, "_test.go") {
+			rel, err := filepath.Rel(path, file)
+			if err != nil {
+				return err
+			}
+			tests = append(tests, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error finding test files: %w", err)
+	}
+	return tests, nil
+}
@@ -0,0 +1,179 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/coverage"
+	"github.com/andro-kes/gokode/internal/fileutil"
+	"github.com/andro-kes/gokode/internal/flaky"
+	"github.com/andro-kes/gokode/internal/gotest"
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// Test suite artifacts in the metrics directory
+const (
+	TestOutputFile   = "test.txt"
+	TestEventsFile   = "test.json"
+	CoverageFile     = "coverage.out"
+	CoverageHTMLFile = "coverage.html"
+)
+
+// RunTestSuite runs the tests of the given packages, or all packages if none
+// are given, once with go test -json -coverprofile. The run yields the test
+// events, the verbose test output, the coverage profile and the coverage
+// HTML report, which are written even when tests fail. Generated files, files
+// matching the exclude globs and functions annotated with //gokode:nocover are
+// left out of the profile; the original is kept in coverage_raw.out and both
+// totals in coverage_exclusions.json. Files of the project
+// created, modified or deleted by the tests are reported in
+// tree_changes.json. Failures of known flaky tests alone don't fail the run.
+func RunTestSuite(ctx context.Context, path, metricsDir string, knownFlaky []flaky.Known, exclude []string, pkgs ...string) error {
+	return runTestSuite(ctx, path, metricsDir, knownFlaky, exclude, "", pkgs)
+}
+
+// runTestSuite is RunTestSuite that also keeps the raw coverage counters of
+// the test binaries in coverDir unless it is empty
+func runTestSuite(ctx context.Context, path, metricsDir string, knownFlaky []flaky.Known, exclude []string, coverDir string, pkgs []string) error {
+	i18n.Println("tests.running")
+	testFile := filepath.Join(metricsDir, TestOutputFile)
+	eventsFile := filepath.Join(metricsDir, TestEventsFile)
+	coverageOut := filepath.Join(metricsDir, CoverageFile)
+	coverageHTML := filepath.Join(metricsDir, CoverageHTMLFile)
+	rawCoverage := filepath.Join(metricsDir, RawCoverageFile)
+	exclusionsFile := filepath.Join(metricsDir, coverage.ExclusionsFileName)
+
+	// Tools write to temporary files first so an interrupted run never
+	// leaves a truncated profile behind
+	coverageOutTmp := fileutil.TempName(coverageOut)
+	coverageHTMLTmp := fileutil.TempName(coverageHTML)
+	defer os.Remove(coverageOutTmp)
+	defer os.Remove(coverageHTMLTmp)
+
+	before := snapshotTree(ctx, path, metricsDir)
+
+	args := append([]string{"test", "-json", "-coverprofile=" + coverageOutTmp}, orAll(pkgs, "./...")...)
+	if coverDir != "" {
+		args = append(args, "-args", "-test.gocoverdir="+coverDir)
+	}
+	cmd := command(ctx, path, "go", args...)
+	var output, events bytes.Buffer
+	cmd.Stderr = io.MultiWriter(os.Stderr, &output)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("error running go test: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error running go test: %w", err)
+	}
+	// The events are shown as the familiar verbose output while they are
+	// recorded
+	copyErr := copyEvents(stdout, &events, io.MultiWriter(os.Stdout, &output))
+	testErr := cmd.Wait()
+	if ctxErr := interrupted(ctx, "go test"); ctxErr != nil {
+		return ctxErr
+	}
+	if copyErr != nil {
+		return fmt.Errorf("error reading go test output: %w", copyErr)
+	}
+	if output.Len() > 0 && !bytes.HasSuffix(output.Bytes(), []byte("\n")) {
+		fmt.Println()
+	}
+
+	// Write results regardless of test failures
+	if err := fileutil.WriteFile(testFile, output.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing test output: %w", err)
+	}
+	if err := fileutil.WriteFile(eventsFile, events.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing test events: %w", err)
+	}
+	if before != nil {
+		if err := checkTree(ctx, path, metricsDir, before, events.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if info, err := os.Stat(coverageOutTmp); err != nil || info.Size() == 0 {
+		// Without a profile, e.g. after a build failure, the previous run's
+		// coverage would be mistaken for this one's
+		removeStale(coverageOut, coverageHTML, rawCoverage, exclusionsFile)
+	} else {
+		if err := excludeCoverage(ctx, path, coverageOutTmp, rawCoverage, exclusionsFile, exclude); err != nil {
+			return err
+		}
+		cmd := command(ctx, path, "go", "tool", "cover", "-html="+coverageOutTmp, "-o", coverageHTMLTmp)
+		if err := cmd.Run(); err != nil {
+			if ctxErr := interrupted(ctx, "go tool cover"); ctxErr != nil {
+				return ctxErr
+			}
+			return fmt.Errorf("error generating HTML coverage report: %w", err)
+		}
+		if err := fileutil.Commit(coverageOutTmp, coverageOut); err != nil {
+			return fmt.Errorf("error writing coverage profile: %w", err)
+		}
+		if err := fileutil.Commit(coverageHTMLTmp, coverageHTML); err != nil {
+			return fmt.Errorf("error writing HTML coverage report: %w", err)
+		}
+	}
+
+	if testErr != nil {
+		summary, err := gotest.ParseJSON(bytes.NewReader(events.Bytes()))
+		if err != nil || len(knownFlaky) == 0 {
+			return fmt.Errorf("tests failed: %w", testErr)
+		}
+		quarantined, ok := flaky.Quarantine(summary, knownFlaky)
+		if !ok {
+			return fmt.Errorf("tests failed: %w", testErr)
+		}
+		names := make([]string, len(quarantined))
+		for i, test := range quarantined {
+			names[i] = test.Package + " " + test.Name
+		}
+		i18n.Println("tests.knownFlaky", strings.Join(names, ", "))
+	}
+	i18n.Println("tests.done", testFile, coverageOut, coverageHTML)
+	return nil
+}
+
+// copyEvents records go test -json output in events and writes the text
+// output of each event to text. Lines that are not events are passed through.
+func copyEvents(r io.Reader, events, text io.Writer) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if event, ok := gotest.DecodeEvent(line); ok {
+				if _, err := events.Write(line); err != nil {
+					return err
+				}
+				if _, err := io.WriteString(text, event.Output); err != nil {
+					return err
+				}
+			} else if _, err := text.Write(line); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// removeStale removes artifacts of an earlier run
+func removeStale(names ...string) {
+	for _, name := range names {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			i18n.Fprintln(os.Stderr, "cli.warning", err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/depgraph"
+	"github.com/andro-kes/gokode/internal/gotest"
+	"github.com/andro-kes/gokode/internal/i18n"
+	"github.com/andro-kes/gokode/internal/rundir"
+	"github.com/andro-kes/gokode/internal/snapshot"
+)
+
+// snapshotTree records the files of the project before the tests run. The
+// metrics written meanwhile are left out. On failure it warns and returns
+// nil, and the tree is not checked.
+func snapshotTree(ctx context.Context, path, metricsDir string) snapshot.Snapshot {
+	snap, err := snapshot.Take(ctx, path, outputRoot(metricsDir))
+	if err != nil {
+		i18n.Fprintln(os.Stderr, "treechanges.disabled", err)
+		return nil
+	}
+	return snap
+}
+
+// outputRoot returns the directory holding the runs when metricsDir is a
+// per-run directory, or metricsDir itself
+func outputRoot(metricsDir string) string {
+	parent := filepath.Dir(metricsDir)
+	if _, err := rundir.Latest(parent); err == nil {
+		return parent
+	}
+	return metricsDir
+}
+
+// checkTree compares the project with the snapshot taken before the tests,
+// attributes each created, modified or deleted file to the package whose
+// tests changed it where possible and writes the result to
+// tree_changes.json
+func checkTree(ctx context.Context, path, metricsDir string, before snapshot.Snapshot, events []byte) error {
+	after, err := snapshot.Take(ctx, path, outputRoot(metricsDir))
+	if err != nil {
+		if ctxErr := interrupted(ctx, "git ls-files"); ctxErr != nil {
+			return ctxErr
+		}
+		return fmt.Errorf("error checking files changed by tests: %w", err)
+	}
+
+	report := &snapshot.Report{Changes: snapshot.Diff(before, after)}
+	if len(report.Changes) > 0 {
+		snapshot.Attribute(report.Changes, testRuns(ctx, path, events))
+	}
+
+	changesFile := filepath.Join(metricsDir, snapshot.File)
+	if err := snapshot.Write(changesFile, report); err != nil {
+		return err
+	}
+	if len(report.Changes) == 0 {
+		return nil
+	}
+
+	i18n.Println("treechanges.changed", len(report.Changes), changesFile)
+	for _, change := range report.Changes {
+		kind := i18n.Current().Name("change", change.Kind)
+		if change.Package != "" {
+			i18n.Println("treechanges.changePackage", kind, change.Path, change.Package)
+		} else {
+			i18n.Println("treechanges.change", kind, change.Path)
+		}
+	}
+	return nil
+}
+
+// testRuns returns when the test binary of each package ran according to
+// its test events, with the package directories from go list
+func testRuns(ctx context.Context, path string, events []byte) []snapshot.Run {
+	graph, err := depgraph.Load(ctx, path)
+	if err != nil {
+		i18n.Fprintln(os.Stderr, "cli.warning", err)
+	}
+	return eventRuns(events, graph)
+}
+
+// eventRuns returns the runs of the packages whose test binary ran, that is
+// with a run, pass or fail event. Packages without test files only get a
+// skip and must not claim the files under their directory. graph may be nil.
+func eventRuns(events []byte, graph *depgraph.Graph) []snapshot.Run {
+	var runs []snapshot.Run
+	index := make(map[string]int)
+	ran := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(events))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		event, ok := gotest.DecodeEvent(scanner.Bytes())
+		if !ok || event.Package == "" || event.Time.IsZero() {
+			continue
+		}
+		switch event.Action {
+		case "run", "pass", "fail":
+			ran[event.Package] = true
+		}
+		i, ok := index[event.Package]
+		if !ok {
+			i = len(runs)
+			index[event.Package] = i
+			run := snapshot.Run{Package: event.Package, Start: event.Time}
+			if graph != nil {
+				run.Dir = strings.TrimPrefix(graph.RelDir(event.Package), "./")
+			}
+			runs = append(runs, run)
+		}
+		runs[i].End = event.Time
+	}
+
+	var tested []snapshot.Run
+	for _, run := range runs {
+		if ran[run.Package] {
+			tested = append(tested, run)
+		}
+	}
+	return tested
+}
@@ -0,0 +1,37 @@
+package runner
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/andro-kes/gokode/internal/depgraph"
+	"github.com/andro-kes/gokode/internal/snapshot"
+)
+
+const nestedEvents = `{"Time":"2026-01-01T10:00:00Z","Action":"start","Package":"example.com/m/worker/scanner"}
+{"Time":"2026-01-01T10:00:01Z","Action":"run","Package":"example.com/m/worker/scanner","Test":"TestScan"}
+{"Time":"2026-01-01T10:00:02Z","Action":"pass","Package":"example.com/m/worker/scanner","Test":"TestScan","Elapsed":1}
+{"Time":"2026-01-01T10:00:02Z","Action":"pass","Package":"example.com/m/worker/scanner","Elapsed":2}
+{"Time":"2026-01-01T10:00:00Z","Action":"start","Package":"example.com/m/worker/scanner/test"}
+{"Time":"2026-01-01T10:00:00Z","Action":"output","Package":"example.com/m/worker/scanner/test","Output":"?   \texample.com/m/worker/scanner/test\t[no test files]\n"}
+{"Time":"2026-01-01T10:00:00Z","Action":"skip","Package":"example.com/m/worker/scanner/test","Elapsed":0}
+`
+
+func TestEventRunsSkipsPackagesWithoutTests(t *testing.T) {
+	root := filepath.FromSlash("/project")
+	graph := &depgraph.Graph{Root: root, Packages: map[string]*depgraph.Package{
+		"example.com/m/worker/scanner":      {ImportPath: "example.com/m/worker/scanner", Dir: filepath.Join(root, "worker", "scanner")},
+		"example.com/m/worker/scanner/test": {ImportPath: "example.com/m/worker/scanner/test", Dir: filepath.Join(root, "worker", "scanner", "test")},
+	}}
+
+	runs := eventRuns([]byte(nestedEvents), graph)
+	if len(runs) != 1 || runs[0].Package != "example.com/m/worker/scanner" || runs[0].Dir != "worker/scanner" {
+		t.Fatalf("Expected only the scanner package to have run, got %+v", runs)
+	}
+
+	changes := []snapshot.Change{{Path: "worker/scanner/test/report.json", Kind: snapshot.Modified}}
+	snapshot.Attribute(changes, runs)
+	if changes[0].Package != "example.com/m/worker/scanner" {
+		t.Errorf("Expected the change to be attributed to the scanner tests, got %q", changes[0].Package)
+	}
+}
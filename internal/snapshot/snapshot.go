@@ -0,0 +1,271 @@
+package snapshot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andro-kes/gokode/internal/fileutil"
+	"github.com/andro-kes/gokode/internal/git"
+)
+
+// File is the name of the report of files changed by the tests in the
+// metrics directory
+const File = "tree_changes.json"
+
+// Kinds of changes to a file
+const (
+	Created  = "created"
+	Modified = "modified"
+	Deleted  = "deleted"
+)
+
+// Entry is the state of a file when the snapshot was taken
+type Entry struct {
+	Size    int64
+	Hash    string
+	ModTime time.Time
+}
+
+// Snapshot is the state of the files of a tree, keyed by path relative to
+// the tree root with forward slashes
+type Snapshot map[string]Entry
+
+// Take records the size and SHA-256 hash of the files under root. In a git
+// repository these are the tracked and untracked files git does not ignore;
+// elsewhere all files outside directories starting with a dot. Files in the
+// exclude directories are left out.
+func Take(ctx context.Context, root string, exclude ...string) (Snapshot, error) {
+	var skip []string
+	for _, dir := range exclude {
+		if rel, err := filepath.Rel(root, dir); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			skip = append(skip, filepath.ToSlash(rel)+"/")
+		}
+	}
+
+	names, err := git.ListFiles(ctx, root)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if names, err = walk(root); err != nil {
+			return nil, err
+		}
+	}
+
+	snap := make(Snapshot, len(names))
+	for _, name := range names {
+		if excluded(name, skip) {
+			continue
+		}
+		entry, ok, err := hashFile(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			snap[name] = entry
+		}
+	}
+	return snap, nil
+}
+
+// walk lists the regular files under root outside directories starting with
+// a dot
+func walk(root string) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if file != root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing files: %w", err)
+	}
+	return names, nil
+}
+
+func excluded(name string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(name, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashFile returns the entry of a regular file. Missing files and other
+// kinds of files, e.g. symlinks, are reported as not ok.
+func hashFile(name string) (Entry, bool, error) {
+	info, err := os.Lstat(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Entry{}, false, nil
+		}
+		return Entry{}, false, fmt.Errorf("error reading %s: %w", name, err)
+	}
+	if !info.Mode().IsRegular() {
+		return Entry{}, false, nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		// The file may be removed between listing and hashing
+		if os.IsNotExist(err) {
+			return Entry{}, false, nil
+		}
+		return Entry{}, false, fmt.Errorf("error reading %s: %w", name, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return Entry{}, false, fmt.Errorf("error reading %s: %w", name, err)
+	}
+	return Entry{Size: info.Size(), Hash: hex.EncodeToString(h.Sum(nil)), ModTime: info.ModTime()}, true, nil
+}
+
+// Change is a file created, modified or deleted between two snapshots
+type Change struct {
+	// Path is relative to the project with forward slashes
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	// Package is the import path of the package whose tests made the change,
+	// if it could be worked out
+	Package string `json:"package,omitempty"`
+	// Size is the size of the file after the change, or before it for
+	// deleted files
+	Size int64 `json:"size"`
+	// ModTime is when a created or modified file was last written
+	ModTime time.Time `json:"-"`
+}
+
+// Diff returns the files created, modified and deleted between before and
+// after, sorted by path
+func Diff(before, after Snapshot) []Change {
+	var changes []Change
+	for name, entry := range after {
+		old, ok := before[name]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Path: name, Kind: Created, Size: entry.Size, ModTime: entry.ModTime})
+		case old.Hash != entry.Hash:
+			changes = append(changes, Change{Path: name, Kind: Modified, Size: entry.Size, ModTime: entry.ModTime})
+		}
+	}
+	for name, entry := range before {
+		if _, ok := after[name]; !ok {
+			changes = append(changes, Change{Path: name, Kind: Deleted, Size: entry.Size})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// Run is a test binary of a package and when it ran
+type Run struct {
+	Package string
+	// Dir is the package directory relative to the project with forward
+	// slashes, "." for the root
+	Dir        string
+	Start, End time.Time
+}
+
+// Attribute sets the package of each change. Test binaries run in their
+// package directory, so a file under the directory of a tested package is
+// attributed to the package with the closest directory. Other created or
+// modified files are attributed to the package whose tests were the only
+// ones running when the file was last written. runs should only hold
+// packages whose tests ran; a package without test files never writes
+// anything.
+func Attribute(changes []Change, runs []Run) {
+	for i := range changes {
+		change := &changes[i]
+		best := -1
+		for j, run := range runs {
+			if contains(run.Dir, change.Path) && (best < 0 || depth(run.Dir) > depth(runs[best].Dir)) {
+				best = j
+			}
+		}
+		if best >= 0 {
+			change.Package = runs[best].Package
+			continue
+		}
+		if change.ModTime.IsZero() {
+			continue
+		}
+		running := ""
+		for _, run := range runs {
+			if change.ModTime.Before(run.Start) || change.ModTime.After(run.End) {
+				continue
+			}
+			if running != "" {
+				running = ""
+				break
+			}
+			running = run.Package
+		}
+		change.Package = running
+	}
+}
+
+// depth returns the number of path elements of dir
+func depth(dir string) int {
+	if dir == "." {
+		return 0
+	}
+	return strings.Count(dir, "/") + 1
+}
+
+// contains reports whether the file is under dir
+func contains(dir, file string) bool {
+	return dir == "." || strings.HasPrefix(file, dir+"/")
+}
+
+// Report is the result of comparing the tree before and after the tests
+type Report struct {
+	Changes []Change `json:"changes"`
+}
+
+// Count returns the number of changes of a kind
+func (r *Report) Count(kind string) int {
+	n := 0
+	for _, change := range r.Changes {
+		if change.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// Write atomically writes the report as JSON
+func Write(name string, report *Report) error {
+	return fileutil.WriteJSON(name, report, "tree changes")
+}
+
+// Read reads a report written by Write
+func Read(name string) (*Report, error) {
+	var report Report
+	if err := fileutil.ReadJSON(name, &report, "tree changes"); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,107 @@
+package snapshot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTakeAndDiff(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.mod"), "module example.com/m\n")
+	writeFile(t, filepath.Join(root, "scanner", "test", "report.json"), "{}")
+	writeFile(t, filepath.Join(root, "jsoner", "test", "test.json"), "[]")
+	writeFile(t, filepath.Join(root, ".hidden", "state"), "a")
+	writeFile(t, filepath.Join(root, "metrics", "test.json"), "a")
+
+	ctx := context.Background()
+	before, err := Take(ctx, root, filepath.Join(root, "metrics"))
+	if err != nil {
+		t.Fatalf("Take failed: %v", err)
+	}
+	if len(before) != 3 {
+		t.Fatalf("Take recorded %d files, want 3: %v", len(before), before)
+	}
+	if entry := before["scanner/test/report.json"]; entry.Size != 2 || len(entry.Hash) != 64 {
+		t.Errorf("entry = %+v, want size 2 and a SHA-256 hash", entry)
+	}
+
+	writeFile(t, filepath.Join(root, "scanner", "test", "report.json"), `{"a":1}`)
+	if err := os.Remove(filepath.Join(root, "jsoner", "test", "test.json")); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(root, "out.txt"), "new")
+	writeFile(t, filepath.Join(root, ".hidden", "state"), "b")
+	writeFile(t, filepath.Join(root, "metrics", "test.json"), "b")
+
+	after, err := Take(ctx, root, filepath.Join(root, "metrics"))
+	if err != nil {
+		t.Fatalf("Take failed: %v", err)
+	}
+	changes := Diff(before, after)
+	for i := range changes {
+		changes[i].ModTime = time.Time{}
+	}
+	want := []Change{
+		{Path: "jsoner/test/test.json", Kind: Deleted, Size: 2},
+		{Path: "out.txt", Kind: Created, Size: 3},
+		{Path: "scanner/test/report.json", Kind: Modified, Size: 7},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Diff = %+v, want %+v", changes, want)
+	}
+}
+
+func TestAttribute(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	runs := []Run{
+		{Package: "example.com/m/worker", Dir: "worker", Start: start, End: start.Add(time.Second)},
+		{Package: "example.com/m/worker/scanner", Dir: "worker/scanner", Start: start.Add(2 * time.Second), End: start.Add(3 * time.Second)},
+		{Package: "example.com/m/worker/jsoner", Dir: "worker/jsoner", Start: start.Add(2 * time.Second), End: start.Add(4 * time.Second)},
+	}
+	changes := []Change{
+		{Path: "worker/scanner/test/report.json", Kind: Modified, ModTime: start.Add(10 * time.Second)},
+		{Path: "worker/out.txt", Kind: Created},
+		{Path: "fixtures/a.json", Kind: Modified, ModTime: start.Add(500 * time.Millisecond)},
+		{Path: "fixtures/b.json", Kind: Modified, ModTime: start.Add(2500 * time.Millisecond)},
+		{Path: "fixtures/c.json", Kind: Deleted},
+	}
+	Attribute(changes, runs)
+
+	want := []string{
+		"example.com/m/worker/scanner",
+		"example.com/m/worker",
+		"example.com/m/worker",
+		// Two packages were running
+		"",
+		"",
+	}
+	for i, change := range changes {
+		if change.Package != want[i] {
+			t.Errorf("%s: package = %q, want %q", change.Path, change.Package, want[i])
+		}
+	}
+}
+
+func TestCount(t *testing.T) {
+	report := &Report{Changes: []Change{
+		{Path: "worker/scanner/test/report.json", Kind: Modified, Package: "example.com/m/worker/scanner", Size: 10},
+		{Path: "out.txt", Kind: Deleted, Size: 3},
+	}}
+	if n := report.Count(Modified); n != 1 {
+		t.Errorf("Count(Modified) = %d, want 1", n)
+	}
+}
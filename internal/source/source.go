@@ -0,0 +1,40 @@
+package source
+
+import (
+	"github.com/andro-kes/gokode/internal/fileutil"
+)
+
+// File is the name of the source snapshot in the metrics directory
+const File = "sources.json"
+
+// Source is a Go file of the project as it was when it was analysed
+type Source struct {
+	// Path is relative to the project with forward slashes
+	Path string `json:"path"`
+	// Package is the import path of the file's package
+	Package string `json:"package"`
+	// Profile is the file name in cover profiles, empty for test files
+	Profile string `json:"profile,omitempty"`
+	Text    string `json:"text"`
+}
+
+// Snapshot is the Go files of a project, sorted by path. The report renders
+// them with their coverage and diagnostics, so it doesn't depend on the
+// project directory still being there.
+type Snapshot struct {
+	Files []Source `json:"files"`
+}
+
+// Write atomically writes the snapshot as JSON
+func Write(name string, snapshot *Snapshot) error {
+	return fileutil.WriteJSON(name, snapshot, "source snapshot")
+}
+
+// Read reads a snapshot written by Write
+func Read(name string) (*Snapshot, error) {
+	var snapshot Snapshot
+	if err := fileutil.ReadJSON(name, &snapshot, "source snapshot"); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
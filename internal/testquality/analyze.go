@@ -0,0 +1,538 @@
+package testquality
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/andro-kes/gokode/internal/i18n"
+)
+
+// maxSnippet limits the length of the expressions quoted in messages
+const maxSnippet = 60
+
+// failMethods are the methods of testing.TB that fail a test
+var failMethods = map[string]bool{"Error": true, "Errorf": true, "Fatal": true, "Fatalf": true, "Fail": true, "FailNow": true}
+
+// skipMethods are the methods of testing.TB that skip a test
+var skipMethods = map[string]bool{"Skip": true, "Skipf": true, "SkipNow": true}
+
+// errorFuncs are functions whose last result is an error, by import path.
+// Without type information they are the only calls known to return one.
+var errorFuncs = map[string]map[string]bool{
+	"os": {
+		"Chdir": true, "Setenv": true, "Unsetenv": true, "Remove": true, "RemoveAll": true,
+		"Mkdir": true, "MkdirAll": true, "WriteFile": true, "Chmod": true, "Chtimes": true,
+		"Rename": true, "Symlink": true, "Link": true, "Truncate": true,
+		"Open": true, "OpenFile": true, "Create": true, "ReadFile": true, "ReadDir": true,
+		"Stat": true, "Lstat": true, "Getwd": true, "MkdirTemp": true, "CreateTemp": true,
+	},
+	"encoding/json": {"Unmarshal": true, "Marshal": true, "MarshalIndent": true},
+	"strconv": {
+		"Atoi": true, "ParseInt": true, "ParseUint": true, "ParseFloat": true,
+		"ParseBool": true, "Unquote": true,
+	},
+	"io":            {"ReadAll": true, "Copy": true},
+	"path/filepath": {"Abs": true, "Rel": true, "Glob": true, "EvalSymlinks": true},
+	"time":          {"Parse": true, "ParseDuration": true},
+	"net/url":       {"Parse": true},
+}
+
+// stateFuncs change the working directory or environment of the process,
+// with the testing method that undoes the change when the test ends
+var stateFuncs = map[string]string{"Chdir": "t.Chdir", "Setenv": "t.Setenv", "Unsetenv": "t.Setenv", "Clearenv": "t.Setenv"}
+
+// environment are the calls and variables whose checks make a skip
+// legitimate, e.g. if testing.Short() or runtime.GOOS == "windows"
+var environment = map[string]map[string]bool{
+	"runtime": nil,
+	"testing": {"Short": true, "Verbose": true},
+	"os":      {"Getenv": true, "LookupEnv": true, "Getuid": true, "Geteuid": true},
+	"os/exec": {"LookPath": true},
+}
+
+// Source is a test file to analyse
+type Source struct {
+	// Name is the file name recorded in issues, relative to the project
+	Name string
+	Src  []byte
+}
+
+// Analyze checks the test files of a project. Files of the same package are
+// analysed together, so helpers declared in one file are followed from the
+// others.
+func Analyze(sources []Source) (*Report, error) {
+	report := &Report{}
+	packages := make(map[string]*pkg)
+	var order []string
+
+	for _, source := range sources {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, source.Name, source.Src, 0)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", source.Name, err)
+		}
+		report.Files++
+
+		key := filepath.Dir(source.Name) + "\x00" + file.Name.Name
+		p, ok := packages[key]
+		if !ok {
+			p = &pkg{helpers: make(map[string]*funcInfo), canFail: make(map[*ast.FuncDecl]failState)}
+			packages[key] = p
+			order = append(order, key)
+		}
+		f := &fileInfo{name: source.Name, fset: fset, imports: imports(file)}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			info := &funcInfo{decl: fn, file: f}
+			p.funcs = append(p.funcs, info)
+			if fn.Recv == nil {
+				p.helpers[fn.Name.Name] = info
+			}
+		}
+	}
+
+	for _, key := range order {
+		p := packages[key]
+		for _, fn := range p.funcs {
+			if isTest(fn) {
+				report.Tests++
+				if !p.fails(fn) {
+					report.Issues = append(report.Issues, fn.issue(fn.decl.Name, KindNoFailure,
+						i18n.T("testquality.noFailure")))
+				}
+			}
+			if len(fn.testingNames()) > 0 {
+				report.Issues = append(report.Issues, fn.check()...)
+			}
+		}
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return report, nil
+}
+
+// pkg holds the functions declared in the test files of a package
+type pkg struct {
+	funcs []*funcInfo
+	// helpers are the package-level functions by name
+	helpers map[string]*funcInfo
+	canFail map[*ast.FuncDecl]failState
+}
+
+type failState int
+
+const (
+	failUnknown failState = iota
+	failVisiting
+	failYes
+	failNo
+)
+
+// fails reports whether fn has a failure path: a call to a failing testing
+// method or panic, a testing value passed to a function outside the test
+// files, e.g. an assertion library, or to a helper that fails
+func (p *pkg) fails(fn *funcInfo) bool {
+	switch p.canFail[fn.decl] {
+	case failYes:
+		return true
+	case failNo, failVisiting:
+		// A recursive helper fails only if another path does
+		return false
+	}
+	p.canFail[fn.decl] = failVisiting
+
+	names := fn.testingNames()
+	found := false
+	ast.Inspect(fn.decl.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || found {
+			return !found
+		}
+		if ident, ok := call.Fun.(*ast.Ident); ok && ident.Name == "panic" {
+			found = true
+			return false
+		}
+		if method, ok := methodOn(call, names); ok && failMethods[method] {
+			found = true
+			return false
+		}
+		for _, arg := range call.Args {
+			ident, ok := arg.(*ast.Ident)
+			if !ok {
+				continue
+			}
+			switch {
+			case p.helpers[ident.Name] != nil:
+				// A helper passed as a function, e.g. t.Run("case", testCase)
+				found = p.fails(p.helpers[ident.Name])
+			case names[ident.Name]:
+				if callee, ok := call.Fun.(*ast.Ident); ok && p.helpers[callee.Name] != nil {
+					found = p.fails(p.helpers[callee.Name])
+				} else {
+					found = true
+				}
+			}
+			if found {
+				return false
+			}
+		}
+		return true
+	})
+
+	if found {
+		p.canFail[fn.decl] = failYes
+	} else {
+		p.canFail[fn.decl] = failNo
+	}
+	return found
+}
+
+// fileInfo is a parsed test file
+type fileInfo struct {
+	name string
+	fset *token.FileSet
+	// imports maps the names imports are referred to by to their paths
+	imports map[string]string
+}
+
+// funcInfo is a function declared in a test file
+type funcInfo struct {
+	decl *ast.FuncDecl
+	file *fileInfo
+}
+
+// isTest reports whether fn is a test function run by go test
+func isTest(fn *funcInfo) bool {
+	name := fn.decl.Name.Name
+	if fn.decl.Recv != nil || !strings.HasPrefix(name, "Test") {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(name[len("Test"):]); unicode.IsLower(r) {
+		return false
+	}
+	params := fn.decl.Type.Params.List
+	return len(params) == 1 && len(params[0].Names) <= 1 && fn.isTesting(params[0].Type, "T")
+}
+
+// testingNames returns the names of the testing parameters of fn and of the
+// function literals in it, e.g. the t of subtests
+func (fn *funcInfo) testingNames() map[string]bool {
+	names := make(map[string]bool)
+	add := func(params *ast.FieldList) {
+		for _, field := range params.List {
+			if fn.isTesting(field.Type, "T", "B", "F", "TB", "M") {
+				for _, name := range field.Names {
+					names[name.Name] = true
+				}
+			}
+		}
+	}
+	add(fn.decl.Type.Params)
+	ast.Inspect(fn.decl.Body, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.FuncLit); ok {
+			add(lit.Type.Params)
+		}
+		return true
+	})
+	return names
+}
+
+// isTesting reports whether typ is one of the given types of the testing
+// package, as a pointer except for the TB interface
+func (fn *funcInfo) isTesting(typ ast.Expr, names ...string) bool {
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	pkgName, name, ok := fn.qualified(typ)
+	if !ok || pkgName != "testing" {
+		return false
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// qualified resolves a pkg.Name expression to the import path and name
+func (fn *funcInfo) qualified(expr ast.Expr) (string, string, bool) {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return "", "", false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", "", false
+	}
+	path, ok := fn.file.imports[ident.Name]
+	return path, sel.Sel.Name, ok
+}
+
+// check finds the issues in a function that uses the testing package
+func (fn *funcInfo) check() []Issue {
+	var issues []Issue
+	names := fn.testingNames()
+	restored := fn.restoresState(names)
+
+	ast.Inspect(fn.decl.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IfStmt:
+			issues = append(issues, fn.checkSkip(n, names)...)
+		case *ast.ExprStmt:
+			call, ok := n.X.(*ast.CallExpr)
+			if !ok {
+				break
+			}
+			if path, name, ok := fn.qualified(call.Fun); ok && errorFuncs[path][name] {
+				issues = append(issues, fn.issue(call, KindIgnoredError,
+					i18n.T("testquality.ignoredError", types.ExprString(call.Fun))))
+			}
+		case *ast.AssignStmt:
+			if len(n.Lhs) < 2 || len(n.Rhs) != 1 {
+				break
+			}
+			call, ok := n.Rhs[0].(*ast.CallExpr)
+			if !ok {
+				break
+			}
+			// The blank result is only an error for calls known to return one;
+			// others end in a bool or a value, e.g. v, _ := m[k] or Lookup
+			if path, name, ok := fn.qualified(call.Fun); !ok || !errorFuncs[path][name] {
+				break
+			}
+			if last, ok := n.Lhs[len(n.Lhs)-1].(*ast.Ident); ok && last.Name == "_" {
+				issues = append(issues, fn.issue(last, KindIgnoredError,
+					i18n.T("testquality.ignoredError", types.ExprString(call.Fun))))
+			}
+		case *ast.DeferStmt:
+			// Deferred calls restore state rather than change it
+			return false
+		case *ast.CallExpr:
+			if method, ok := methodOn(n, names); ok && method == "Cleanup" {
+				return false
+			}
+			path, name, ok := fn.qualified(n.Fun)
+			if ok && path == "os" && stateFuncs[name] != "" && !restored {
+				issues = append(issues, fn.issue(n, KindProcessState,
+					i18n.T("testquality.processState", name, stateFuncs[name])))
+			}
+		}
+		return true
+	})
+	return issues
+}
+
+// checkSkip reports a skip directly in either branch of an if statement
+// that is guarded by an assertion or an error check
+func (fn *funcInfo) checkSkip(stmt *ast.IfStmt, names map[string]bool) []Issue {
+	if fn.mentionsEnvironment(stmt.Init) || fn.mentionsEnvironment(stmt.Cond) {
+		return nil
+	}
+	var kind, message string
+	cond := snippet(types.ExprString(stmt.Cond))
+	switch {
+	case isErrorCheck(stmt.Cond):
+		kind, message = KindSkipOnError, i18n.T("testquality.skip", cond)
+	case isComparison(stmt.Cond):
+		kind, message = KindSkipOnMismatch, i18n.T("testquality.skip", cond)
+	default:
+		return nil
+	}
+
+	var issues []Issue
+	branches := []ast.Stmt{stmt.Body}
+	if stmt.Else != nil {
+		branches = append(branches, stmt.Else)
+	}
+	for _, branch := range branches {
+		block, ok := branch.(*ast.BlockStmt)
+		if !ok {
+			continue
+		}
+		for _, s := range block.List {
+			expr, ok := s.(*ast.ExprStmt)
+			if !ok {
+				continue
+			}
+			call, ok := expr.X.(*ast.CallExpr)
+			if !ok {
+				continue
+			}
+			if method, ok := methodOn(call, names); ok && skipMethods[method] {
+				issues = append(issues, fn.issue(call, kind, message))
+			}
+		}
+	}
+	return issues
+}
+
+// restoresState reports whether fn defers or registers a cleanup that
+// changes the working directory or environment back
+func (fn *funcInfo) restoresState(names map[string]bool) bool {
+	restores := func(n ast.Node) bool {
+		found := false
+		ast.Inspect(n, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				if path, name, ok := fn.qualified(call.Fun); ok && path == "os" && stateFuncs[name] != "" {
+					found = true
+				}
+			}
+			return !found
+		})
+		return found
+	}
+
+	found := false
+	ast.Inspect(fn.decl.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.DeferStmt:
+			found = found || restores(n)
+		case *ast.CallExpr:
+			if method, ok := methodOn(n, names); ok && method == "Cleanup" {
+				found = found || restores(n)
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// mentionsEnvironment reports whether n checks the platform, the
+// environment or the test flags
+func (fn *funcInfo) mentionsEnvironment(n ast.Node) bool {
+	if n == nil {
+		return false
+	}
+	found := false
+	ast.Inspect(n, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if path, name, ok := fn.qualified(sel); ok {
+				if names, ok := environment[path]; ok && (names == nil || names[name]) {
+					found = true
+				}
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// issue creates an issue of fn at the position of n
+func (fn *funcInfo) issue(n ast.Node, kind, message string) Issue {
+	position := fn.file.fset.Position(n.Pos())
+	return Issue{
+		File:    fn.file.name,
+		Line:    position.Line,
+		Column:  position.Column,
+		Func:    fn.decl.Name.Name,
+		Kind:    kind,
+		Message: message,
+	}
+}
+
+// methodOn returns the method called if call is a method call on one of the
+// testing values names
+func methodOn(call *ast.CallExpr, names map[string]bool) (string, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok || !names[ident.Name] {
+		return "", false
+	}
+	return sel.Sel.Name, true
+}
+
+// isErrorCheck reports whether cond is err != nil for an error variable
+func isErrorCheck(cond ast.Expr) bool {
+	bin, ok := ast.Unparen(cond).(*ast.BinaryExpr)
+	if !ok || bin.Op != token.NEQ || !isNil(bin.Y) {
+		return false
+	}
+	name := ""
+	switch x := bin.X.(type) {
+	case *ast.Ident:
+		name = x.Name
+	case *ast.SelectorExpr:
+		name = x.Sel.Name
+	}
+	return strings.Contains(strings.ToLower(name), "err")
+}
+
+// isComparison reports whether cond compares values, possibly negated or
+// combined with other conditions, e.g. !reflect.DeepEqual(got, want)
+func isComparison(cond ast.Expr) bool {
+	switch cond := ast.Unparen(cond).(type) {
+	case *ast.UnaryExpr:
+		return cond.Op == token.NOT && isComparison(cond.X)
+	case *ast.BinaryExpr:
+		switch cond.Op {
+		case token.LAND, token.LOR:
+			return isComparison(cond.X) || isComparison(cond.Y)
+		case token.EQL, token.NEQ, token.LSS, token.GTR, token.LEQ, token.GEQ:
+			return !isNil(cond.X) && !isNil(cond.Y)
+		}
+	case *ast.CallExpr:
+		name := ""
+		switch fun := cond.Fun.(type) {
+		case *ast.Ident:
+			name = fun.Name
+		case *ast.SelectorExpr:
+			name = fun.Sel.Name
+		}
+		return strings.Contains(name, "Equal")
+	}
+	return false
+}
+
+func isNil(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == "nil"
+}
+
+// imports maps the names the imports of file are referred to by to their
+// paths. The name of an import without one is the last path element.
+func imports(file *ast.File) map[string]string {
+	names := make(map[string]string)
+	for _, imp := range file.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path[strings.LastIndex(path, "/")+1:]
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		names[name] = path
+	}
+	return names
+}
+
+// snippet collapses whitespace in an expression and shortens it for display
+func snippet(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > maxSnippet {
+		return string(runes[:maxSnippet-1]) + "…"
+	}
+	return s
+}
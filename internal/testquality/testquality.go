@@ -0,0 +1,91 @@
+package testquality
+
+import (
+	"github.com/andro-kes/gokode/internal/fileutil"
+)
+
+// File is the name of the test quality report in the metrics directory
+const File = "test_quality.json"
+
+// Kinds of test quality issues
+const (
+	// KindNoFailure is a test that cannot fail: neither it nor its helpers
+	// call t.Error, t.Fatal or another failure method
+	KindNoFailure = "no-failure"
+	// KindSkipOnMismatch is a t.Skip guarded by a comparison of results,
+	// turning a failed assertion into a skipped test
+	KindSkipOnMismatch = "skip-on-mismatch"
+	// KindSkipOnError is a t.Skip guarded by an error check, turning a
+	// failure into a skipped test
+	KindSkipOnError = "skip-on-error"
+	// KindIgnoredError is an error result of a call that is dropped
+	KindIgnoredError = "ignored-error"
+	// KindProcessState is a change to the working directory or environment
+	// of the test binary that is not undone when the test ends
+	KindProcessState = "process-state"
+)
+
+// Kinds lists the kinds of issues in the order they are reported
+var Kinds = []string{KindNoFailure, KindSkipOnMismatch, KindSkipOnError, KindIgnoredError, KindProcessState}
+
+// Issue is a problem found in a test or test helper
+type Issue struct {
+	// File is relative to the project
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// Func is the test or helper function the issue is in
+	Func    string `json:"func"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// Report is the result of analysing the test files of a project
+type Report struct {
+	Files  int     `json:"files"`
+	Tests  int     `json:"tests"`
+	Issues []Issue `json:"issues"`
+}
+
+// Count returns the number of issues of a kind
+func (r *Report) Count(kind string) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// KindCount is the number of issues of a kind
+type KindCount struct {
+	Kind  string
+	Count int
+}
+
+// Counts returns the number of issues of each kind found, in the order of
+// Kinds
+func (r *Report) Counts() []KindCount {
+	var counts []KindCount
+	for _, kind := range Kinds {
+		if n := r.Count(kind); n > 0 {
+			counts = append(counts, KindCount{Kind: kind, Count: n})
+		}
+	}
+	return counts
+}
+
+// Write atomically writes the report as JSON
+func Write(name string, report *Report) error {
+	return fileutil.WriteJSON(name, report, "test quality report")
+}
+
+// Read reads a report written by Write
+func Read(name string) (*Report, error) {
+	var report Report
+	if err := fileutil.ReadJSON(name, &report, "test quality report"); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,161 @@
+package testquality
+
+import (
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+const helperSource = `package jsoner
+
+import (
+	"os"
+	"testing"
+)
+
+func setup(t *testing.T) *os.File {
+	t.Helper()
+	file, err := os.Create("test.json")
+	if err != nil {
+		t.Skip(err.Error())
+	}
+	return file
+}
+
+func mustOpen(t testing.TB, name string) *os.File {
+	file, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+`
+
+const testSource = `package jsoner
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestSkips(t *testing.T) {
+	file := setup(t)
+	defer file.Close()
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on windows")
+	}
+	got, want := read(file), "x"
+	if !reflect.DeepEqual(got, want) {
+		t.Skip("Данные не совпадают")
+	}
+}
+
+func TestHelperFails(t *testing.T) {
+	mustOpen(t, "a.json")
+}
+
+func TestSubtest(t *testing.T) {
+	t.Run("case", func(t *testing.T) {
+		if got := read(nil); got != "" {
+			t.Errorf("got %q", got)
+		}
+	})
+}
+
+func TestState(t *testing.T) {
+	os.Chdir("testdata")
+	n, _ := strconv.Atoi("1")
+	en, _ := Lookup("en")
+	home, _ := os.LookupEnv("HOME")
+	if n != 1 || en == nil || home == "" {
+		t.Fatal("n")
+	}
+}
+
+func TestRestoredState(t *testing.T) {
+	old := os.Getenv("HOME")
+	t.Cleanup(func() { os.Setenv("HOME", old) })
+	if err := os.Setenv("HOME", "/tmp"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAssertLibrary(t *testing.T) {
+	assertEqual(t, 1, 1)
+}
+
+func helper() {}
+`
+
+func TestAnalyze(t *testing.T) {
+	report, err := Analyze([]Source{
+		{Name: "jsoner/helpers_test.go", Src: []byte(helperSource)},
+		{Name: "jsoner/json_test.go", Src: []byte(testSource)},
+	})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if report.Files != 2 || report.Tests != 6 {
+		t.Errorf("Expected 6 tests in 2 files, got %d in %d", report.Tests, report.Files)
+	}
+
+	var got []string
+	for _, issue := range report.Issues {
+		got = append(got, filepath.Base(issue.File)+":"+strconv.Itoa(issue.Line)+" "+issue.Func+" "+issue.Kind)
+	}
+	want := []string{
+		"helpers_test.go:12 setup skip-on-error",
+		"json_test.go:12 TestSkips no-failure",
+		"json_test.go:20 TestSkips skip-on-mismatch",
+		"json_test.go:37 TestState ignored-error",
+		"json_test.go:37 TestState process-state",
+		"json_test.go:38 TestState ignored-error",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Issues =\n%q\nwant\n%q", got, want)
+	}
+
+	for _, issue := range report.Issues {
+		if issue.Kind == KindSkipOnMismatch && issue.Message != "test is skipped instead of failing when !reflect.DeepEqual(got, want)" {
+			t.Errorf("Unexpected message: %q", issue.Message)
+		}
+		if issue.Kind == KindProcessState && issue.Message != "os.Chdir changes the whole test binary and is not undone when the test ends; use t.Chdir" {
+			t.Errorf("Unexpected message: %q", issue.Message)
+		}
+	}
+
+	counts := report.Counts()
+	wantCounts := []KindCount{{KindNoFailure, 1}, {KindSkipOnMismatch, 1}, {KindSkipOnError, 1}, {KindIgnoredError, 2}, {KindProcessState, 1}}
+	if !reflect.DeepEqual(counts, wantCounts) {
+		t.Errorf("Counts = %v, want %v", counts, wantCounts)
+	}
+}
+
+func TestAnalyzeRecursiveHelper(t *testing.T) {
+	src := `package p
+
+import "testing"
+
+func walk(t *testing.T, n int) {
+	if n > 0 {
+		walk(t, n-1)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	walk(t, 3)
+}
+`
+	report, err := Analyze([]Source{{Name: "p/p_test.go", Src: []byte(src)}})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if len(report.Issues) != 1 || report.Issues[0].Kind != KindNoFailure || report.Issues[0].Func != "TestWalk" {
+		t.Errorf("Expected TestWalk to be reported as unable to fail, got %+v", report.Issues)
+	}
+}
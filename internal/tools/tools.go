@@ -1,9 +1,16 @@
 package tools
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"strings"
+
+	"github.com/andro-kes/gokode/internal/i18n"
 )
 
 const (
@@ -17,9 +24,52 @@ func IsInstalled(tool string) bool {
 	return err == nil
 }
 
+// Identities identifies the installed external analysis tools by a hash of
+// the binary found in PATH, so upgrading or replacing a tool changes them
+// even when it reports no version
+func Identities() map[string]string {
+	identities := make(map[string]string)
+	for _, tool := range []string{"golangci-lint", "gocyclo"} {
+		identities[tool] = identity(tool)
+	}
+	return identities
+}
+
+// identity returns the SHA-256 of the binary of tool, or "missing" if it
+// cannot be found or read
+func identity(tool string) string {
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		return "missing"
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "missing"
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "missing"
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// GoVersion returns the version of the Go toolchain selected for the project
+// in dir, which may differ from the one gokode was built with
+func GoVersion(ctx context.Context, dir string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", "env", "GOVERSION")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("error detecting Go version: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 // InstallGolangciLint installs golangci-lint at the specified version
 func InstallGolangciLint() error {
-	fmt.Printf("Installing golangci-lint %s...\n", GolangciLintVersion)
+	i18n.Println("tools.installing", "golangci-lint", GolangciLintVersion)
 	cmd := exec.Command("go", "install", fmt.Sprintf("github.com/golangci/golangci-lint/cmd/golangci-lint@%s", GolangciLintVersion))
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -28,7 +78,7 @@ func InstallGolangciLint() error {
 
 // InstallGocyclo installs gocyclo at the specified version
 func InstallGocyclo() error {
-	fmt.Printf("Installing gocyclo %s...\n", GocycloVersion)
+	i18n.Println("tools.installing", "gocyclo", GocycloVersion)
 	cmd := exec.Command("go", "install", fmt.Sprintf("github.com/fzipp/gocyclo/cmd/gocyclo@%s", GocycloVersion))
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -37,28 +87,28 @@ func InstallGocyclo() error {
 
 // InstallAll installs all required tools
 func InstallAll() error {
-	fmt.Println("Installing required tools...")
+	i18n.Println("tools.installingAll")
 
 	var failed bool
 
 	if err := InstallGolangciLint(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error installing golangci-lint: %v\n", err)
+		i18n.Fprintln(os.Stderr, "tools.failed", "golangci-lint", err)
 		failed = true
 	} else {
-		fmt.Println("✓ golangci-lint installed")
+		i18n.Println("tools.installed", "golangci-lint")
 	}
 
 	if err := InstallGocyclo(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error installing gocyclo: %v\n", err)
+		i18n.Fprintln(os.Stderr, "tools.failed", "gocyclo", err)
 		failed = true
 	} else {
-		fmt.Println("✓ gocyclo installed")
+		i18n.Println("tools.installed", "gocyclo")
 	}
 
 	if failed {
 		return fmt.Errorf("failed to install some tools")
 	}
 
-	fmt.Println("✓ All tools installed successfully")
+	i18n.Println("tools.done")
 	return nil
 }
@@ -1,6 +1,8 @@
 package tools
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -15,3 +17,28 @@ func TestIsInstalled(t *testing.T) {
 		t.Error("Expected non-existent tool to not be found")
 	}
 }
+
+func TestIdentities(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+	if got := Identities()["gocyclo"]; got != "missing" {
+		t.Errorf("Expected a missing tool, got %q", got)
+	}
+
+	binary := filepath.Join(dir, "gocyclo")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\necho v0.6.0\n"), 0755); err != nil {
+		t.Fatalf("Failed to write gocyclo: %v", err)
+	}
+	first := Identities()["gocyclo"]
+	if first == "missing" {
+		t.Fatal("Expected the tool in PATH to be found")
+	}
+
+	// Replacing the binary, e.g. with another version, changes the identity
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\necho v0.7.0\n"), 0755); err != nil {
+		t.Fatalf("Failed to write gocyclo: %v", err)
+	}
+	if Identities()["gocyclo"] == first {
+		t.Error("Expected the identity to change with the binary")
+	}
+}